@@ -0,0 +1,97 @@
+// Package appliance — пример своего коллектора вне репозитория агента: счётчики портов
+// и число сессий с HTTP-статуса устройства (балансировщик, файрвол), которое агент сам не знает.
+//
+// Устройство отдаёт GET PLUGIN_APPLIANCE_URL:
+//
+//	{"sessions": 1520, "ports": [{"name": "wan0", "rx_bytes": 123, "tx_bytes": 456}]}
+//
+// Коллектор переводит счётчики в скорости по разнице с прошлым вызовом — так же, как агент
+// считает интерфейсы, — поэтому состояние держит в себе (вызовы не пересекаются)
+package appliance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/sdk"
+)
+
+func init() {
+	sdk.Register("appliance", New)
+}
+
+type status struct {
+	Sessions float64 `json:"sessions"`
+	Ports    []struct {
+		Name    string `json:"name"`
+		RxBytes uint64 `json:"rx_bytes"`
+		TxBytes uint64 `json:"tx_bytes"`
+	} `json:"ports"`
+}
+
+type counters struct{ rx, tx uint64 }
+
+// Collector читает статус устройства
+type Collector struct {
+	url, token string
+	client     *http.Client
+
+	prev   map[string]counters
+	prevAt time.Time
+}
+
+// New — фабрика для sdk.Register: PLUGIN_APPLIANCE_URL обязателен, PLUGIN_APPLIANCE_TOKEN — Bearer-токен
+func New(cfg sdk.Config) (sdk.Collector, error) {
+	c := &Collector{url: cfg.Get("URL"), token: cfg.Get("TOKEN"), client: &http.Client{}}
+	if c.url == "" {
+		return nil, errors.New("URL is required")
+	}
+	return c, nil
+}
+
+// Collect: первый вызов только запоминает счётчики, скорости — со второго
+func (c *Collector) Collect(ctx context.Context) ([]sdk.Sample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", c.url, resp.Status)
+	}
+	var st status
+	if err := json.NewDecoder(resp.Body).Decode(&st); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.url, err)
+	}
+
+	now := time.Now()
+	samples := []sdk.Sample{{Name: "sessions", Value: st.Sessions, Unit: "count"}}
+	cur := make(map[string]counters, len(st.Ports))
+	sec := now.Sub(c.prevAt).Seconds()
+	for _, p := range st.Ports {
+		cur[p.Name] = counters{p.RxBytes, p.TxBytes}
+		prev, ok := c.prev[p.Name]
+		// сброс счётчика (перезагрузка устройства) — пропускаем интервал, а не шлём отрицательную скорость
+		if !ok || p.RxBytes < prev.rx || p.TxBytes < prev.tx || sec <= 0 {
+			continue
+		}
+		labels := map[string]string{"port": p.Name}
+		samples = append(samples,
+			sdk.Sample{Name: "rx_bytes_per_sec", Value: float64(p.RxBytes-prev.rx) / sec, Unit: "bytes_per_sec", Labels: labels},
+			sdk.Sample{Name: "tx_bytes_per_sec", Value: float64(p.TxBytes-prev.tx) / sec, Unit: "bytes_per_sec", Labels: labels},
+		)
+	}
+	c.prev, c.prevAt = cur, now
+	return samples, nil
+}
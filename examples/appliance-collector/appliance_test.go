@@ -0,0 +1,97 @@
+package appliance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/sdk"
+	"github.com/iflixer/network-stater/src/pkg/sdk/sdktest"
+)
+
+// device — статус устройства, счётчики которого тест меняет между вызовами
+type device struct {
+	mu       sync.Mutex
+	rx, tx   uint64
+	sessions int
+	status   int
+	auth     string
+}
+
+func (d *device) set(rx, tx uint64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.rx, d.tx = rx, tx
+}
+
+func (d *device) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.auth = r.Header.Get("Authorization")
+	if d.status != 0 {
+		w.WriteHeader(d.status)
+		return
+	}
+	fmt.Fprintf(w, `{"sessions": %d, "ports": [{"name": "wan0", "rx_bytes": %d, "tx_bytes": %d}]}`, d.sessions, d.rx, d.tx)
+}
+
+func TestCollect(t *testing.T) {
+	dev := &device{rx: 1000, tx: 2000, sessions: 1520}
+	srv := httptest.NewServer(dev)
+	defer srv.Close()
+	c := sdktest.New(t, "appliance", map[string]string{"URL": srv.URL, "TOKEN": "secret"})
+
+	// первый вызов — только сессии: скоростям не с чем сравнить
+	samples := sdktest.Collect(t, c)
+	if s := sdktest.Require(t, samples, "sessions", nil); s.Value != 1520 || s.Unit != "count" {
+		t.Errorf("sessions = %+v", s)
+	}
+	if _, ok := sdktest.Find(samples, "rx_bytes_per_sec", map[string]string{"port": "wan0"}); ok {
+		t.Error("rates on the first call")
+	}
+	if dev.auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", dev.auth)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	dev.set(1000+1<<20, 2000)
+	samples = sdktest.Collect(t, c)
+	port := map[string]string{"port": "wan0"}
+	if rx := sdktest.Require(t, samples, "rx_bytes_per_sec", port); rx.Value <= 0 || rx.Unit != "bytes_per_sec" {
+		t.Errorf("rx = %+v, want a positive rate", rx)
+	}
+	if tx := sdktest.Require(t, samples, "tx_bytes_per_sec", port); tx.Value != 0 {
+		t.Errorf("tx = %+v, want 0", tx)
+	}
+
+	// сброс счётчика — интервал порта пропускается
+	dev.set(10, 10)
+	samples = sdktest.Collect(t, c)
+	if _, ok := sdktest.Find(samples, "rx_bytes_per_sec", port); ok {
+		t.Error("rate after a counter reset")
+	}
+	sdktest.Require(t, samples, "sessions", nil)
+}
+
+func TestCollectErrors(t *testing.T) {
+	dev := &device{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(dev)
+	defer srv.Close()
+	c := sdktest.New(t, "appliance", map[string]string{"URL": srv.URL})
+	if _, err := c.Collect(context.Background()); err == nil {
+		t.Error("want an error for 503")
+	}
+	if dev.auth != "" {
+		t.Errorf("Authorization = %q without TOKEN", dev.auth)
+	}
+}
+
+func TestNewRequiresURL(t *testing.T) {
+	if _, err := New(sdk.MapConfig{}); err == nil {
+		t.Error("want an error without URL")
+	}
+}
@@ -0,0 +1,8 @@
+module example.com/appliance-collector
+
+go 1.23.2
+
+require github.com/iflixer/network-stater/src v0.0.0
+
+// в своём репозитории — версия модуля агента вместо replace
+replace github.com/iflixer/network-stater/src => ../../src
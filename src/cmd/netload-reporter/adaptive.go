@@ -0,0 +1,87 @@
+package main
+
+import (
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+const (
+	defaultAdaptiveSample      = 5 * time.Second
+	defaultAdaptiveMinInterval = 10 * time.Second
+)
+
+// adaptivePolicy: счётчики читаются каждые sample, а отчёт уходит, когда подошёл
+// текущий интервал. Пока скорость выше highBps, интервал — minInterval; после спада
+// он удваивается с каждым отчётом, пока не вернётся к обычному. Так короткое насыщение
+// канала не растворяется в минутном среднем. С highPct порог — доля суммарной скорости линков
+// (LINK_SPEED), а highBps — порог, пока она не известна
+type adaptivePolicy struct {
+	sample      time.Duration
+	minInterval time.Duration
+	maxInterval time.Duration
+	highBps     float64
+	highPct     float64
+	// суммарная скорость линков, байт/с, по последнему отчёту
+	linkBps float64
+
+	every  time.Duration
+	high   bool
+	last   collector.Counters
+	lastAt time.Time
+}
+
+// due учитывает очередной замер cur и решает, пора ли отчёт; sinceReport — с прошлого
+// отчёта, base — обычный интервал с учётом экономного и лимитного режимов
+func (a *adaptivePolicy) due(now time.Time, cur collector.Counters, sinceReport, base time.Duration) bool {
+	if a.every == 0 || a.every > base {
+		a.every = base
+	}
+	if !a.lastAt.IsZero() {
+		if sec := now.Sub(a.lastAt).Seconds(); sec > 0 {
+			bps := (collector.Delta(cur.Rx, a.last.Rx) + collector.Delta(cur.Tx, a.last.Tx)) / sec
+			limit := a.threshold()
+			if high := limit > 0 && bps >= limit; high != a.high {
+				a.high = high
+				if high {
+					msg.Printf(msg.AdaptiveFast, bps, limit, a.minInterval)
+				} else {
+					msg.Printf(msg.AdaptiveBackoff, limit, base)
+				}
+			}
+		}
+	}
+	a.last, a.lastAt = cur, now
+
+	if a.high {
+		a.every = a.minInterval
+	}
+	// полшага допуска: замеры идут с шагом sample и точно на границу не попадают
+	if sinceReport+a.sample/2 < a.every {
+		return false
+	}
+	if !a.high {
+		a.every = min(a.every*2, base)
+	}
+	return true
+}
+
+// threshold — порог скорости, байт/с; 0 — не ускоряться (доля линка без известной скорости
+// и без highBps)
+func (a *adaptivePolicy) threshold() float64 {
+	if a.highPct > 0 && a.linkBps > 0 {
+		return a.linkBps * a.highPct / 100
+	}
+	return a.highBps
+}
+
+// setLinkSpeed запоминает суммарную скорость линков ifaces (Мбит/с из LINK_SPEED); упавшие
+// линки без скорости не учитываются
+func (a *adaptivePolicy) setLinkSpeed(speed map[string]int, ifaces []string) {
+	var mbps int
+	for _, iface := range ifaces {
+		mbps += speed[iface]
+	}
+	a.linkBps = float64(mbps) * 1e6 / 8
+}
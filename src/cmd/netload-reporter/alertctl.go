@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"os/user"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// silenceRequest — тело POST /v1/alerts/silences и /v1/alerts/ack
+type silenceRequest struct {
+	Rule      string `json:"rule"`
+	Interface string `json:"interface"`
+	Duration  string `json:"duration,omitempty"`
+	By        string `json:"by,omitempty"`
+	Reason    string `json:"reason,omitempty"`
+}
+
+// alertsResponse — ответ GET /v1/alerts
+type alertsResponse struct {
+	Alerts   []reporter.ActiveAlert `json:"alerts"`
+	Silences []alertSilence         `json:"silences"`
+}
+
+// registerAlertAPI — тишина и подтверждение локальных алертов без остановки агента
+func registerAlertAPI(mux *http.ServeMux, alerts *alertEngine, writeToken string) {
+	decode := func(w http.ResponseWriter, r *http.Request) (silenceRequest, bool) {
+		var req silenceRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil || req.Rule == "" {
+			http.Error(w, "want JSON with rule, interface", http.StatusBadRequest)
+			return req, false
+		}
+		return req, true
+	}
+
+	mux.HandleFunc("GET /v1/alerts", func(w http.ResponseWriter, r *http.Request) {
+		res := alertsResponse{Alerts: []reporter.ActiveAlert{}, Silences: []alertSilence{}}
+		active, silences := alerts.snapshot()
+		res.Alerts, res.Silences = append(res.Alerts, active...), append(res.Silences, silences...)
+		writeJSON(w, res)
+	})
+	mux.HandleFunc("POST /v1/alerts/silences", func(w http.ResponseWriter, r *http.Request) {
+		if !privileged(w, r, writeToken) {
+			return
+		}
+		req, ok := decode(w, r)
+		if !ok {
+			return
+		}
+		d, err := time.ParseDuration(req.Duration)
+		if err != nil {
+			http.Error(w, "invalid duration", http.StatusBadRequest)
+			return
+		}
+		sl, err := alerts.silence(req.Rule, req.Interface, d, req.By, req.Reason, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, sl)
+	})
+	mux.HandleFunc("DELETE /v1/alerts/silences/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !privileged(w, r, writeToken) {
+			return
+		}
+		if !alerts.unsilence(r.PathValue("id"), r.URL.Query().Get("by")) {
+			http.Error(w, "no such silence", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("POST /v1/alerts/ack", func(w http.ResponseWriter, r *http.Request) {
+		if !privileged(w, r, writeToken) {
+			return
+		}
+		req, ok := decode(w, r)
+		if !ok {
+			return
+		}
+		a, ok := alerts.acknowledge(req.Rule, req.Interface, req.By)
+		if !ok {
+			http.Error(w, "alert is not firing", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, a)
+	})
+}
+
+// runAlerts — управление алертами работающего агента через его API:
+// list, silence RULE[/IFACE] DURATION [reason], unsilence ID, ack RULE/IFACE
+func runAlerts(args []string) int {
+	fs := flag.NewFlagSet("alerts", flag.ContinueOnError)
+	api := fs.String("api", os.Getenv("API_LISTEN"), "agent API address")
+	token := fs.String("token", os.Getenv("API_WRITE_TOKEN"), "API write token")
+	by := fs.String("by", currentUser(), "who silences or acknowledges")
+	fs.Usage = func() {
+		out := fs.Output()
+		fmt.Fprintln(out, "usage: netload-reporter alerts [flags] list")
+		fmt.Fprintln(out, "       netload-reporter alerts [flags] silence RULE[/IFACE] DURATION [reason]")
+		fmt.Fprintln(out, "       netload-reporter alerts [flags] unsilence ID")
+		fmt.Fprintln(out, "       netload-reporter alerts [flags] ack RULE/IFACE")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *api == "" {
+		fmt.Fprintln(os.Stderr, "alerts: agent API address is not set (API_LISTEN or -api)")
+		return 2
+	}
+	addr := *api
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	c := &alertClient{base: "http://" + addr, token: *token, http: &http.Client{Timeout: 5 * time.Second}}
+
+	cmdArgs := fs.Args()
+	if len(cmdArgs) == 0 {
+		cmdArgs = []string{"list"}
+	}
+	var err error
+	switch cmd, rest := cmdArgs[0], cmdArgs[1:]; {
+	case cmd == "list" && len(rest) == 0:
+		err = c.list()
+	case cmd == "silence" && len(rest) >= 2:
+		rule, iface, _ := strings.Cut(rest[0], "/")
+		var sl alertSilence
+		req := silenceRequest{Rule: rule, Interface: iface, Duration: rest[1], By: *by, Reason: strings.Join(rest[2:], " ")}
+		if err = c.do(http.MethodPost, "/v1/alerts/silences", req, &sl); err == nil {
+			fmt.Printf("silence %s: %s until %s\n", sl.ID, sl.target(), sl.Until.Local().Format(time.DateTime))
+		}
+	case cmd == "unsilence" && len(rest) == 1:
+		err = c.do(http.MethodDelete, "/v1/alerts/silences/"+neturl.PathEscape(rest[0])+"?by="+neturl.QueryEscape(*by), nil, nil)
+	case cmd == "ack" && len(rest) == 1:
+		rule, iface, ok := strings.Cut(rest[0], "/")
+		if !ok {
+			fs.Usage()
+			return 2
+		}
+		var a reporter.ActiveAlert
+		if err = c.do(http.MethodPost, "/v1/alerts/ack", silenceRequest{Rule: rule, Interface: iface, By: *by}, &a); err == nil {
+			fmt.Printf("acknowledged %s/%s (%s)\n", a.Rule, a.Interface, a.Level)
+		}
+	default:
+		fs.Usage()
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "alerts: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+type alertClient struct {
+	base, token string
+	http        *http.Client
+}
+
+func (c *alertClient) do(method, path string, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(b)
+	}
+	req, err := http.NewRequest(method, c.base+path, body)
+	if err != nil {
+		return err
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *alertClient) list() error {
+	var res alertsResponse
+	if err := c.do(http.MethodGet, "/v1/alerts", nil, &res); err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(tw, "RULE\tINTERFACE\tLEVEL\tRATE\tTHRESHOLD\tSINCE\tSTATE")
+	for _, a := range res.Alerts {
+		var state []string
+		if a.SilenceID != "" {
+			state = append(state, "silenced "+a.SilenceID)
+		}
+		if a.Acknowledged {
+			state = append(state, "acked by "+a.AcknowledgedBy)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s %s\t%s\t%s\t%s\n", a.Rule, a.Interface, a.Level, a.Direction, formatBits(a.BitsPerSec/8),
+			formatBits(a.ThresholdBitsPerSec/8), time.Unix(a.Since, 0).Local().Format(time.DateTime), cmp.Or(strings.Join(state, ", "), "-"))
+	}
+	tw.Flush()
+	if len(res.Silences) > 0 {
+		fmt.Println()
+		tw = tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+		fmt.Fprintln(tw, "SILENCE\tTARGET\tUNTIL\tBY\tREASON")
+		for _, sl := range res.Silences {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", sl.ID, sl.target(), sl.Until.Local().Format(time.DateTime), cmp.Or(sl.By, "-"), cmp.Or(sl.Reason, "-"))
+		}
+		tw.Flush()
+	}
+	return nil
+}
+
+func currentUser() string {
+	if u, err := user.Current(); err == nil {
+		return u.Username
+	}
+	return os.Getenv("USER")
+}
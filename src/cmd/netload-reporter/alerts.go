@@ -0,0 +1,397 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+	"github.com/iflixer/network-stater/src/pkg/window"
+)
+
+// уровни срабатывания правила
+const (
+	alertOK       = "ok"
+	alertWarning  = "warning"
+	alertCritical = "critical"
+)
+
+// alertRule — порог на скорость интерфейсов по маскам в одном направлении, бит/с;
+// window 0 — по мгновенной скорости интервала, иначе по средней за окно
+type alertRule struct {
+	name      string
+	patterns  []string
+	direction string // rx, tx или total
+	warn      float64
+	crit      float64
+	window    time.Duration
+}
+
+type alertState struct {
+	win   *window.Window
+	level string
+	since time.Time
+	// подтверждение действует, пока уровень не сменится
+	ackBy string
+}
+
+// alertEngine — локальные правила ALERT_RULES: проверяются на каждом интервале по счётчикам
+// каждого подходящего интерфейса; смена уровня — событие alert_warning/alert_critical/alert_resolved,
+// сработавшие правила — в поле alerts отчёта. Тишина и подтверждения приходят из API
+// в другой горутине, поэтому всё состояние — под mu
+type alertEngine struct {
+	rules []alertRule
+	// идущие выкладки глушат алерты на своих интерфейсах
+	deploys *deployTracker
+
+	mu       sync.Mutex
+	state    map[string]*alertState
+	silences []alertSilence
+	active   []reporter.ActiveAlert
+	// события действий через API: уходят с ближайшим интервалом
+	pending []reporter.Event
+}
+
+func newAlertEngine(rules []alertRule) *alertEngine {
+	return &alertEngine{rules: rules, state: make(map[string]*alertState)}
+}
+
+func alertKey(rule, iface string) string { return rule + "\x00" + iface }
+
+func (e *alertEngine) observe(ctx context.Context, bus *reporter.EventBus, cur, prev map[string]collector.Counters, sec float64, now time.Time) []reporter.ActiveAlert {
+	e.mu.Lock()
+	evs := e.evaluate(cur, prev, sec, now)
+	e.mu.Unlock()
+	for _, ev := range evs {
+		emit(ctx, bus, ev)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.active)
+}
+
+func (e *alertEngine) evaluate(cur, prev map[string]collector.Counters, sec float64, now time.Time) []reporter.Event {
+	evs := e.pending
+	e.pending = nil
+	kept := e.silences[:0]
+	for _, sl := range e.silences {
+		if now.Before(sl.Until) {
+			kept = append(kept, sl)
+			continue
+		}
+		evs = append(evs, reporter.Event{
+			Type:    "alert_silence_expired",
+			Message: msg.Text(msg.AlertSilenceExpired, sl.ID, sl.target()),
+			Data:    map[string]any{"silence": sl},
+		})
+	}
+	e.silences = kept
+
+	var out []reporter.ActiveAlert
+	live := make(map[string]bool)
+	for _, r := range e.rules {
+		for iface, c := range cur {
+			p, ok := prev[iface]
+			if !ok || !collector.MatchAny(r.patterns, iface) {
+				continue
+			}
+			key := alertKey(r.name, iface)
+			live[key] = true
+			st := e.state[key]
+			if st == nil {
+				st = &alertState{level: alertOK}
+				if r.window > 0 {
+					st.win = window.New(r.window, now.Add(-time.Duration(sec*float64(time.Second))))
+				}
+				e.state[key] = st
+			}
+			drx, dtx := collector.Delta(c.Rx, p.Rx), collector.Delta(c.Tx, p.Tx)
+			rx, tx := drx/sec, dtx/sec
+			if st.win != nil {
+				rx, tx, _ = st.win.Add(now, drx, dtx)
+			}
+			var bps float64
+			switch r.direction {
+			case "rx":
+				bps = rx * 8
+			case "tx":
+				bps = tx * 8
+			default:
+				bps = (rx + tx) * 8
+			}
+
+			level, threshold := alertOK, 0.0
+			switch {
+			case r.crit > 0 && bps >= r.crit:
+				level, threshold = alertCritical, r.crit
+			case r.warn > 0 && bps >= r.warn:
+				level, threshold = alertWarning, r.warn
+			}
+			silence := e.silencedBy(r.name, iface)
+			if level != st.level {
+				evs = append(evs, alertTransition(r, iface, st.level, level, bps, threshold, silence, st.ackBy))
+				st.level, st.since, st.ackBy = level, now, ""
+			}
+			if level != alertOK {
+				a := reporter.ActiveAlert{
+					Rule:                r.name,
+					Interface:           iface,
+					Direction:           r.direction,
+					Level:               level,
+					BitsPerSec:          bps,
+					ThresholdBitsPerSec: threshold,
+					Since:               st.since.UTC().Unix(),
+				}
+				if r.window > 0 {
+					a.WindowSeconds = r.window.Seconds()
+				}
+				if silence != nil {
+					a.SilenceID, a.SilencedUntil, a.DeployID = silence.ID, silence.Until.UTC().Unix(), silence.Deploy
+				}
+				a.AcknowledgedBy = st.ackBy
+				a.Acknowledged = st.ackBy != ""
+				out = append(out, a)
+			}
+		}
+	}
+	// пропавший интерфейс забываем: вернётся — окно начнётся заново
+	for key := range e.state {
+		if !live[key] {
+			delete(e.state, key)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Rule != out[j].Rule {
+			return out[i].Rule < out[j].Rule
+		}
+		return out[i].Interface < out[j].Interface
+	})
+	e.active = out
+	return evs
+}
+
+// alertTransition — событие смены уровня; тишина и подтверждение событие не отменяют,
+// а помечают: приёмник сам решает, будить ли дежурного
+func alertTransition(r alertRule, iface, from, to string, bps, threshold float64, silence *alertSilence, ackBy string) reporter.Event {
+	data := map[string]any{
+		"rule":           r.name,
+		"direction":      r.direction,
+		"previous_level": from,
+		"level":          to,
+		"bits_per_sec":   bps,
+		"silenced":       silence != nil,
+	}
+	if r.window > 0 {
+		data["window_seconds"] = r.window.Seconds()
+	}
+	if silence != nil {
+		data["silence"] = *silence
+	}
+	if ackBy != "" {
+		data["acknowledged_by"] = ackBy
+	}
+	ev := reporter.Event{Interface: iface, Data: data}
+	if to == alertOK {
+		ev.Type = "alert_resolved"
+		ev.Message = msg.Text(msg.AlertResolved, r.name, iface, r.direction, formatBits(bps/8))
+	} else {
+		data["threshold_bits_per_sec"] = threshold
+		ev.Type = "alert_" + to
+		ev.Message = msg.Text(msg.AlertFiring, r.name, iface, r.direction, formatBits(bps/8), formatBits(threshold/8), to)
+	}
+	if silence != nil {
+		ev.Message += ", silenced until " + silence.Until.UTC().Format(time.RFC3339)
+	}
+	return ev
+}
+
+// alertSilence глушит правило (или все — "*") на интерфейсах по маске до Until
+type alertSilence struct {
+	ID        string    `json:"id"`
+	Rule      string    `json:"rule"`
+	Interface string    `json:"interface"`
+	Until     time.Time `json:"until"`
+	By        string    `json:"by,omitempty"`
+	Reason    string    `json:"reason,omitempty"`
+	Created   time.Time `json:"created"`
+	// тишина выкладки: не снимается через /v1/alerts, кончается вместе с ней
+	Deploy string `json:"deploy,omitempty"`
+}
+
+func (sl alertSilence) target() string { return sl.Rule + "/" + sl.Interface }
+
+func (sl alertSilence) matches(rule, iface string) bool {
+	if sl.Rule != "*" && sl.Rule != rule {
+		return false
+	}
+	ok, _ := filepath.Match(sl.Interface, iface)
+	return ok
+}
+
+// silencedBy — самая долгая тишина, под которую попадает алерт, включая тишину выкладок
+func (e *alertEngine) silencedBy(rule, iface string) *alertSilence {
+	var best *alertSilence
+	for i := range e.silences {
+		if sl := &e.silences[i]; sl.matches(rule, iface) && (best == nil || sl.Until.After(best.Until)) {
+			best = sl
+		}
+	}
+	if e.deploys != nil {
+		for _, dw := range e.deploys.snapshot() {
+			if dw.matches(iface) && (best == nil || dw.Until.After(best.Until)) {
+				best = &alertSilence{ID: "deploy-" + dw.ID, Rule: "*", Interface: dw.Interface, Until: dw.Until, By: dw.By, Reason: "deploy " + dw.ID, Created: dw.Since, Deploy: dw.ID}
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	sl := *best
+	return &sl
+}
+
+// silence добавляет тишину; rule — имя правила или "*", iface — маска (пусто — все)
+func (e *alertEngine) silence(rule, iface string, d time.Duration, by, reason string, now time.Time) (alertSilence, error) {
+	if rule != "*" && !slices.ContainsFunc(e.rules, func(r alertRule) bool { return r.name == rule }) {
+		return alertSilence{}, fmt.Errorf("unknown rule %q", rule)
+	}
+	if iface == "" {
+		iface = "*"
+	}
+	if _, err := filepath.Match(iface, ""); err != nil {
+		return alertSilence{}, fmt.Errorf("invalid interface pattern %q", iface)
+	}
+	if d <= 0 {
+		return alertSilence{}, fmt.Errorf("duration must be positive")
+	}
+	id := make([]byte, 4)
+	rand.Read(id)
+	sl := alertSilence{ID: hex.EncodeToString(id), Rule: rule, Interface: iface, Until: now.Add(d), By: by, Reason: reason, Created: now}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.silences = append(e.silences, sl)
+	e.pending = append(e.pending, reporter.Event{
+		Type:    "alert_silenced",
+		Message: msg.Text(msg.AlertSilenced, sl.target(), sl.Until.UTC().Format(time.RFC3339), cmp.Or(by, "unknown"), reason),
+		Data:    map[string]any{"silence": sl},
+	})
+	return sl, nil
+}
+
+// unsilence снимает тишину досрочно
+func (e *alertEngine) unsilence(id, by string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	i := slices.IndexFunc(e.silences, func(sl alertSilence) bool { return sl.ID == id })
+	if i < 0 {
+		return false
+	}
+	sl := e.silences[i]
+	e.silences = slices.Delete(e.silences, i, i+1)
+	e.pending = append(e.pending, reporter.Event{
+		Type:    "alert_unsilenced",
+		Message: msg.Text(msg.AlertUnsilenced, sl.ID, sl.target(), cmp.Or(by, "unknown")),
+		Data:    map[string]any{"silence": sl},
+	})
+	return true
+}
+
+// acknowledge подтверждает сработавший алерт: он остаётся в отчёте с пометкой,
+// пока не вернётся в норму или не сменит уровень
+func (e *alertEngine) acknowledge(rule, iface, by string) (reporter.ActiveAlert, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	st := e.state[alertKey(rule, iface)]
+	i := slices.IndexFunc(e.active, func(a reporter.ActiveAlert) bool { return a.Rule == rule && a.Interface == iface })
+	if st == nil || st.level == alertOK || i < 0 {
+		return reporter.ActiveAlert{}, false
+	}
+	st.ackBy = cmp.Or(by, "unknown")
+	a := &e.active[i]
+	a.Acknowledged, a.AcknowledgedBy = true, st.ackBy
+	e.pending = append(e.pending, reporter.Event{
+		Type:      "alert_acknowledged",
+		Interface: iface,
+		Message:   msg.Text(msg.AlertAcknowledged, rule, iface, a.Level, st.ackBy),
+		Data:      map[string]any{"rule": rule, "level": a.Level, "acknowledged_by": st.ackBy},
+	})
+	return *a, true
+}
+
+// snapshot — сработавшие алерты последнего интервала и действующие тишины
+func (e *alertEngine) snapshot() ([]reporter.ActiveAlert, []alertSilence) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return slices.Clone(e.active), slices.Clone(e.silences)
+}
+
+// parseAlertRules разбирает ALERT_RULES: правила через ";", в правиле — имя и поля key=value
+// через пробел: "uplink iface=en*,bond* dir=tx warn=6G crit=9G window=5m; ingest iface=eth1 dir=rx crit=2G"
+func parseAlertRules(v string) ([]alertRule, error) {
+	var rules []alertRule
+	seen := make(map[string]bool)
+	for _, spec := range strings.Split(v, ";") {
+		fields := strings.Fields(spec)
+		if len(fields) == 0 {
+			continue
+		}
+		r := alertRule{name: fields[0], patterns: []string{"*"}, direction: "total"}
+		if strings.Contains(r.name, "=") {
+			return nil, fmt.Errorf("rule %q: name is required before key=value fields", spec)
+		}
+		if seen[r.name] {
+			return nil, fmt.Errorf("rule %q defined twice", r.name)
+		}
+		seen[r.name] = true
+		for _, f := range fields[1:] {
+			k, val, ok := strings.Cut(f, "=")
+			if !ok || val == "" {
+				return nil, fmt.Errorf("rule %s: invalid field %q, want key=value", r.name, f)
+			}
+			var err error
+			switch k {
+			case "iface":
+				r.patterns = splitList(val)
+			case "dir":
+				if val != "rx" && val != "tx" && val != "total" {
+					return nil, fmt.Errorf("rule %s: unknown direction %q", r.name, val)
+				}
+				r.direction = val
+			case "warn":
+				r.warn, err = parseRate(val)
+			case "crit":
+				r.crit, err = parseRate(val)
+			case "window":
+				if val != "instant" {
+					if r.window, err = time.ParseDuration(val); err == nil && r.window <= 0 {
+						err = fmt.Errorf("invalid window %q", val)
+					}
+				}
+			default:
+				return nil, fmt.Errorf("rule %s: unknown field %q", r.name, k)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("rule %s: %s: %w", r.name, k, err)
+			}
+		}
+		if r.warn <= 0 && r.crit <= 0 {
+			return nil, fmt.Errorf("rule %s: warn or crit is required", r.name)
+		}
+		if r.warn > 0 && r.crit > 0 && r.warn > r.crit {
+			return nil, fmt.Errorf("rule %s: warn is above crit", r.name)
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
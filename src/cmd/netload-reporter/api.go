@@ -0,0 +1,275 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const defaultHistoryWindow = time.Hour
+
+// ---- кольцевой буфер последних отчётов ----
+
+type payloadRing struct {
+	mu   sync.RWMutex
+	buf  []reporter.Payload
+	next int
+	full bool
+}
+
+func newPayloadRing(size int) *payloadRing {
+	if size < 1 {
+		size = 1
+	}
+	return &payloadRing{buf: make([]reporter.Payload, size)}
+}
+
+func (r *payloadRing) push(p reporter.Payload) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.buf[r.next] = p
+	r.next = (r.next + 1) % len(r.buf)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+func (r *payloadRing) latest() (reporter.Payload, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if !r.full && r.next == 0 {
+		return reporter.Payload{}, false
+	}
+	return r.buf[(r.next-1+len(r.buf))%len(r.buf)], true
+}
+
+// since возвращает отчёты не старше from, от старых к новым
+func (r *payloadRing) since(from time.Time) []reporter.Payload {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	start, n := 0, r.next
+	if r.full {
+		start, n = r.next, len(r.buf)
+	}
+	out := make([]reporter.Payload, 0, n)
+	for i := 0; i < n; i++ {
+		p := r.buf[(start+i)%len(r.buf)]
+		if p.Timestamp >= from.Unix() {
+			out = append(out, p)
+		}
+	}
+	return out
+}
+
+// ---- HTTP API: чтение отчётов; запись — тишина и подтверждение алертов, выкладки, получатели ----
+
+// единицы /current: делитель скорости в байтах/с; строчные — биты, с заглавной B — байты (SI)
+var rateUnits = map[string]float64{
+	"bps": 1.0 / 8, "kbps": 1e3 / 8, "mbps": 1e6 / 8, "gbps": 1e9 / 8,
+	"Bps": 1, "KBps": 1e3, "MBps": 1e6, "GBps": 1e9,
+}
+
+func newAPIHandler(cfg *config, ring *payloadRing, stats *selfStats, alerts *alertEngine, deploys *deployTracker, sinks *sinkSet, logs *logRing, writeToken string) http.Handler {
+	mux := http.NewServeMux()
+	if alerts != nil {
+		registerAlertAPI(mux, alerts, writeToken)
+	}
+	if deploys != nil {
+		registerDeployAPI(mux, deploys, writeToken)
+	}
+	if sinks != nil {
+		registerSinkAPI(mux, sinks, writeToken)
+	}
+	mux.HandleFunc("GET /debug/bundle", func(w http.ResponseWriter, r *http.Request) {
+		if privileged(w, r, writeToken) {
+			serveDebugBundle(w, cfg, ring, stats, alerts, sinks, logs)
+		}
+	})
+	mux.HandleFunc("GET /v1/current", func(w http.ResponseWriter, r *http.Request) {
+		pl, ok := ring.latest()
+		if !ok {
+			http.Error(w, "no samples yet", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, pl)
+	})
+	mux.HandleFunc("GET /v1/history", func(w http.ResponseWriter, r *http.Request) {
+		from := time.Time{}
+		if v := r.URL.Query().Get("window"); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil || d <= 0 {
+				http.Error(w, "invalid window", http.StatusBadRequest)
+				return
+			}
+			from = time.Now().Add(-d)
+		}
+		writeHistory(w, ring.since(from))
+	})
+	// одно число текстом для скриптов и MOTD: /current?unit=mbps&iface=eth0&dir=rx
+	mux.HandleFunc("GET /current", func(w http.ResponseWriter, r *http.Request) {
+		pl, ok := ring.latest()
+		if !ok {
+			http.Error(w, "no samples yet", http.StatusServiceUnavailable)
+			return
+		}
+		q := r.URL.Query()
+		div, ok := rateUnits[cmp.Or(q.Get("unit"), "bps")]
+		if !ok {
+			http.Error(w, "unknown unit, want bps, kbps, mbps, gbps, Bps, KBps, MBps or GBps", http.StatusBadRequest)
+			return
+		}
+		rx, tx := pl.RxBytesPerSec, pl.TxBytesPerSec
+		if iface := q.Get("iface"); iface != "" {
+			i := slices.IndexFunc(pl.Interfaces, func(ir reporter.InterfaceRates) bool { return ir.Interface == iface })
+			if i < 0 {
+				http.Error(w, "unknown interface "+iface+" (per-interface rates need INTERFACE_BREAKDOWN)", http.StatusNotFound)
+				return
+			}
+			rx, tx = pl.Interfaces[i].RxBytesPerSec, pl.Interfaces[i].TxBytesPerSec
+		}
+		var v float64
+		switch q.Get("dir") {
+		case "rx":
+			v = rx
+		case "tx":
+			v = tx
+		case "", "total":
+			v = rx + tx
+		default:
+			http.Error(w, "unknown dir, want rx, tx or total", http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		fmt.Fprintf(w, "%.2f\n", v/div)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		ok, available := stats.health()
+		if ok {
+			writeJSON(w, map[string]string{"status": "ok"})
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		writeJSON(w, map[string]any{
+			"status":                "degraded",
+			"no_interfaces_matched": true,
+			"available_interfaces":  available,
+		})
+	})
+	// последний отчёт в формате Prometheus, телеметрия агента — на момент запроса
+	mux.HandleFunc("GET /metrics", func(w http.ResponseWriter, r *http.Request) {
+		pl, ok := ring.latest()
+		if !ok {
+			http.Error(w, "no samples yet", http.StatusServiceUnavailable)
+			return
+		}
+		pl.Telemetry = stats.snapshot(time.Now())
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		if err := reporter.WritePrometheus(w, pl); err != nil {
+			msg.Printf(msg.APIWriteMetrics, err)
+		}
+	})
+	return mux
+}
+
+// privileged — запись и отладка: с API_WRITE_TOKEN (Authorization: Bearer), без него — только с loopback
+func privileged(w http.ResponseWriter, r *http.Request, writeToken string) bool {
+	if writeToken != "" {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+writeToken)) == 1 {
+			return true
+		}
+		http.Error(w, "invalid or missing write token", http.StatusUnauthorized)
+		return false
+	}
+	host, _, _ := net.SplitHostPort(r.RemoteAddr)
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	http.Error(w, "allowed from loopback only (set API_WRITE_TOKEN for remote access)", http.StatusForbidden)
+	return false
+}
+
+// refuseWrites — API профиля readonly: только чтение, запись отказана при любом токене
+func refuseWrites(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			http.Error(w, "writes are refused by the "+profileReadOnly+" security profile", http.StatusForbidden)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil && !errors.Is(err, errResponseTooLarge) {
+		msg.Printf(msg.APIWriteResponse, err)
+	}
+}
+
+// writeHistory пишет массив отчётов по одному: длинная история не собирается в памяти целиком
+func writeHistory(w http.ResponseWriter, pls []reporter.Payload) {
+	w.Header().Set("Content-Type", "application/json")
+	sep := "["
+	for _, pl := range pls {
+		b, err := json.Marshal(pl)
+		if err != nil {
+			msg.Printf(msg.APIWriteResponse, err)
+			return
+		}
+		if _, err := w.Write(append([]byte(sep), b...)); err != nil {
+			return
+		}
+		sep = ","
+	}
+	if sep == "[" {
+		w.Write([]byte("[]\n"))
+		return
+	}
+	w.Write([]byte("]\n"))
+}
+
+func serveAPI(ctx context.Context, cfg *config, ring *payloadRing, stats *selfStats, sinks *sinkSet, logs *logRing) error {
+	addr, limits, traffic := cfg.apiListen, cfg.apiLimits, cfg.source.Traffic
+	h := newAPIHandler(cfg, ring, stats, cfg.alerts, cfg.deploys, sinks, logs, cfg.apiWriteToken)
+	if cfg.readOnly {
+		h = refuseWrites(h)
+	}
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           limitAPI(h, limits),
+		ReadHeaderTimeout: 5 * time.Second,
+		// медленный клиент не держит соединение и горутину сколько угодно
+		ReadTimeout:    30 * time.Second,
+		WriteTimeout:   time.Minute,
+		IdleTimeout:    time.Minute,
+		MaxHeaderBytes: 16 << 10,
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	msg.Printf(msg.APIListening, addr)
+	// ответы сборщикам pull-режима — тоже собственный трафик агента
+	if err := srv.Serve(traffic.Listener(ln)); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+const (
+	defaultAPIRate        = 10 // запросов/с с одного адреса
+	defaultAPIBurst       = 20
+	defaultAPIInflight    = 8
+	defaultAPIMaxResponse = 8 << 20
+
+	// адресов с отдельным счётом; сверх — общий на всех новых, чтобы перебор адресов не раздувал память
+	maxAPIClients = 4096
+	// сводка о сброшенных запросах — не чаще, чем раз в это время
+	apiThrottleReport = time.Minute
+)
+
+var errResponseTooLarge = errors.New("response exceeds API_MAX_RESPONSE")
+
+// apiLimits — защита встроенного API от усердного скрейпера: запросы сверх rate/burst с одного
+// адреса (IPv6 — с /64) получают 429, сверх inflight одновременно — 503, ответ длиннее
+// maxResponse обрывается. Агент работает на боевом узле, и API не должен отнимать у него CPU и память
+type apiLimits struct {
+	rate        float64 // 0 — без ограничения
+	burst       float64
+	inflight    int
+	maxResponse int64
+}
+
+type apiBucket struct {
+	tokens float64
+	at     time.Time
+}
+
+// apiLimiter — token bucket на адрес клиента
+type apiLimiter struct {
+	apiLimits
+	slots chan struct{}
+
+	mu        sync.Mutex
+	clients   map[string]*apiBucket
+	throttled int
+	reported  time.Time
+}
+
+func limitAPI(h http.Handler, l apiLimits) http.Handler {
+	lim := &apiLimiter{apiLimits: l, slots: make(chan struct{}, l.inflight), clients: make(map[string]*apiBucket)}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if wait, ok := lim.allow(clientKey(r.RemoteAddr), time.Now()); !ok {
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			http.Error(w, "too many requests", http.StatusTooManyRequests)
+			return
+		}
+		select {
+		case lim.slots <- struct{}{}:
+			defer func() { <-lim.slots }()
+		default:
+			lim.count(time.Now())
+			w.Header().Set("Retry-After", "1")
+			http.Error(w, "too many concurrent requests", http.StatusServiceUnavailable)
+			return
+		}
+		// архив отладки по размеру не предсказать, и нужен он как раз когда агенту плохо
+		if r.URL.Path == "/debug/bundle" {
+			h.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(&cappedWriter{ResponseWriter: w, left: l.maxResponse}, r)
+	})
+}
+
+// clientKey — адрес клиента без порта; IPv6 — по /64, которые обычно выдают целиком
+func clientKey(remote string) string {
+	host, _, _ := net.SplitHostPort(remote)
+	ip, err := netip.ParseAddr(host)
+	if err != nil {
+		return host
+	}
+	if ip = ip.Unmap(); ip.Is6() {
+		p, _ := ip.Prefix(64)
+		return p.String()
+	}
+	return ip.String()
+}
+
+// allow списывает токен клиента; при отказе — через сколько появится следующий
+func (l *apiLimiter) allow(key string, now time.Time) (time.Duration, bool) {
+	if l.rate <= 0 {
+		return 0, true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	b := l.clients[key]
+	if b == nil {
+		l.prune(now)
+		if len(l.clients) >= maxAPIClients {
+			key = ""
+		}
+		if b = l.clients[key]; b == nil {
+			b = &apiBucket{tokens: l.burst, at: now}
+			l.clients[key] = b
+		}
+	}
+	b.tokens = math.Min(l.burst, b.tokens+now.Sub(b.at).Seconds()*l.rate)
+	b.at = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	l.countLocked(now)
+	return time.Duration((1 - b.tokens) / l.rate * float64(time.Second)), false
+}
+
+// prune забывает клиентов, чей счёт уже восстановился: они ничем не отличаются от новых
+func (l *apiLimiter) prune(now time.Time) {
+	full := time.Duration(l.burst / l.rate * float64(time.Second))
+	for k, b := range l.clients {
+		if now.Sub(b.at) >= full {
+			delete(l.clients, k)
+		}
+	}
+}
+
+func (l *apiLimiter) count(now time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.countLocked(now)
+}
+
+// countLocked считает отказ; сводка в журнал — раз в apiThrottleReport, а не на каждый запрос
+func (l *apiLimiter) countLocked(now time.Time) {
+	l.throttled++
+	if now.Sub(l.reported) < apiThrottleReport {
+		return
+	}
+	msg.Warnf(msg.APIThrottled, l.throttled, len(l.clients))
+	l.throttled, l.reported = 0, now
+}
+
+// cappedWriter обрывает ответ на maxResponse байтах: если ещё ничего не ушло — 503 целиком,
+// иначе клиент получит оборванное тело и ошибку, а не похожий на правду урезанный JSON
+type cappedWriter struct {
+	http.ResponseWriter
+	left  int64
+	wrote bool
+}
+
+func (w *cappedWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *cappedWriter) WriteHeader(code int) {
+	w.wrote = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *cappedWriter) Write(b []byte) (int, error) {
+	if int64(len(b)) > w.left {
+		if !w.wrote {
+			http.Error(w.ResponseWriter, errResponseTooLarge.Error(), http.StatusServiceUnavailable)
+			w.wrote, w.left = true, 0
+			return 0, errResponseTooLarge
+		}
+		panic(http.ErrAbortHandler)
+	}
+	w.wrote = true
+	w.left -= int64(len(b))
+	return w.ResponseWriter.Write(b)
+}
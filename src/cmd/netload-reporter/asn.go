@@ -0,0 +1,89 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/asn"
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+	"github.com/iflixer/network-stater/src/pkg/subnets"
+)
+
+const defaultASNTop = 10
+
+// asnWatch считает трафик по origin ASN удалённого адреса: каждая ASN — группа
+// в eBPF-учёте subnets, в отчёт идут top самых нагруженных
+type asnWatch struct {
+	path string
+	top  int
+
+	acct   *subnets.Accounting
+	asns   map[string]uint32 // имя группы -> ASN
+	prev   map[string]collector.Counters
+	prevAt time.Time
+}
+
+func (w *asnWatch) open(cgroupPath string, now time.Time) error {
+	started := time.Now()
+	table, err := asn.Load(w.path)
+	if err != nil {
+		return err
+	}
+	groups := make([]subnets.Group, 0, len(table))
+	w.asns = make(map[string]uint32, len(table)+1)
+	for _, a := range table.ASNs() {
+		name := "AS" + strconv.FormatUint(uint64(a), 10)
+		groups = append(groups, subnets.Group{Name: name, Prefixes: table[a]})
+		w.asns[name] = a
+	}
+	w.asns[subnets.OtherGroup] = 0
+	if w.acct, err = subnets.Open(groups, cgroupPath); err != nil {
+		return err
+	}
+	if w.prev, err = w.acct.Read(); err != nil {
+		w.acct.Close()
+		return err
+	}
+	w.prevAt = now
+	msg.Printf(msg.ASNLoaded,
+		table.Prefixes(), len(table), w.path, time.Since(started).Round(time.Millisecond))
+	return nil
+}
+
+func (w *asnWatch) Close() error {
+	return w.acct.Close()
+}
+
+// observe — top ASN по сумме rx+tx за интервал; ASN без трафика не попадают. Прошлые счётчики
+// сдвигает только advance: если чтение опоздало и результат отброшен, следующий интервал
+// посчитается от тех же prev и трафик не потеряется
+func (w *asnWatch) observe(now time.Time) (top []reporter.ASNRates, advance func(), err error) {
+	cur, err := w.acct.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	sec := now.Sub(w.prevAt).Seconds()
+	var out []reporter.ASNRates
+	for name, c := range cur {
+		p := w.prev[name]
+		rx, tx := collector.Delta(c.Rx, p.Rx)/sec, collector.Delta(c.Tx, p.Tx)/sec
+		if rx+tx == 0 {
+			continue
+		}
+		out = append(out, reporter.ASNRates{ASN: w.asns[name], RxBytesPerSec: rx, TxBytesPerSec: tx, RxBitsPerSec: rx * 8, TxBitsPerSec: tx * 8})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i].RxBytesPerSec+out[i].TxBytesPerSec, out[j].RxBytesPerSec+out[j].TxBytesPerSec
+		if a != b {
+			return a > b
+		}
+		return out[i].ASN < out[j].ASN
+	})
+	if len(out) > w.top {
+		out = out[:w.top]
+	}
+	return out, func() { w.prev, w.prevAt = cur, now }, nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/enrich"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// профиль безопасности (SECURITY_PROFILE)
+const (
+	profileStandard = "standard"
+	// только чтение /proc: для окружений, где агенту не дают ни capability, ни sysfs, ни сырых сокетов
+	profileReadOnly = "readonly"
+)
+
+// auditEntry — строка аудита: что агент собирает, каким доступом и в каком состоянии
+type auditEntry struct {
+	feature string
+	access  string
+	state   string // allowed, refused, off
+}
+
+// lockDown применяет профиль readonly до проверки возможностей ядра — сами проверки
+// eBPF и netlink тоже требуют лишнего — и печатает аудит в stdout: под кем работает процесс,
+// какие коллекторы включены и какие отказаны. Отказ — как выключение при недоступности:
+// агент работает без коллектора
+func lockDown(cfg *config) {
+	var audit []auditEntry
+	add := func(feature, access string, requested, allowed bool) bool {
+		state := "off"
+		switch {
+		case requested && allowed:
+			state = "allowed"
+		case requested:
+			state = "refused"
+			msg.Warnf(msg.ProfileRefused, feature, profileReadOnly, access)
+		}
+		audit = append(audit, auditEntry{feature: feature, access: access, state: state})
+		return requested && allowed
+	}
+	p := cfg.paths
+	orDefault := func(path, def string) string {
+		if path == "" {
+			return def
+		}
+		return path
+	}
+
+	switch cfg.netdevSource {
+	case collectorFake, collectorReplay:
+		add("interface counters ("+cfg.netdevSource+")", "none", true, true)
+	case netdevNetlink:
+		add("NETDEV_SOURCE=netlink", "netlink socket", true, false)
+		fallthrough
+	default:
+		cfg.netdevSource = netdevProc
+		add("interface counters", "read "+orDefault(p.netDev, collector.DefaultProcNetDev), true, true)
+	}
+	add("IP_FAMILY_STATS", "read "+orDefault(p.netstat, collector.DefaultProcNetNetstat), cfg.ipFamily, true)
+	add("CONN_STATS", "read "+orDefault(p.conn.Sockstat, collector.DefaultProcNetSockstat)+", "+orDefault(p.conn.Snmp, collector.DefaultProcNetSnmp), cfg.connStats, true)
+	add("CLOCK_INFO", "read /proc/uptime, /proc/sys/kernel/random/boot_id", cfg.clockInfo, true)
+	add("METERED=auto", "read "+orDefault(p.route, collector.DefaultProcNetRoute), cfg.metered.mode == meteredAuto, true)
+	// sysfs вне /proc; без топологии члены bond/bridge считаются вместе с master-ом
+	cfg.includeMembers = !add("bond/bridge member exclusion", "read sysfs "+orDefault(p.sysClassNet, collector.DefaultSysClassNet), !cfg.includeMembers, false)
+	if !add("POWER_MODE=auto", "read sysfs "+orDefault(p.powerSupply, collector.DefaultPowerSupplyPath), cfg.power.mode == powerAuto, false) && cfg.power.mode == powerAuto {
+		cfg.power.mode = powerOff
+	}
+	cfg.linkSpeed = add("LINK_SPEED", "read sysfs "+orDefault(p.sysClassNet, collector.DefaultSysClassNet), cfg.linkSpeed, false)
+	if cfg.lldp = add("LLDP/CDP", "raw socket (CAP_NET_RAW)", cfg.lldp || cfg.cdp, false); !cfg.lldp {
+		cfg.cdp = false
+	}
+	// проверочные порты принимают соединения из интернета — лишняя поверхность атаки для профиля
+	if !add("REACHABILITY_PORTS", "listening sockets", len(cfg.reachPorts) > 0, false) {
+		cfg.reachPorts = nil
+	}
+	// что делает свой коллектор, профиль проверить не может
+	if !add("PLUGINS", "third-party collector code", len(cfg.plugins) > 0, false) {
+		cfg.plugins = nil
+	}
+	const ebpf = "eBPF cgroup_skb (CAP_BPF, CAP_NET_ADMIN)"
+	if !add("SUBNET_GROUPS", ebpf, len(cfg.subnetGroups) > 0, false) {
+		cfg.subnetGroups = nil
+	}
+	if !add("PORT_GROUPS", ebpf, len(cfg.portGroups) > 0, false) {
+		cfg.portGroups = nil
+	}
+	if !add("UPSTREAMS", ebpf, cfg.upstreams != nil, false) {
+		cfg.upstreams = nil
+	}
+	if !add("ASN_TABLE", ebpf, cfg.asns != nil, false) {
+		cfg.asns = nil
+	}
+	// внешняя команда — тот же чужой код, что и свой коллектор
+	var exec, kubeStage bool
+	for _, s := range cfg.enrich.Steps {
+		exec = exec || s.Stage.Name() == enrichExec
+		kubeStage = kubeStage || s.Stage.Name() == enrichKubernetes
+	}
+	command := "external command"
+	if f := strings.Fields(os.Getenv("ENRICH_EXEC")); len(f) > 0 {
+		command += " " + f[0]
+	}
+	if !add("ENRICH exec", command, exec, false) {
+		cfg.enrich.Steps = slices.DeleteFunc(cfg.enrich.Steps, func(s *enrich.Step) bool { return s.Stage.Name() == enrichExec })
+	}
+	// запись через API меняет поведение агента: тишина алертов, выкладки, получатели отчётов
+	add("API_LISTEN writes", "silences, deploy marks, sinks over HTTP", cfg.apiListen != "", false)
+
+	// сеть и файлы — не коллекторы, но тоже доступ сверх /proc
+	const kubeAPI = "Kubernetes API (service account token)"
+	add("ENRICH kubernetes", kubeAPI+": read Node", kubeStage, true)
+	add("K8S_NODE_PUBLISH", kubeAPI+": patch Node", cfg.kube != nil, true)
+	add("DEPLOY_ANNOTATION", kubeAPI+": read Node", cfg.deploys.annotation != "", true)
+	add("STATE_DIR", "write "+cfg.stateDir, cfg.stateDir != "", true)
+	var storeDir string
+	if cfg.store != nil {
+		storeDir = cfg.store.dir
+	}
+	add("STORE_DIR", "write "+storeDir, cfg.store != nil, true)
+
+	// аудит — в stdout отдельно от журнала: его собирают и хранят иначе
+	id, err := collector.ReadProcessIdentity("")
+	if err != nil {
+		fmt.Printf("audit: profile=%s identity unavailable: %v\n", profileReadOnly, err)
+	} else {
+		caps := "none"
+		if len(id.Capabilities) > 0 {
+			caps = strings.Join(id.Capabilities, ",")
+		}
+		fmt.Printf("audit: profile=%s uid=%s gid=%s capabilities=%s\n", profileReadOnly, id.UID, id.GID, caps)
+		// профилю не нужно ничего сверх чтения /proc: лишнее — повод урезать права процесса
+		if id.UID == "0" || len(id.Capabilities) > 0 {
+			fmt.Printf("audit: WARNING: process has more privileges than the %s profile needs\n", profileReadOnly)
+		}
+	}
+	for _, e := range audit {
+		fmt.Printf("audit: %-8s %-30s %s\n", e.state, e.feature, e.access)
+	}
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const (
+	bootstrapTXTPrefix = "_netload-reporter."
+	bootstrapTXTTag    = "v=netload1"
+	bootstrapWellKnown = "/.well-known/netload-reporter.env"
+	bootstrapCacheFile = "bootstrap.env"
+	bootstrapResolv    = "/etc/resolv.conf"
+
+	defaultBootstrapTimeout = 10 * time.Minute
+	bootstrapRetryMax       = time.Minute
+)
+
+// что можно получить при начальной настройке: куда слать и как регистрироваться.
+// Пути к файлам и ключи TLS не принимаем — источник в DNS подделать проще, чем узел
+var bootstrapKeys = map[string]bool{
+	"REPORT_URL": true, "EVENTS_URL": true, "CANARY_URL": true, "CANARY_RATIO": true,
+	"REPORT_ENCODING": true, "API_KEY": true, "ENROLL_URL": true, "SIGN_REPORTS": true,
+	"EXPORTER": true, "NATS_URL": true, "NATS_SUBJECT": true, "KAFKA_BROKERS": true, "KAFKA_TOPIC": true,
+	"INTERVAL": true, "LABELS": true,
+}
+
+// bootstrap — настройка без конфигурации для свежеустановленного узла: по домену узла
+// ищет TXT-запись _netload-reporter.<домен> ("v=netload1 REPORT_URL=... ENROLL_URL=...")
+// или файл https://<домен>/.well-known/netload-reporter.env (формат .env), поднимаясь
+// к родительским доменам. Найденное дополняет окружение (заданное явно не перетирается)
+// и сохраняется в STATE_DIR: при перезапуске без сети агент берёт последнюю настройку.
+// Пока источник недоступен (сеть ещё поднимается), повторяет до BOOTSTRAP_TIMEOUT.
+// С ENROLL_URL создаёт ключ и регистрирует его один раз
+func bootstrap() error {
+	mode := os.Getenv("BOOTSTRAP")
+	// без единой настройки доставки узел ничего не знает — пробуем найти сами, но один раз:
+	// долгое ожидание только при явном BOOTSTRAP
+	implicit := mode == ""
+	if implicit {
+		mode = "off"
+		if os.Getenv("REPORT_URL") == "" && os.Getenv("API_LISTEN") == "" && os.Getenv("EXPORTER") == "" {
+			mode = "auto"
+		}
+	}
+	switch mode {
+	case "off":
+		return nil
+	case "auto", "dns", "url":
+	default:
+		return fmt.Errorf("BOOTSTRAP: unknown mode %q", mode)
+	}
+
+	cache := filepath.Join(envString("STATE_DIR", "."), bootstrapCacheFile)
+	domain := os.Getenv("BOOTSTRAP_DOMAIN")
+	if domain == "" {
+		domain = hostDomain()
+	}
+	client, err := bootstrapClient()
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(envDuration("BOOTSTRAP_TIMEOUT", defaultBootstrapTimeout))
+	var vals map[string]string
+	for wait := 5 * time.Second; ; wait = min(wait*2, bootstrapRetryMax) {
+		var source string
+		var err error
+		switch url := os.Getenv("BOOTSTRAP_URL"); {
+		case url != "":
+			vals, err = fetchBootstrap(client, url)
+			source = url
+		case domain == "":
+			err = errors.New("cannot determine host domain, set BOOTSTRAP_DOMAIN")
+		default:
+			vals, source, err = discover(client, mode, domain)
+		}
+		if err == nil {
+			msg.Printf(msg.BootstrapSource, source)
+			for k := range vals {
+				if !bootstrapKeys[k] {
+					msg.Warnf(msg.BootstrapIgnored, k)
+					delete(vals, k)
+				}
+			}
+			if err := godotenv.Write(vals, cache); err != nil {
+				msg.Warnf(msg.BootstrapSave, cache, err)
+			}
+			break
+		}
+		// последняя удачная настройка лучше ожидания: сеть могла просто не подняться
+		if cached, cerr := godotenv.Read(cache); cerr == nil {
+			msg.Warnf(msg.BootstrapCached, err, cache)
+			vals = cached
+			break
+		}
+		if implicit {
+			msg.Printf(msg.BootstrapSkipped, err)
+			return nil
+		}
+		if time.Now().Add(wait).After(deadline) {
+			return fmt.Errorf("bootstrap: %w", err)
+		}
+		msg.Warnf(msg.BootstrapRetry, err, wait)
+		time.Sleep(wait)
+	}
+	applyBootstrap(vals)
+
+	if url := os.Getenv("ENROLL_URL"); url != "" {
+		autoEnroll(url)
+	}
+	return nil
+}
+
+// discover перебирает домен и его родителей: сначала DNS, потом well-known URL
+func discover(client *http.Client, mode, domain string) (map[string]string, string, error) {
+	var errs []error
+	if mode != "url" {
+		for _, d := range domainSuffixes(domain) {
+			name := bootstrapTXTPrefix + d
+			vals, err := lookupBootstrapTXT(name)
+			if err == nil {
+				return vals, "dns:" + name, nil
+			}
+			errs = append(errs, err)
+		}
+	}
+	if mode != "dns" {
+		for _, d := range domainSuffixes(domain) {
+			url := "https://" + d + bootstrapWellKnown
+			vals, err := fetchBootstrap(client, url)
+			if err == nil {
+				return vals, url, nil
+			}
+			errs = append(errs, err)
+		}
+	}
+	return nil, "", errors.Join(errs...)
+}
+
+func lookupBootstrapTXT(name string) (map[string]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	records, err := net.DefaultResolver.LookupTXT(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		// запись "v=netload1 KEY=value ...": значения без пробелов, метка отсекает чужие TXT
+		f := strings.Fields(r)
+		if len(f) < 2 || f[0] != bootstrapTXTTag {
+			continue
+		}
+		vals, err := godotenv.Unmarshal(strings.Join(f[1:], "\n"))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		return vals, nil
+	}
+	return nil, fmt.Errorf("%s: no %s record", name, bootstrapTXTTag)
+}
+
+// bootstrapClient учитывает PROXY_URL и SOURCE_*: на свежем узле выход наружу может быть
+// только через прокси или сеть управления
+func bootstrapClient() (*http.Client, error) {
+	proxy, err := envProxy()
+	if err != nil {
+		return nil, err
+	}
+	src, err := envSource()
+	if err != nil {
+		return nil, err
+	}
+	return reporter.NewSourceHTTPClient(defaultConnectTimeout, defaultResponseTimeout, proxy, src), nil
+}
+
+func fetchBootstrap(client *http.Client, url string) (map[string]string, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return nil, err
+	}
+	vals, err := godotenv.Unmarshal(string(body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", url, err)
+	}
+	return vals, nil
+}
+
+// applyBootstrap переносит разрешённые ключи в окружение, не трогая заданные явно
+func applyBootstrap(vals map[string]string) {
+	// источник начальной настройки обновляют не одновременно с агентами
+	if err := migrateValues(vals, "bootstrap"); err != nil {
+		msg.Warnf(msg.BootstrapSkipped, err)
+	}
+	var applied []string
+	for k, v := range vals {
+		if bootstrapKeys[k] && os.Getenv(k) == "" {
+			os.Setenv(k, v)
+			applied = append(applied, k)
+		}
+	}
+	sort.Strings(applied)
+	msg.Printf(msg.BootstrapApplied, strings.Join(applied, ","))
+}
+
+// autoEnroll создаёт ключ и регистрирует его на url, если ещё не регистрировал там;
+// отметка о регистрации лежит рядом с ключом. Неудача не мешает работе — повтор при следующем запуске
+func autoEnroll(url string) {
+	keyPath := signingKeyPath()
+	marker := keyPath + ".enrolled"
+	if os.Getenv("SIGN_REPORTS") == "" {
+		os.Setenv("SIGN_REPORTS", "true")
+	}
+	if b, err := os.ReadFile(marker); err == nil && strings.TrimSpace(string(b)) == url {
+		return
+	}
+	signer, created, err := loadOrGenerateKey(keyPath)
+	if err != nil {
+		msg.Warnf(msg.BootstrapKeyError, err)
+		return
+	}
+	if created {
+		msg.Printf(msg.BootstrapKeyNew, keyPath)
+	}
+	if err := register(signer, url); err != nil {
+		msg.Warnf(msg.BootstrapEnrollErr, url, err)
+		return
+	}
+	if err := os.WriteFile(marker, []byte(url+"\n"), 0o644); err != nil {
+		msg.Warnf(msg.BootstrapMarker, err)
+	}
+	msg.Printf(msg.BootstrapEnrolled, signer.KeyID, url)
+}
+
+// hostDomain — домен узла: из полного имени хоста, иначе domain/search из resolv.conf
+func hostDomain() string {
+	if h, err := os.Hostname(); err == nil {
+		if _, d, ok := strings.Cut(h, "."); ok && d != "" {
+			return strings.TrimSuffix(d, ".")
+		}
+	}
+	b, err := os.ReadFile(bootstrapResolv)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		f := strings.Fields(line)
+		if len(f) >= 2 && (f[0] == "domain" || f[0] == "search") {
+			return strings.TrimSuffix(f[1], ".")
+		}
+	}
+	return ""
+}
+
+// domainSuffixes: rack1.dc1.example.com → rack1.dc1.example.com, dc1.example.com, example.com
+func domainSuffixes(domain string) []string {
+	var out []string
+	for d := domain; strings.Count(d, ".") >= 1; {
+		out = append(out, d)
+		_, d, _ = strings.Cut(d, ".")
+	}
+	if len(out) == 0 && domain != "" {
+		out = append(out, domain)
+	}
+	return out
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const (
+	// провайдеры снимают 5-минутные средние и считают, сколько из них выше базовой скорости
+	defaultBurstSample    = 5 * time.Minute
+	defaultBurstAllowance = "5%"
+	defaultBurstAlertPct  = 80
+)
+
+// направление, по которому провайдер считает burst (BURST_DIRECTION)
+const (
+	burstMax   = "max" // большее из rx и tx — как в 95-м перцентиле
+	burstRx    = "rx"
+	burstTx    = "tx"
+	burstTotal = "total"
+)
+
+type burstState struct {
+	Month       string  `json:"month"`
+	Slots       int     `json:"burst_slots"`
+	ExcessBytes float64 `json:"excess_bytes"`
+	Alerted     bool    `json:"alerted"`
+	Exhausted   bool    `json:"exhausted"`
+	// открытый слот
+	SlotStart   int64   `json:"slot_start"`
+	SlotRx      float64 `json:"slot_rx"`
+	SlotTx      float64 `json:"slot_tx"`
+	SlotSeconds float64 `json:"slot_seconds"`
+}
+
+// burstTracker — расход burst-бюджета тарифа «база + burst» (например, 1G с burst до 10G):
+// расчётный период — календарный месяц UTC, поделённый на слоты BURST_SAMPLE; слот, средняя
+// скорость которого выше BURST_BASE_BPS, расходует бюджет целиком. Бюджет — BURST_ALLOWANCE,
+// доля периода ("5%" — как 95-й перцентиль) или время ("36h")
+type burstTracker struct {
+	base, ceiling float64 // бит/с
+	sample        time.Duration
+	allowPct      float64 // доля периода; 0 — задано временем
+	allowTime     time.Duration
+	direction     string
+	alertPct      float64
+	path          string // пусто — без сохранения между перезапусками
+	state         burstState
+}
+
+func newBurstTracker(b *burstTracker, stateDir string) (*burstTracker, error) {
+	if stateDir == "" {
+		return b, nil
+	}
+	b.path = filepath.Join(stateDir, "burst.json")
+	raw, err := os.ReadFile(b.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &b.state); err != nil {
+		return nil, fmt.Errorf("%s: %w", b.path, err)
+	}
+	return b, nil
+}
+
+// parseBurstAllowance: "5%" — доля расчётного периода, "36h" — время
+func parseBurstAllowance(v string) (pct float64, d time.Duration, err error) {
+	if p, ok := strings.CutSuffix(v, "%"); ok {
+		pct, err = strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil || pct <= 0 || pct > 100 {
+			return 0, 0, fmt.Errorf("invalid allowance %q", v)
+		}
+		return pct / 100, 0, nil
+	}
+	if d, err = time.ParseDuration(v); err != nil || d <= 0 {
+		return 0, 0, fmt.Errorf("invalid allowance %q, want percent or duration", v)
+	}
+	return 0, d, nil
+}
+
+func (b *burstTracker) pick(rx, tx float64) float64 {
+	switch b.direction {
+	case burstRx:
+		return rx
+	case burstTx:
+		return tx
+	case burstTotal:
+		return rx + tx
+	}
+	return math.Max(rx, tx)
+}
+
+// allowed — бюджет периода, с
+func (b *burstTracker) allowed(month string) float64 {
+	if b.allowPct == 0 {
+		return b.allowTime.Seconds()
+	}
+	start, _ := time.Parse("2006-01", month)
+	return start.AddDate(0, 1, 0).Sub(start).Seconds() * b.allowPct
+}
+
+// add учитывает интервал, закрывает слот при переходе границы и возвращает расход бюджета;
+// rxBps, txBps — скорости интервала в байт/с (для запаса прямо сейчас)
+func (b *burstTracker) add(ctx context.Context, bus *reporter.EventBus, now time.Time, drx, dtx float64, dt time.Duration, rxBps, txBps float64) *reporter.BurstUsage {
+	slot := now.Truncate(b.sample).Unix()
+	s := &b.state
+	if s.SlotStart != slot {
+		if s.SlotSeconds > 0 {
+			b.closeSlot(ctx, bus)
+		}
+		s.SlotStart, s.SlotRx, s.SlotTx, s.SlotSeconds = slot, 0, 0, 0
+	}
+	s.SlotRx += drx
+	s.SlotTx += dtx
+	s.SlotSeconds += dt.Seconds()
+	if key := monthKey(now); s.Month != key {
+		b.reset(key)
+	}
+
+	allowed := b.allowed(s.Month)
+	used := float64(s.Slots) * b.sample.Seconds()
+	u := &reporter.BurstUsage{
+		BurstPeriod:            s.Month,
+		BurstBaseBitsPerSec:    b.base,
+		BurstCeilingBitsPerSec: b.ceiling,
+		BurstSecondsUsed:       used,
+		BurstSecondsAllowed:    allowed,
+		BurstSecondsRemaining:  math.Max(allowed-used, 0),
+		BurstExcessBytes:       uint64(s.ExcessBytes),
+		BurstExhausted:         s.Exhausted,
+		// текущий слот пока выше базы: если так и закроется, спишет ещё один слот
+		BurstActive: b.pick(s.SlotRx, s.SlotTx)*8/s.SlotSeconds > b.base,
+	}
+	if allowed > 0 {
+		u.BurstBudgetUsedPct = used / allowed * 100
+	}
+	limit := b.ceiling
+	if s.Exhausted {
+		limit = b.base
+	}
+	u.BurstHeadroomBitsPerSec = math.Max(limit-b.pick(rxBps, txBps)*8, 0)
+	if err := b.save(); err != nil {
+		msg.Errorf(msg.BurstSave, b.path, err)
+	}
+	return u
+}
+
+func (b *burstTracker) reset(month string) {
+	s := &b.state
+	s.Month, s.Slots, s.ExcessBytes, s.Alerted, s.Exhausted = month, 0, 0, false, false
+}
+
+func (b *burstTracker) closeSlot(ctx context.Context, bus *reporter.EventBus) {
+	s := &b.state
+	start := time.Unix(s.SlotStart, 0)
+	// слот принадлежит периоду, в котором начался
+	if key := monthKey(start); s.Month != key {
+		b.reset(key)
+	}
+	bytes := b.pick(s.SlotRx, s.SlotTx)
+	if bytes*8/s.SlotSeconds <= b.base {
+		return
+	}
+	s.Slots++
+	s.ExcessBytes += math.Max(bytes-b.base/8*s.SlotSeconds, 0)
+
+	allowed := b.allowed(s.Month)
+	used := float64(s.Slots) * b.sample.Seconds()
+	pct := used / allowed * 100
+	switch {
+	case pct >= 100 && !s.Exhausted:
+		s.Exhausted, s.Alerted = true, true
+		emit(ctx, bus, reporter.Event{
+			Type:    "burst_budget_exhausted",
+			Message: msg.Text(msg.BurstExhausted, s.Month, time.Duration(used)*time.Second, formatBits(b.base/8)),
+			Data:    map[string]any{"period": s.Month, "burst_seconds_used": used, "burst_seconds_allowed": allowed},
+		})
+	case pct >= b.alertPct && !s.Alerted:
+		s.Alerted = true
+		emit(ctx, bus, reporter.Event{
+			Type:    "burst_budget_low",
+			Message: msg.Text(msg.BurstLow, s.Month, pct, time.Duration(used)*time.Second, time.Duration(allowed)*time.Second),
+			Data:    map[string]any{"period": s.Month, "burst_seconds_used": used, "burst_seconds_allowed": allowed, "used_pct": pct},
+		})
+	}
+}
+
+func (b *burstTracker) save() error {
+	if b.path == "" {
+		return nil
+	}
+	return writeFileAtomic(b.path, b.state)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/subnets"
+)
+
+// источник счётчиков интерфейсов (NETDEV_SOURCE)
+const (
+	netdevProc    = "proc"
+	netdevNetlink = "netlink"
+	netdevAuto    = "auto"
+)
+
+// probeCapabilities проверяет возможности ядра один раз при старте, печатает отчёт
+// и выключает коллекторы, которым чего-то не хватает, вместо ошибок на каждом интервале
+func probeCapabilities(cfg *config) {
+	p := cfg.paths
+	orDefault := func(path, def string) string {
+		if path == "" {
+			return def
+		}
+		return path
+	}
+	netfilter := orDefault(p.conn.Netfilter, collector.DefaultNetfilterPath)
+
+	procDev := collector.ProbeFile("/proc/net/dev", orDefault(p.netDev, collector.DefaultProcNetDev))
+	// в профиле readonly не трогаем ничего, кроме /proc: ни netlink, ни sysfs, ни bpf()
+	skip := func(name string) collector.Capability {
+		return collector.Capability{Name: name, Detail: "not probed (" + profileReadOnly + " profile)"}
+	}
+	if cfg.readOnly {
+		msg.Printf(msg.CapabilitiesTitle)
+		for _, c := range []collector.Capability{procDev, skip("netlink stats64"), skip("bond/bridge topology"), skip("eBPF cgroup_skb")} {
+			msg.Printf(msg.CapabilityRow, c.Name, yesNo(c.OK), c.Detail)
+		}
+		msg.Printf(msg.CountersSource, cfg.netdevSource)
+		return
+	}
+	stats64 := collector.ProbeStats64()
+	netstat := collector.ProbeFile("IpExt counters", orDefault(p.netstat, collector.DefaultProcNetNetstat))
+	snmp6 := collector.ProbeFile("IPv6 counters", orDefault(p.snmp6, collector.DefaultProcNetSnmp6))
+	sockstat := collector.ProbeFile("TCP sockstat", orDefault(p.conn.Sockstat, collector.DefaultProcNetSockstat))
+	snmp := collector.ProbeFile("TCP snmp", orDefault(p.conn.Snmp, collector.DefaultProcNetSnmp))
+	conntrack := collector.ProbeFile("conntrack table", filepath.Join(netfilter, "nf_conntrack_count"))
+	acct := collector.ProbeConntrackAcct(netfilter)
+	topo := collector.ProbeTopology(orDefault(p.sysClassNet, collector.DefaultSysClassNet))
+	bpf := collector.Capability{Name: "eBPF cgroup_skb", OK: true, Detail: "supported"}
+	if err := subnets.Supported(); err != nil {
+		bpf.OK, bpf.Detail = false, err.Error()
+	} else if err := subnets.CheckCapabilities(); err != nil {
+		bpf.OK, bpf.Detail = false, err.Error()
+	}
+
+	msg.Printf(msg.CapabilitiesTitle)
+	for _, c := range []collector.Capability{procDev, stats64, netstat, snmp6, sockstat, snmp, conntrack, acct, topo, bpf} {
+		msg.Printf(msg.CapabilityRow, c.Name, yesNo(c.OK), c.Detail)
+	}
+
+	disable := func(feature string, c collector.Capability) {
+		msg.Warnf(msg.FeatureMissing, feature, c.Name, c.Detail)
+	}
+	switch cfg.netdevSource {
+	case collectorFake, collectorReplay:
+	case netdevAuto:
+		cfg.netdevSource = netdevProc
+		if stats64.OK {
+			cfg.netdevSource = netdevNetlink
+		}
+	case netdevNetlink:
+		if !stats64.OK {
+			disable("NETDEV_SOURCE=netlink", stats64)
+			cfg.netdevSource = netdevProc
+		}
+	}
+	msg.Printf(msg.CountersSource, cfg.netdevSource)
+	if cfg.ipFamily && !netstat.OK {
+		disable("IP_FAMILY_STATS", netstat)
+		cfg.ipFamily = false
+	}
+	if cfg.connStats && (!sockstat.OK || !snmp.OK) {
+		c := sockstat
+		if c.OK {
+			c = snmp
+		}
+		disable("CONN_STATS", c)
+		cfg.connStats = false
+	}
+	if !cfg.includeMembers && !topo.OK {
+		disable("bond/bridge member exclusion", topo)
+		cfg.includeMembers = true
+	}
+	if len(cfg.subnetGroups) > 0 && !bpf.OK {
+		disable("SUBNET_GROUPS", bpf)
+		cfg.subnetGroups = nil
+	}
+	if len(cfg.portGroups) > 0 && !bpf.OK {
+		disable("PORT_GROUPS", bpf)
+		cfg.portGroups = nil
+	}
+	if cfg.upstreams != nil && !bpf.OK {
+		disable("UPSTREAMS", bpf)
+		cfg.upstreams = nil
+	}
+	if cfg.asns != nil && !bpf.OK {
+		disable("ASN_TABLE", bpf)
+		cfg.asns = nil
+	}
+}
+
+func yesNo(ok bool) string {
+	if ok {
+		return "yes"
+	}
+	return "no"
+}
+
+func netdevSource(cfg *config) collector.Source {
+	if cfg.simulated != nil {
+		return cfg.simulated
+	}
+	if cfg.netdevSource == netdevNetlink {
+		return collector.NetlinkStats64{}
+	}
+	return collector.ProcNetDev{Path: cfg.paths.netDev}
+}
+
+func validNetdevSource(s string) error {
+	if s != netdevProc && s != netdevNetlink && s != netdevAuto {
+		return fmt.Errorf("NETDEV_SOURCE: unknown source %q", s)
+	}
+	return nil
+}
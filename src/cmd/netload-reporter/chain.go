@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+type chainState struct {
+	Index uint64 `json:"index"`
+	Hash  string `json:"hash"`
+}
+
+// hashChain связывает отчёты, прошедшие политики отправки, в цепочку хешей (HASH_CHAIN).
+// Звено сохраняется до отправки: отчёт, потерянный в очереди или при падении, сервер
+// увидит разрывом, а не молчанием
+type hashChain struct {
+	path  string // пусто — цепочка начинается заново при каждом запуске
+	state chainState
+}
+
+func newHashChain(stateDir string) (*hashChain, error) {
+	c := &hashChain{}
+	if stateDir == "" {
+		return c, nil
+	}
+	c.path = filepath.Join(stateDir, "hashchain.json")
+	b, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &c.state); err != nil {
+		return nil, fmt.Errorf("%s: %w", c.path, err)
+	}
+	return c, nil
+}
+
+func (c *hashChain) link(pl *reporter.Payload) {
+	c.state.Index++
+	pl.Link(c.state.Hash, c.state.Index)
+	c.state.Hash = pl.ChainHash
+	if c.path == "" {
+		return
+	}
+	if err := writeFileAtomic(c.path, c.state); err != nil {
+		msg.Errorf(msg.ChainSave, c.path, err)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+const defaultCollectorTimeout = 5 * time.Second
+
+// collectJob — одно чтение коллектора. read выполняется в своей горутине и ничего общего
+// не меняет: результат переносится в отчёт через apply уже в основном цикле. Прошлые
+// счётчики коллектора тоже сдвигает apply — отброшенный по таймауту результат не съедает
+// дельту, она войдёт в следующий интервал
+type collectJob struct {
+	name string
+	read func() (apply func(), err error)
+}
+
+// collectorRunner запускает коллекторы параллельно, у каждого свой таймаут
+// (COLLECTOR_TIMEOUT, отдельные — COLLECTOR_TIMEOUTS=conn_stats=2s,asns=10s): медленный
+// источник не задерживает отчёт, его данные просто не попадают в этот интервал.
+// Зависшее чтение не запускается повторно, пока не вернётся, поэтому состояние
+// коллектора никогда не трогают две горутины сразу
+type collectorRunner struct {
+	timeout  time.Duration
+	timeouts map[string]time.Duration
+	stats    *selfStats
+
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+func newCollectorRunner(timeout time.Duration, timeouts map[string]time.Duration, stats *selfStats) *collectorRunner {
+	return &collectorRunner{timeout: timeout, timeouts: timeouts, stats: stats, busy: make(map[string]bool)}
+}
+
+type collectResult struct {
+	apply func()
+	err   error
+}
+
+// run выполняет jobs и применяет успевшие в порядке jobs; true — все успели без ошибок
+func (r *collectorRunner) run(jobs ...collectJob) bool {
+	start := time.Now()
+	done := make([]chan collectResult, len(jobs))
+	for i, j := range jobs {
+		r.mu.Lock()
+		busy := r.busy[j.name]
+		r.busy[j.name] = true
+		r.mu.Unlock()
+		if busy {
+			continue
+		}
+		done[i] = make(chan collectResult, 1)
+		go func(ch chan<- collectResult) {
+			t0 := time.Now()
+			apply, err := j.read()
+			r.stats.collectorDone(j.name, time.Since(t0))
+			r.mu.Lock()
+			r.busy[j.name] = false
+			r.mu.Unlock()
+			ch <- collectResult{apply: apply, err: err}
+		}(done[i])
+	}
+
+	ok := true
+	for i, j := range jobs {
+		if done[i] == nil {
+			msg.Errorf(msg.CollectorBusy, j.name)
+			r.stats.collectorTimeout()
+			ok = false
+			continue
+		}
+		timeout := r.timeoutOf(j.name)
+		timer := time.NewTimer(time.Until(start.Add(timeout)))
+		select {
+		case res := <-done[i]:
+			timer.Stop()
+			if res.err != nil {
+				msg.Error(res.err)
+				r.stats.readError()
+				ok = false
+			} else if res.apply != nil {
+				res.apply()
+			}
+		case <-timer.C:
+			msg.Errorf(msg.CollectorTimeout, j.name, timeout)
+			r.stats.collectorTimeout()
+			ok = false
+		}
+	}
+	return ok
+}
+
+// timeoutOf — таймаут коллектора name
+func (r *collectorRunner) timeoutOf(name string) time.Duration {
+	if t, ok := r.timeouts[name]; ok {
+		return t
+	}
+	return r.timeout
+}
+
+// parseTimeouts разбирает "name=5s,name2=1m"
+func parseTimeouts(v string) (map[string]time.Duration, error) {
+	pairs, err := parseLabels(v)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]time.Duration, len(pairs))
+	for name, s := range pairs {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return nil, fmt.Errorf("%s: invalid timeout %q", name, s)
+		}
+		out[name] = d
+	}
+	return out, nil
+}
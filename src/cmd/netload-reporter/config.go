@@ -0,0 +1,642 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/bus"
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/enrich"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/ports"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+	"github.com/iflixer/network-stater/src/pkg/subnets"
+)
+
+// режимы сглаживания (SMOOTHING)
+const (
+	smoothingWindow = "window"
+	smoothingEWMA   = "ewma"
+	smoothingBoth   = "both"
+)
+
+const (
+	avgWindow           = 5 * time.Minute
+	defaultEWMAHalfLife = time.Minute
+	// доля отчётов, дублируемых на canary-эндпоинт
+	defaultCanaryRatio = 0.1
+	// шум для стороннего получателя: ε=1 на отчёт, скрывается вклад до 1 Мбит/с
+	defaultShareEpsilon     = 1
+	defaultShareSensitivity = "1M"
+
+	defaultConnectTimeout  = 5 * time.Second
+	defaultResponseTimeout = 10 * time.Second
+	defaultMaxAttempts     = 3
+	defaultRetryBase       = time.Second
+	defaultRetryMax        = 30 * time.Second
+	defaultQueueSize       = 100
+
+	// расхождение стенного и монотонного интервала, после которого считаем, что часы перевели
+	defaultClockStepThreshold = 2 * time.Second
+)
+
+// пути к файлам ядра, переопределяются для тестовых стендов и контейнеров
+type procPaths struct {
+	netDev      string
+	netstat     string
+	snmp6       string
+	conn        collector.ConnStatsPaths
+	route       string
+	powerSupply string
+	cgroup      string
+	sysClassNet string
+}
+
+type config struct {
+	apiListen     string
+	apiWriteToken string
+	apiLimits     apiLimits
+	nodeName      string
+	labels        map[string]string
+	enrich        *enrich.Chain
+	primary       reporter.Sink
+	canary        reporter.Sink
+	canaryRatio   float64
+	// сторонний получатель обезличенных агрегатов
+	share        reporter.Sink
+	sharePrivacy reporter.Privacy
+	// получатели, перечитываемые по SIGHUP
+	sinksFile        string
+	events           reporter.Sink
+	eventCorrelation time.Duration
+	exporter         string
+	interval         time.Duration
+	historyWindow    time.Duration
+
+	nats  bus.NATSConfig
+	kafka bus.KafkaConfig
+
+	signReports bool
+	hashChain   bool
+	signingKey  string
+	// файл открытых ключей сервера; пусто — без шифрования
+	encryptionKeys string
+
+	connectTimeout  time.Duration
+	responseTimeout time.Duration
+	proxy           *url.URL
+	source          reporter.Source
+	ownTraffic      string
+	retry           reporter.Retry
+	queueSize       int
+
+	interfaces   collector.Filter
+	netdevSource string
+	// таймаут чтения коллектора: общий и по имени
+	collectorTimeout  time.Duration
+	collectorTimeouts map[string]time.Duration
+	// свои коллекторы pkg/sdk (PLUGINS)
+	plugins []string
+	// COLLECTOR=fake|replay: счётчики не из ядра
+	simulated collector.Source
+
+	// члены bond/bridge в сумме вместе с master-ом (двойной счёт, как до учёта топологии)
+	includeMembers bool
+	breakdown      bool
+	ifLabels       map[string]string
+	lldp           bool
+	linkSpeed      bool
+	cdp            bool
+	// проверочные порты входящей доступности
+	reachPorts  []collector.ReachPort
+	reachListen string
+
+	useWindow bool
+	useEWMA   bool
+	halfLife  time.Duration
+	// средние за сутки и неделю (LONG_WINDOWS)
+	daily, weekly  bool
+	longWindowStep time.Duration
+
+	ipFamily  bool
+	connStats bool
+
+	clockInfo      bool
+	clockThreshold time.Duration
+
+	subnetGroups []subnets.Group
+	portGroups   []ports.Group
+	upstreams    *upstreamWatch
+	asns         *asnWatch
+
+	monthly       bool
+	burst         *burstTracker
+	stateDir      string
+	monthlyQuota  uint64
+	quotaAlertPct float64
+
+	power    *powerPolicy
+	metered  *meteredPolicy
+	failover *failoverWatch
+	alerts   *alertEngine
+	deploys  *deployTracker
+	kube     *nodePublisher
+	store    *localStore
+
+	policies []sendPolicy
+	adaptive *adaptivePolicy
+
+	// SECURITY_PROFILE=readonly: только чтение /proc, аудит прав при старте
+	readOnly bool
+
+	paths procPaths
+}
+
+func loadConfig() (*config, error) {
+	cfg := &config{
+		apiListen:     os.Getenv("API_LISTEN"),
+		apiWriteToken: os.Getenv("API_WRITE_TOKEN"),
+		nodeName:      os.Getenv("NODE_NAME"),
+		interval:      envDuration("INTERVAL", time.Minute),
+		stateDir:      os.Getenv("STATE_DIR"),
+		paths: procPaths{
+			netDev:  os.Getenv("PROC_NET_DEV"),
+			netstat: os.Getenv("PROC_NET_NETSTAT"),
+			snmp6:   os.Getenv("PROC_NET_SNMP6"),
+			conn: collector.ConnStatsPaths{
+				Sockstat:  os.Getenv("PROC_NET_SOCKSTAT"),
+				Snmp:      os.Getenv("PROC_NET_SNMP"),
+				Netfilter: os.Getenv("NETFILTER_PATH"),
+			},
+			route:       os.Getenv("PROC_NET_ROUTE"),
+			powerSupply: os.Getenv("POWER_SUPPLY_PATH"),
+			cgroup:      os.Getenv("CGROUP2_PATH"),
+			sysClassNet: os.Getenv("SYS_CLASS_NET"),
+		},
+	}
+
+	reportURL := os.Getenv("REPORT_URL")
+	cfg.exporter = envString("EXPORTER", exporterHTTP)
+	if cfg.exporter != exporterHTTP && cfg.exporter != exporterNATS && cfg.exporter != exporterKafka {
+		return nil, fmt.Errorf("EXPORTER: unknown exporter %q", cfg.exporter)
+	}
+	if cfg.exporter == exporterHTTP && reportURL == "" && cfg.apiListen == "" {
+		return nil, fmt.Errorf("REPORT_URL or API_LISTEN is required")
+	}
+	apiKey := os.Getenv("API_KEY")
+	var err error
+	if cfg.labels, err = parseLabels(os.Getenv("LABELS")); err != nil {
+		return nil, fmt.Errorf("LABELS: %w", err)
+	}
+	if cfg.enrich, err = newEnrichChain(cfg); err != nil {
+		return nil, err
+	}
+
+	cfg.primary = reporter.Sink{Name: "report", URL: reportURL, APIKey: apiKey, Encoding: envString("REPORT_ENCODING", reporter.EncodingJSON)}
+	cfg.canary = reporter.Sink{
+		Name:     "canary",
+		URL:      os.Getenv("CANARY_URL"),
+		APIKey:   envString("CANARY_API_KEY", apiKey),
+		Encoding: envString("CANARY_ENCODING", cfg.primary.Encoding),
+	}
+	cfg.canaryRatio = envFloat("CANARY_RATIO", defaultCanaryRatio, 0, 1)
+	if cfg.share, cfg.sharePrivacy, err = loadShare(); err != nil {
+		return nil, err
+	}
+	cfg.sinksFile = os.Getenv("SINKS_FILE")
+	for _, s := range []reporter.Sink{cfg.primary, cfg.canary, cfg.share} {
+		if !reporter.ValidEncoding(s.Encoding) {
+			return nil, fmt.Errorf("%s: unknown encoding %q", s.Name, s.Encoding)
+		}
+	}
+	if err := loadBusConfig(cfg); err != nil {
+		return nil, err
+	}
+	cfg.signReports = envBool("SIGN_REPORTS")
+	cfg.hashChain = envBool("HASH_CHAIN")
+	cfg.signingKey = signingKeyPath()
+	cfg.encryptionKeys = os.Getenv("ENCRYPTION_KEYS")
+	cfg.events = reporter.Sink{Name: "events", URL: os.Getenv("EVENTS_URL"), APIKey: apiKey, Encoding: reporter.EncodingJSON}
+	cfg.eventCorrelation = envDuration("EVENT_CORRELATION_WINDOW", 0)
+
+	cfg.connectTimeout = envDuration("REPORT_CONNECT_TIMEOUT", defaultConnectTimeout)
+	cfg.responseTimeout = envDuration("REPORT_RESPONSE_TIMEOUT", defaultResponseTimeout)
+	if v := os.Getenv("PROXY_URL"); v != "" {
+		if cfg.proxy, err = reporter.ParseProxy(v); err != nil {
+			return nil, fmt.Errorf("PROXY_URL: %w", err)
+		}
+	}
+	if cfg.source, err = envSource(); err != nil {
+		return nil, err
+	}
+	switch cfg.ownTraffic = os.Getenv("OWN_TRAFFIC"); cfg.ownTraffic {
+	case "":
+	case ownTrafficReport, ownTrafficSubtract:
+		cfg.source.Traffic = &reporter.Traffic{}
+	default:
+		return nil, fmt.Errorf("OWN_TRAFFIC: want %s or %s, got %q", ownTrafficReport, ownTrafficSubtract, cfg.ownTraffic)
+	}
+	cfg.retry = reporter.Retry{
+		MaxAttempts:    envInt("REPORT_MAX_ATTEMPTS", defaultMaxAttempts, 1),
+		BaseDelay:      envDuration("REPORT_RETRY_BASE", defaultRetryBase),
+		MaxDelay:       envDuration("REPORT_RETRY_MAX", defaultRetryMax),
+		AttemptTimeout: cfg.connectTimeout + cfg.responseTimeout,
+	}
+	cfg.queueSize = envInt("REPORT_QUEUE", defaultQueueSize, 1)
+
+	smoothing := envString("SMOOTHING", smoothingWindow)
+	if smoothing != smoothingWindow && smoothing != smoothingEWMA && smoothing != smoothingBoth {
+		return nil, fmt.Errorf("SMOOTHING: unknown mode %q", smoothing)
+	}
+	cfg.useWindow = smoothing == smoothingWindow || smoothing == smoothingBoth
+	cfg.useEWMA = smoothing == smoothingEWMA || smoothing == smoothingBoth
+	cfg.halfLife = envDuration("EWMA_HALF_LIFE", defaultEWMAHalfLife)
+	if cfg.daily, cfg.weekly, err = parseLongWindows(os.Getenv("LONG_WINDOWS")); err != nil {
+		return nil, fmt.Errorf("LONG_WINDOWS: %w", err)
+	}
+	cfg.longWindowStep = envDuration("LONG_WINDOW_STEP", defaultLongWindowStep)
+	cfg.historyWindow = envDuration("HISTORY_WINDOW", defaultHistoryWindow)
+	cfg.apiLimits = apiLimits{
+		rate:        envFloat("API_RATE_LIMIT", defaultAPIRate, 0, -1),
+		burst:       envFloat("API_RATE_BURST", defaultAPIBurst, 1, -1),
+		inflight:    envInt("API_MAX_INFLIGHT", defaultAPIInflight, 1),
+		maxResponse: defaultAPIMaxResponse,
+	}
+	if v := os.Getenv("API_MAX_RESPONSE"); v != "" {
+		n, err := parseBytes(v)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("API_MAX_RESPONSE: invalid size %q", v)
+		}
+		cfg.apiLimits.maxResponse = int64(n)
+	}
+
+	cfg.power = &powerPolicy{
+		mode:          envString("POWER_MODE", powerOff),
+		supplyPath:    cfg.paths.powerSupply,
+		factor:        envInt("POWER_INTERVAL_FACTOR", defaultPowerIntervalFactor, 1),
+		maxDelay:      envDuration("POWER_MAX_DELAY", defaultPowerMaxDelay),
+		wakeBytesPerS: envFloat("POWER_WAKE_BYTES_PER_SEC", defaultPowerWakeBytesPerSec, 0, -1),
+	}
+	if m := cfg.power.mode; m != powerOff && m != powerAuto && m != powerOn {
+		return nil, fmt.Errorf("POWER_MODE: unknown mode %q", m)
+	}
+
+	if v := os.Getenv("BURST_BASE_BPS"); v != "" {
+		b := &burstTracker{
+			sample:    envDuration("BURST_SAMPLE", defaultBurstSample),
+			direction: envString("BURST_DIRECTION", burstMax),
+			alertPct:  envFloat("BURST_ALERT_PCT", defaultBurstAlertPct, 0, 100),
+		}
+		if b.base, err = parseRate(v); err != nil || b.base <= 0 {
+			return nil, fmt.Errorf("BURST_BASE_BPS: invalid rate %q", v)
+		}
+		c := os.Getenv("BURST_CEILING_BPS")
+		if b.ceiling, err = parseRate(c); err != nil || b.ceiling < b.base {
+			return nil, fmt.Errorf("BURST_CEILING_BPS: want a rate not below BURST_BASE_BPS, got %q", c)
+		}
+		if d := b.direction; d != burstMax && d != burstRx && d != burstTx && d != burstTotal {
+			return nil, fmt.Errorf("BURST_DIRECTION: unknown direction %q", d)
+		}
+		if b.allowPct, b.allowTime, err = parseBurstAllowance(envString("BURST_ALLOWANCE", defaultBurstAllowance)); err != nil {
+			return nil, fmt.Errorf("BURST_ALLOWANCE: %w", err)
+		}
+		cfg.burst = b
+	}
+
+	if cfg.monthly = envBool("MONTHLY_ACCOUNTING"); cfg.monthly {
+		if v := os.Getenv("MONTHLY_QUOTA"); v != "" {
+			if cfg.monthlyQuota, err = parseBytes(v); err != nil {
+				return nil, fmt.Errorf("MONTHLY_QUOTA: %w", err)
+			}
+		}
+		cfg.quotaAlertPct = envFloat("MONTHLY_QUOTA_ALERT_PCT", defaultQuotaAlertPct, 0, -1)
+	}
+
+	cfg.metered = &meteredPolicy{
+		mode:      envString("METERED", meteredOff),
+		patterns:  splitList(envString("METERED_INTERFACES", defaultMeteredInterfaces)),
+		routePath: cfg.paths.route,
+		factor:    envInt("METERED_INTERVAL_FACTOR", defaultMeteredIntervalFactor, 1),
+		batchSize: envInt("METERED_BATCH", defaultMeteredBatch, 1),
+		maxDelay:  envDuration("METERED_MAX_DELAY", defaultMeteredMaxDelay),
+	}
+	if m := cfg.metered.mode; m != meteredOff && m != meteredAuto && m != meteredOn {
+		return nil, fmt.Errorf("METERED: unknown mode %q", m)
+	}
+
+	if v := os.Getenv("ALERT_RULES"); v != "" {
+		rules, err := parseAlertRules(v)
+		if err != nil {
+			return nil, fmt.Errorf("ALERT_RULES: %w", err)
+		}
+		cfg.alerts = newAlertEngine(rules)
+	}
+
+	cfg.deploys = &deployTracker{
+		duration:   envDuration("DEPLOY_DURATION", defaultDeployDuration),
+		poll:       envDuration("DEPLOY_POLL", defaultDeployPoll),
+		file:       os.Getenv("DEPLOY_FILE"),
+		annotation: os.Getenv("DEPLOY_ANNOTATION"),
+		node:       cfg.nodeName,
+		saDir:      os.Getenv("K8S_SERVICE_ACCOUNT_DIR"),
+	}
+	if cfg.deploys.annotation != "" && cfg.nodeName == "" {
+		return nil, fmt.Errorf("DEPLOY_ANNOTATION: NODE_NAME is required")
+	}
+	if cfg.alerts != nil {
+		cfg.alerts.deploys = cfg.deploys
+	}
+
+	if dir := os.Getenv("STORE_DIR"); dir != "" {
+		cfg.store = &localStore{dir: dir, maxBytes: defaultStoreMax, compact: envDuration("STORE_COMPACT_INTERVAL", defaultStoreCompact)}
+		if cfg.store.retention, err = parseRetention(os.Getenv("STORE_RETENTION")); err != nil {
+			return nil, fmt.Errorf("STORE_RETENTION: %w", err)
+		}
+		if v := os.Getenv("STORE_MAX_BYTES"); v != "" {
+			if cfg.store.maxBytes, err = parseBytes(v); err != nil {
+				return nil, fmt.Errorf("STORE_MAX_BYTES: %w", err)
+			}
+		}
+	}
+
+	if v := os.Getenv("BACKUP_INTERFACES"); v != "" {
+		cfg.failover = newFailoverWatch(splitList(v), envFloat("BACKUP_ACTIVE_BYTES_PER_SEC", defaultBackupActiveBps, 0, -1))
+	}
+
+	if m := envString("K8S_NODE_PUBLISH", kubeOff); m != kubeOff {
+		if m != kubeAnnotations && m != kubeCondition {
+			return nil, fmt.Errorf("K8S_NODE_PUBLISH: unknown mode %q", m)
+		}
+		if cfg.nodeName == "" {
+			return nil, fmt.Errorf("K8S_NODE_PUBLISH: NODE_NAME is required")
+		}
+		cfg.kube = &nodePublisher{
+			mode:          m,
+			node:          cfg.nodeName,
+			interval:      envDuration("K8S_PUBLISH_INTERVAL", defaultKubeInterval),
+			prefix:        envString("K8S_ANNOTATION_PREFIX", defaultKubeAnnotationPrefix),
+			conditionType: envString("K8S_CONDITION_TYPE", defaultKubeConditionType),
+			saDir:         os.Getenv("K8S_SERVICE_ACCOUNT_DIR"),
+		}
+		if m == kubeCondition {
+			v := os.Getenv("K8S_HIGH_LOAD_BPS")
+			if v == "" {
+				return nil, fmt.Errorf("K8S_HIGH_LOAD_BPS is required for condition mode")
+			}
+			if cfg.kube.highLoadBps, err = parseRate(v); err != nil || cfg.kube.highLoadBps <= 0 {
+				return nil, fmt.Errorf("K8S_HIGH_LOAD_BPS: invalid rate %q", v)
+			}
+		}
+	}
+
+	if v := os.Getenv("IDLE_SUPPRESS_BYTES_PER_SEC"); v != "" {
+		floor, err := strconv.ParseFloat(v, 64)
+		if err != nil || floor <= 0 {
+			return nil, fmt.Errorf("IDLE_SUPPRESS_BYTES_PER_SEC: invalid value %q", v)
+		}
+		cfg.policies = append(cfg.policies, &idlePolicy{
+			floorBps:  floor,
+			after:     envInt("IDLE_SUPPRESS_AFTER", defaultIdleAfter, 0),
+			heartbeat: envDuration("IDLE_HEARTBEAT", defaultIdleHeartbeat),
+		})
+	}
+
+	if bps, pct := envFloat("ADAPTIVE_HIGH_BYTES_PER_SEC", 0, 0, -1), envFloat("ADAPTIVE_HIGH_PCT", 0, 0, 100); bps > 0 || pct > 0 {
+		if pct > 0 && !envBool("LINK_SPEED") {
+			return nil, fmt.Errorf("ADAPTIVE_HIGH_PCT needs LINK_SPEED=true")
+		}
+		a := &adaptivePolicy{
+			sample:      envDuration("ADAPTIVE_SAMPLE", defaultAdaptiveSample),
+			minInterval: envDuration("ADAPTIVE_MIN_INTERVAL", defaultAdaptiveMinInterval),
+			maxInterval: envDuration("ADAPTIVE_MAX_INTERVAL", cfg.interval),
+			highBps:     bps,
+			highPct:     pct,
+		}
+		a.minInterval = max(a.minInterval, a.sample)
+		a.maxInterval = max(a.maxInterval, a.minInterval)
+		cfg.adaptive = a
+	}
+
+	if pct, bps := envFloat("REPORT_ON_CHANGE_PCT", 0, 0, -1), envFloat("REPORT_ON_CHANGE_BYTES_PER_SEC", 0, 0, -1); pct > 0 || bps > 0 {
+		cfg.policies = append(cfg.policies, &changePolicy{
+			pct:        pct,
+			bps:        bps,
+			maxSilence: envDuration("REPORT_MAX_SILENCE", defaultMaxSilence),
+		})
+	}
+
+	if cfg.plugins, err = pluginNames(os.Getenv("PLUGINS")); err != nil {
+		return nil, fmt.Errorf("PLUGINS: %w", err)
+	}
+	cfg.collectorTimeout = envDuration("COLLECTOR_TIMEOUT", defaultCollectorTimeout)
+	if cfg.collectorTimeouts, err = parseTimeouts(os.Getenv("COLLECTOR_TIMEOUTS")); err != nil {
+		return nil, fmt.Errorf("COLLECTOR_TIMEOUTS: %w", err)
+	}
+	switch p := envString("SECURITY_PROFILE", profileStandard); p {
+	case profileStandard:
+	case profileReadOnly:
+		cfg.readOnly = true
+	default:
+		return nil, fmt.Errorf("SECURITY_PROFILE: unknown profile %q", p)
+	}
+	cfg.netdevSource = envString("NETDEV_SOURCE", netdevProc)
+	if err := validNetdevSource(cfg.netdevSource); err != nil {
+		return nil, err
+	}
+	if cfg.simulated, err = loadSimulated(); err != nil {
+		return nil, err
+	}
+	if cfg.simulated != nil {
+		// синтетика главнее источника ядра: NETDEV_SOURCE при ней не действует
+		if src := os.Getenv("NETDEV_SOURCE"); src != "" {
+			msg.Warnf(msg.SimulatedSource, os.Getenv("COLLECTOR"), src)
+		}
+		cfg.netdevSource = os.Getenv("COLLECTOR")
+	}
+	cfg.interfaces = collector.Filter{
+		Include: splitList(os.Getenv("INTERFACES")),
+		Exclude: splitList(os.Getenv("INTERFACES_EXCLUDE")),
+	}
+	cfg.includeMembers = envBool("INTERFACES_INCLUDE_MEMBERS")
+	cfg.breakdown = envBool("INTERFACE_BREAKDOWN")
+	if cfg.ifLabels, err = parseLabels(os.Getenv("INTERFACE_LABELS")); err != nil {
+		return nil, fmt.Errorf("INTERFACE_LABELS: %w", err)
+	}
+	cfg.lldp = envBool("LLDP")
+	cfg.linkSpeed = envBool("LINK_SPEED")
+	// CDP слушает тот же приёмник, что и LLDP
+	cfg.cdp = envBool("CDP")
+	// подписи видны только в разбивке по интерфейсам, без неё они бессмысленны
+	if len(cfg.ifLabels) > 0 || cfg.lldp || cfg.cdp {
+		cfg.breakdown = true
+	}
+
+	if cfg.reachPorts, err = collector.ParseReachPorts(os.Getenv("REACHABILITY_PORTS")); err != nil {
+		return nil, fmt.Errorf("REACHABILITY_PORTS: %w", err)
+	}
+	cfg.reachListen = os.Getenv("REACHABILITY_LISTEN")
+
+	if v := os.Getenv("SUBNET_GROUPS"); v != "" {
+		if cfg.subnetGroups, err = subnets.ParseGroups(v); err != nil {
+			return nil, fmt.Errorf("SUBNET_GROUPS: %w", err)
+		}
+	}
+
+	if v := os.Getenv("PORT_GROUPS"); v != "" {
+		if cfg.portGroups, err = ports.ParseGroups(v); err != nil {
+			return nil, fmt.Errorf("PORT_GROUPS: %w", err)
+		}
+	}
+
+	if v := os.Getenv("UPSTREAMS"); v != "" {
+		if cfg.upstreams, err = newUpstreamWatch(splitList(v), envDuration("UPSTREAM_RESOLVE_INTERVAL", defaultUpstreamResolve)); err != nil {
+			return nil, fmt.Errorf("UPSTREAMS: %w", err)
+		}
+	}
+
+	if v := os.Getenv("ASN_TABLE"); v != "" {
+		cfg.asns = &asnWatch{path: v, top: envInt("ASN_TOP", defaultASNTop, 1)}
+	}
+
+	cfg.clockInfo = envBool("CLOCK_INFO")
+	cfg.clockThreshold = envDuration("CLOCK_STEP_THRESHOLD", defaultClockStepThreshold)
+
+	cfg.ipFamily = envBool("IP_FAMILY_STATS")
+	cfg.connStats = envBool("CONN_STATS")
+	return cfg, nil
+}
+
+func hostname() string {
+	host, _ := os.Hostname()
+	return filepath.Base(host)
+}
+
+// ---- разбор переменных окружения; некорректное значение — значение по умолчанию ----
+
+func envString(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
+
+func envBool(name string) bool {
+	on, _ := strconv.ParseBool(os.Getenv(name))
+	return on
+}
+
+func envDuration(name string, def time.Duration) time.Duration {
+	if v := os.Getenv(name); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			return d
+		}
+	}
+	return def
+}
+
+func envInt(name string, def, min int) int {
+	if v := os.Getenv(name); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= min {
+			return n
+		}
+	}
+	return def
+}
+
+// envFloat: max < min — без верхней границы
+func envFloat(name string, def, min, max float64) float64 {
+	if v := os.Getenv(name); v != "" {
+		if f, err := strconv.ParseFloat(v, 64); err == nil && f >= min && (max < min || f <= max) {
+			return f
+		}
+	}
+	return def
+}
+
+// loadShare — получатель SHARE_URL; ключ API_KEY ему не передаётся, точные данные тоже
+func loadShare() (reporter.Sink, reporter.Privacy, error) {
+	s := reporter.Sink{
+		Name:     "share",
+		URL:      os.Getenv("SHARE_URL"),
+		APIKey:   os.Getenv("SHARE_API_KEY"),
+		Encoding: envString("SHARE_ENCODING", reporter.EncodingJSON),
+	}
+	// шум нужен и получателям с share из SINKS_FILE и API, поэтому разбирается всегда
+	p := reporter.Privacy{Epsilon: envFloat("SHARE_NOISE_EPSILON", defaultShareEpsilon, 0, -1)}
+	v := envString("SHARE_NOISE_SENSITIVITY", defaultShareSensitivity)
+	bits, err := parseRate(v)
+	if err != nil || bits <= 0 {
+		return s, p, fmt.Errorf("SHARE_NOISE_SENSITIVITY: invalid rate %q", v)
+	}
+	p.Sensitivity = bits / 8
+	if v := os.Getenv("SHARE_ROUND"); v != "" {
+		if bits, err = parseRate(v); err != nil || bits < 0 {
+			return s, p, fmt.Errorf("SHARE_ROUND: invalid rate %q", v)
+		}
+		p.Round = bits / 8
+	}
+	if s.URL != "" && p.Epsilon == 0 && p.Round == 0 {
+		return s, p, fmt.Errorf("SHARE_URL: exact data is not shared, set SHARE_NOISE_EPSILON or SHARE_ROUND")
+	}
+	return s, p, nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseLabels разбирает статические метки вида "dc=fra1,rack=r12,env=prod"
+func parseLabels(s string) (map[string]string, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+	labels := make(map[string]string)
+	for _, kv := range strings.Split(s, ",") {
+		kv = strings.TrimSpace(kv)
+		if kv == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(kv, "=")
+		k, v = strings.TrimSpace(k), strings.TrimSpace(v)
+		if !ok || k == "" {
+			return nil, fmt.Errorf("invalid label %q, want key=value", kv)
+		}
+		labels[k] = v
+	}
+	return labels, nil
+}
+
+// parseBytes понимает "500GB", "1.5TB", "2TiB" и просто число байт
+func parseBytes(s string) (uint64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	units := []struct {
+		suffix string
+		mult   float64
+	}{
+		{"PIB", 1 << 50}, {"TIB", 1 << 40}, {"GIB", 1 << 30}, {"MIB", 1 << 20}, {"KIB", 1 << 10},
+		{"PB", 1e15}, {"TB", 1e12}, {"GB", 1e9}, {"MB", 1e6}, {"KB", 1e3}, {"B", 1},
+	}
+	mult := 1.0
+	for _, u := range units {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSpace(strings.TrimSuffix(s, u.suffix)), u.mult
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return uint64(f * mult), nil
+}
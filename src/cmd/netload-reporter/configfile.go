@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// CONFIG_FILE — ключи конфигурации файлом, а не окружением: строки `report.url = https://…`,
+// пустые и `# комментарии`. Ключ файла — имя переменной в нижнем регистре, первое слово
+// отделено точкой (REPORT_URL — report.url, FAKE_RX_BYTES_PER_SEC — fake.rx_bytes_per_sec,
+// INTERVAL — interval). Значение — до конца строки или в двойных кавычках с экранированием Go.
+// Заданное в окружении и .env главнее файла, файл — главнее начальной настройки
+
+var confKeyLine = regexp.MustCompile(`^(\s*)([a-z_][a-z0-9_.]*)\s*=\s*(.*?)\s*$`)
+
+// confKey — ключ файла для переменной окружения
+func confKey(env string) string {
+	return strings.Replace(strings.ToLower(env), "_", ".", 1)
+}
+
+// envKey — переменная окружения для ключа файла
+func envKey(key string) string {
+	return strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// parseConfigFile — ключи файла по именам переменных окружения
+func parseConfigFile(data []byte) (map[string]string, error) {
+	vals := make(map[string]string)
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := confKeyLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: want key = value", n)
+		}
+		k := envKey(m[2])
+		if _, dup := vals[k]; dup {
+			return nil, fmt.Errorf("line %d: %s is set twice", n, m[2])
+		}
+		v, err := confValue(m[3])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, m[2], err)
+		}
+		vals[k] = v
+	}
+	return vals, sc.Err()
+}
+
+func confValue(raw string) (string, error) {
+	if !strings.HasPrefix(raw, `"`) {
+		return raw, nil
+	}
+	v, err := strconv.Unquote(raw)
+	if err != nil {
+		return "", fmt.Errorf("bad quoted value %s", raw)
+	}
+	return v, nil
+}
+
+// confQuote — значение, которое parseConfigFile прочитает как есть
+func confQuote(v string) string {
+	if v == "" || v != strings.TrimSpace(v) || strings.HasPrefix(v, `"`) || strings.ContainsAny(v, "\n\r\t") {
+		return strconv.Quote(v)
+	}
+	return v
+}
+
+// loadConfigFile переносит ключи CONFIG_FILE в окружение, не трогая заданные явно; устаревшие
+// ключи файла переводятся так же, как в окружении
+func loadConfigFile() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("CONFIG_FILE: %w", err)
+	}
+	vals, err := parseConfigFile(data)
+	if err != nil {
+		return fmt.Errorf("CONFIG_FILE: %s: %w", path, err)
+	}
+	if err := migrateValues(vals, path); err != nil {
+		return fmt.Errorf("CONFIG_FILE: %s: %w", path, err)
+	}
+	for k, v := range vals {
+		if cur, set := os.LookupEnv(k); set {
+			// при переезде с окружения на файл забытая переменная молча перекрыла бы файл
+			if cur != v {
+				msg.Warnf(msg.ConfigShadowed, path, confKey(k))
+			}
+			continue
+		}
+		os.Setenv(k, v)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"maps"
+	"testing"
+)
+
+func TestParseConfigFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"keys", "# c\n\nreport.url = https://x/r\ninterval=15s\nfake.rx_bytes_per_sec = 10\n", map[string]string{"REPORT_URL": "https://x/r", "INTERVAL": "15s", "FAKE_RX_BYTES_PER_SEC": "10"}, false},
+		{"quoted", `api.key = "  a\"b "` + "\n", map[string]string{"API_KEY": `  a"b `}, false},
+		{"empty value", "interfaces =\n", map[string]string{"INTERFACES": ""}, false},
+		{"duplicate", "interval = 1s\ninterval = 2s\n", nil, true},
+		{"env name", "REPORT_URL = x\n", nil, true},
+		{"bad quote", `api.key = "x` + "\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseConfigFile([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !maps.Equal(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfKeyRoundTrip(t *testing.T) {
+	for _, env := range []string{"INTERVAL", "REPORT_URL", "FAKE_RX_BYTES_PER_SEC"} {
+		if got := envKey(confKey(env)); got != env {
+			t.Errorf("%s -> %s -> %s", env, confKey(env), got)
+		}
+	}
+}
+
+func withMigrations(t *testing.T, m ...configMigration) {
+	saved := configMigrations
+	configMigrations = m
+	t.Cleanup(func() { configMigrations = saved })
+}
+
+func TestMigrateFile(t *testing.T) {
+	withMigrations(t,
+		configMigration{old: "OLD_URL", new: "REPORT_URL"},
+		configMigration{old: "OLD_MODE", new: "MODE", convert: func(v string) (string, error) {
+			if v == "default" {
+				return "", nil
+			}
+			return v, nil
+		}},
+	)
+	tests := []struct {
+		name, path, in, want string
+		changes              int
+	}{
+		{"dotenv rename", "a.env", "# keep\nexport OLD_URL=https://x\nINTERVAL=1s\n", "# keep\nexport REPORT_URL=https://x\nINTERVAL=1s\n", 1},
+		{"dotenv drop", "a.env", "OLD_MODE=default\n", "# OLD_MODE=default  # deprecated, no effect: see MODE\n", 1},
+		{"dotenv conflict", "a.env", "OLD_URL=a\nREPORT_URL=b\n", "# OLD_URL=a  # deprecated, ignored: REPORT_URL is set\nREPORT_URL=b\n", 1},
+		{"dotenv same value", "a.env", "OLD_URL=a\nREPORT_URL=a\n", "# OLD_URL=a  # deprecated, no effect: see REPORT_URL\nREPORT_URL=a\n", 1},
+		{"conf rename", "a.conf", "old.url = https://x\n", "report.url = https://x\n", 1},
+		{"nothing to do", "a.conf", "interval = 1s\n", "interval = 1s\n", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, changes, err := migrateFile([]byte(tt.in), formatOf(tt.path))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(out) != tt.want || len(changes) != tt.changes {
+				t.Errorf("got %q (%d changes), want %q (%d)", out, len(changes), tt.want, tt.changes)
+			}
+		})
+	}
+}
+
+func TestDotenvToConf(t *testing.T) {
+	withMigrations(t, configMigration{old: "OLD_URL", new: "REPORT_URL"})
+	in := "# target\nOLD_URL=https://x\n\nAPI_KEY=' k '\nINTERVAL=1s\nINTERVAL=2s\n"
+	want := "# target\nreport.url = https://x\n\napi.key = \" k \"\ninterval = 2s\n"
+	out, changes, err := dotenvToConf([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != want || len(changes) != 1 {
+		t.Errorf("got %q (%d changes), want %q", out, len(changes), want)
+	}
+	back, err := parseConfigFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back["API_KEY"] != " k " || back["REPORT_URL"] != "https://x" {
+		t.Errorf("round trip: %v", back)
+	}
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// Контрактные проверки обмена с сервером приёма отчётов.
+//
+// record отправляет фиксированные отчёты на REPORT_URL/EVENTS_URL и сохраняет
+// эталоны: что прислал агент и что ответил сервер. verify сверяет, что агент
+// по-прежнему формирует те же запросы, и переигрывает сохранённые запросы
+// на сервер, проверяя, что он отвечает как раньше. Ломающее изменение
+// с любой стороны видно до выкатки.
+
+const defaultContractDir = "contract"
+
+type goldenRequest struct {
+	ContentType     string          `json:"content_type"`
+	ContentEncoding string          `json:"content_encoding,omitempty"`
+	Body            json.RawMessage `json:"body"`
+}
+
+type goldenResponse struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        string `json:"body,omitempty"`
+}
+
+type golden struct {
+	Request  goldenRequest   `json:"request"`
+	Response *goldenResponse `json:"response,omitempty"`
+}
+
+type contractCase struct {
+	name     string
+	url      string
+	apiKey   string
+	value    any
+	encoding string
+}
+
+func contractFixture(ts int64, rx, tx float64) reporter.Payload {
+	return reporter.Payload{
+		Host:             "contract-host",
+		NodeName:         "contract-node",
+		Labels:           map[string]string{"env": "contract"},
+		Timestamp:        ts,
+		IntervalSeconds:  15,
+		RxBytesPerSec:    rx,
+		TxBytesPerSec:    tx,
+		RxBitsPerSec:     rx * 8,
+		TxBitsPerSec:     tx * 8,
+		TotalBytesPerSec: rx + tx,
+		TotalBitsPerSec:  (rx + tx) * 8,
+
+		IntervalWallSeconds:      15,
+		IntervalMonotonicSeconds: 15,
+		WindowStart:              (ts - 15) * 1000,
+		WindowEnd:                ts * 1000,
+
+		WindowAvg: &reporter.WindowAvg{
+			RxBytesPerSec5m:    rx,
+			TxBytesPerSec5m:    tx,
+			TotalBytesPerSec5m: rx + tx,
+			RxBitsPerSec5m:     rx * 8,
+			TxBitsPerSec5m:     tx * 8,
+			TotalBitsPerSec5m:  (rx + tx) * 8,
+		},
+	}
+}
+
+func contractCases() []contractCase {
+	reportURL, eventsURL, apiKey := os.Getenv("REPORT_URL"), os.Getenv("EVENTS_URL"), os.Getenv("API_KEY")
+	pl := contractFixture(1700000000, 125000, 25000)
+	return []contractCase{
+		{name: "report", url: reportURL, apiKey: apiKey, value: pl, encoding: reporter.EncodingJSON},
+		{name: "report_gzip", url: reportURL, apiKey: apiKey, value: pl, encoding: reporter.EncodingJSONGzip},
+		{name: "batch", url: reportURL, apiKey: apiKey, encoding: reporter.EncodingJSONGzip,
+			value: []reporter.Payload{pl, contractFixture(1700000015, 250000, 50000)}},
+		{name: "event", url: eventsURL, apiKey: apiKey, encoding: reporter.EncodingJSON, value: reporter.Event{
+			Type:      "backup_link_active",
+			Timestamp: 1700000000,
+			Host:      "contract-host",
+			NodeName:  "contract-node",
+			Interface: "wwan0",
+			Message:   "traffic moved onto backup link wwan0: rx=4096.0B/s tx=1024.0B/s",
+			Data:      map[string]any{"rx_bytes_per_sec": 4096.0, "tx_bytes_per_sec": 1024.0},
+		}},
+	}
+}
+
+func runContract(args []string) int {
+	if len(args) == 0 || (args[0] != "record" && args[0] != "verify") {
+		fmt.Fprintln(os.Stderr, "usage: netload-reporter contract record|verify [-dir DIR] [-offline]")
+		return 2
+	}
+	fs := flag.NewFlagSet("contract "+args[0], flag.ContinueOnError)
+	dir := fs.String("dir", defaultContractDir, "directory with golden files")
+	offline := fs.Bool("offline", false, "verify: only check agent requests, don't contact the server")
+	if err := fs.Parse(args[1:]); err != nil {
+		return 2
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	failed := false
+	for _, c := range contractCases() {
+		var err error
+		if args[0] == "record" {
+			err = recordCase(client, *dir, c)
+		} else {
+			err = verifyCase(client, *dir, c, *offline)
+		}
+		if err != nil {
+			failed = true
+			fmt.Printf("FAIL %s: %v\n", c.name, err)
+			continue
+		}
+		fmt.Printf("ok   %s\n", c.name)
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
+
+func goldenPath(dir, name string) string { return filepath.Join(dir, name+".json") }
+
+func buildGoldenRequest(c contractCase) (goldenRequest, reporter.Body, error) {
+	body, err := reporter.Encode(c.value, c.encoding)
+	if err != nil {
+		return goldenRequest{}, body, err
+	}
+	raw, _ := json.Marshal(c.value)
+	return goldenRequest{ContentType: body.ContentType, ContentEncoding: body.ContentEncoding, Body: raw}, body, nil
+}
+
+func recordCase(client *http.Client, dir string, c contractCase) error {
+	gr, body, err := buildGoldenRequest(c)
+	if err != nil {
+		return err
+	}
+	g := golden{Request: gr}
+	if c.url != "" {
+		if g.Response, err = doContractRequest(client, c, body); err != nil {
+			return err
+		}
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	b, _ := json.MarshalIndent(g, "", "  ")
+	return os.WriteFile(goldenPath(dir, c.name), append(b, '\n'), 0o644)
+}
+
+func verifyCase(client *http.Client, dir string, c contractCase, offline bool) error {
+	b, err := os.ReadFile(goldenPath(dir, c.name))
+	if err != nil {
+		return err
+	}
+	var g golden
+	if err := json.Unmarshal(b, &g); err != nil {
+		return fmt.Errorf("%s: %w", goldenPath(dir, c.name), err)
+	}
+
+	// сторона агента: запрос должен совпадать с эталоном
+	gr, _, err := buildGoldenRequest(c)
+	if err != nil {
+		return err
+	}
+	if gr.ContentType != g.Request.ContentType || gr.ContentEncoding != g.Request.ContentEncoding {
+		return fmt.Errorf("request headers changed: %s/%s, golden %s/%s",
+			gr.ContentType, gr.ContentEncoding, g.Request.ContentType, g.Request.ContentEncoding)
+	}
+	if diff := jsonDiff(g.Request.Body, gr.Body); len(diff) > 0 {
+		return fmt.Errorf("request body changed: %v", diff)
+	}
+
+	// сторона сервера: переигрываем записанный запрос
+	if offline || g.Response == nil || c.url == "" {
+		return nil
+	}
+	body, err := reencode(g.Request)
+	if err != nil {
+		return err
+	}
+	resp, err := doContractRequest(client, c, body)
+	if err != nil {
+		return err
+	}
+	if resp.Status != g.Response.Status {
+		return fmt.Errorf("server status %d, golden %d", resp.Status, g.Response.Status)
+	}
+	if missing := missingJSONKeys(g.Response.Body, resp.Body); len(missing) > 0 {
+		return fmt.Errorf("server response lost keys %v", missing)
+	}
+	return nil
+}
+
+func reencode(gr goldenRequest) (reporter.Body, error) {
+	if gr.ContentEncoding == "gzip" {
+		return reporter.Gzip(gr.Body)
+	}
+	return reporter.Body{Data: gr.Body, ContentType: gr.ContentType}, nil
+}
+
+func doContractRequest(client *http.Client, c contractCase, body reporter.Body) (*goldenResponse, error) {
+	s := reporter.Sink{Name: c.name, URL: c.url, APIKey: c.apiKey}
+	req, err := s.NewRequest(context.Background(), body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	b, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+	return &goldenResponse{Status: resp.StatusCode, ContentType: resp.Header.Get("Content-Type"), Body: string(b)}, nil
+}
+
+// jsonDiff возвращает пути, по которым JSON-документы расходятся
+func jsonDiff(want, got []byte) []string {
+	var w, g any
+	if err := json.Unmarshal(want, &w); err != nil {
+		return []string{"golden: " + err.Error()}
+	}
+	if err := json.Unmarshal(got, &g); err != nil {
+		return []string{"actual: " + err.Error()}
+	}
+	var out []string
+	diffValues("$", w, g, &out)
+	return out
+}
+
+func diffValues(path string, w, g any, out *[]string) {
+	wm, wok := w.(map[string]any)
+	gm, gok := g.(map[string]any)
+	if wok && gok {
+		keys := make(map[string]bool)
+		for k := range wm {
+			keys[k] = true
+		}
+		for k := range gm {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			diffValues(path+"."+k, wm[k], gm[k], out)
+		}
+		return
+	}
+	wa, wok := w.([]any)
+	ga, gok := g.([]any)
+	if wok && gok && len(wa) == len(ga) {
+		for i := range wa {
+			diffValues(fmt.Sprintf("%s[%d]", path, i), wa[i], ga[i], out)
+		}
+		return
+	}
+	if !reflect.DeepEqual(w, g) {
+		*out = append(*out, path)
+	}
+}
+
+// missingJSONKeys: ключи верхнего уровня из эталонного ответа, которых нет в новом
+func missingJSONKeys(want, got string) []string {
+	var w, g map[string]any
+	if json.Unmarshal([]byte(want), &w) != nil {
+		return nil
+	}
+	if json.Unmarshal([]byte(got), &g) != nil {
+		return []string{"<body is not a JSON object>"}
+	}
+	var out []string
+	for k := range w {
+		if _, ok := g[k]; !ok {
+			out = append(out, k)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/kube"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const (
+	defaultDeployDuration = 30 * time.Minute
+	defaultDeployPoll     = 10 * time.Second
+)
+
+// источники выкладок
+const (
+	deployFromFile = "file"
+	deployFromAPI  = "api"
+	deployFromKube = "kube"
+)
+
+// deployWindow — выкладка: до Until смена трафика на интерфейсах по маске ожидаема
+type deployWindow struct {
+	ID        string    `json:"id"`
+	Interface string    `json:"interface"`
+	Source    string    `json:"source"`
+	Since     time.Time `json:"since"`
+	Until     time.Time `json:"until"`
+	By        string    `json:"by,omitempty"`
+}
+
+func (dw deployWindow) matches(iface string) bool {
+	ok, _ := filepath.Match(dw.Interface, iface)
+	return ok
+}
+
+// deployTracker собирает выкладки из DEPLOY_FILE, аннотации Node (DEPLOY_ANNOTATION) и API.
+// Пока выкладка идёт, алерты на её интерфейсах заглушены, а отчёты помечены её ID.
+// API и опрос сигналов работают в своих горутинах, поэтому всё состояние — под mu
+type deployTracker struct {
+	duration   time.Duration
+	poll       time.Duration
+	file       string
+	annotation string
+	node       string
+	saDir      string
+	client     *kube.Client
+
+	mu      sync.Mutex
+	windows []deployWindow
+	// последний сигнал каждого источника: тот же сигнал после конца окна его не открывает
+	signals map[string]string
+	pending []reporter.Event
+}
+
+// start открывает выкладку или продлевает её, если ID уже идёт; iface — маска (пусто — все)
+func (d *deployTracker) start(id, iface string, dur time.Duration, source, by string, now time.Time) (deployWindow, error) {
+	if id == "" {
+		return deployWindow{}, fmt.Errorf("deploy id is required")
+	}
+	if iface == "" {
+		iface = "*"
+	}
+	if _, err := filepath.Match(iface, ""); err != nil {
+		return deployWindow{}, fmt.Errorf("invalid interface pattern %q", iface)
+	}
+	if dur <= 0 {
+		return deployWindow{}, fmt.Errorf("duration must be positive")
+	}
+	dw := deployWindow{ID: id, Interface: iface, Source: source, Since: now, Until: now.Add(dur), By: by}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if i := slices.IndexFunc(d.windows, func(w deployWindow) bool { return w.ID == id }); i >= 0 {
+		dw.Since = d.windows[i].Since
+		d.windows[i] = dw
+	} else {
+		d.windows = append(d.windows, dw)
+	}
+	d.pending = append(d.pending, reporter.Event{
+		Type:    "deploy_started",
+		Message: msg.Text(msg.DeployStarted, dw.ID, dw.Interface, dw.Until.UTC().Format(time.RFC3339), source, cmp.Or(by, "unknown")),
+		Data:    map[string]any{"deploy": dw},
+	})
+	return dw, nil
+}
+
+// finish закрывает выкладку досрочно
+func (d *deployTracker) finish(id, reason string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	i := slices.IndexFunc(d.windows, func(w deployWindow) bool { return w.ID == id })
+	if i < 0 {
+		return false
+	}
+	d.closeLocked(i, reason)
+	return true
+}
+
+func (d *deployTracker) closeLocked(i int, reason string) {
+	dw := d.windows[i]
+	d.windows = slices.Delete(d.windows, i, i+1)
+	d.pending = append(d.pending, reporter.Event{
+		Type:    "deploy_finished",
+		Message: msg.Text(msg.DeployFinished, dw.ID, dw.Interface, reason),
+		Data:    map[string]any{"deploy": dw, "reason": reason},
+	})
+}
+
+// signal применяет состояние источника by: value — "ID [маска]", пусто — сигнала нет.
+// key отличает новый сигнал от прежнего (для файла — вместе с mtime)
+func (d *deployTracker) signal(source, by, value, key string, since, now time.Time) {
+	d.mu.Lock()
+	if d.signals == nil {
+		d.signals = make(map[string]string)
+	}
+	prev := d.signals[source]
+	d.signals[source] = key
+	if key == prev {
+		d.mu.Unlock()
+		return
+	}
+	// прежний сигнал источника больше не действует: новый ID или снятие
+	reason := "signal replaced"
+	if value == "" {
+		reason = "signal removed"
+	}
+	for i := len(d.windows) - 1; i >= 0; i-- {
+		if d.windows[i].Source == source {
+			d.closeLocked(i, reason)
+		}
+	}
+	d.mu.Unlock()
+
+	fields := strings.Fields(value)
+	if len(fields) == 0 || !since.Add(d.duration).After(now) {
+		return
+	}
+	iface := ""
+	if len(fields) > 1 {
+		iface = fields[1]
+	}
+	if _, err := d.start(fields[0], iface, since.Add(d.duration).Sub(now), source, by, now); err != nil {
+		msg.Warnf(msg.DeployError, fmt.Errorf("%s: %w", source, err))
+	}
+}
+
+// run опрашивает файл и аннотацию Node раз в poll; ошибки API не фатальны
+func (d *deployTracker) run(ctx context.Context) {
+	if d.file == "" && d.client == nil {
+		return
+	}
+	ticker := time.NewTicker(d.poll)
+	defer ticker.Stop()
+	for {
+		d.pollSignals(ctx, time.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (d *deployTracker) pollSignals(ctx context.Context, now time.Time) {
+	if d.file != "" {
+		st, err := os.Stat(d.file)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			d.signal(deployFromFile, d.file, "", "", now, now)
+		case err != nil:
+			msg.Warnf(msg.DeployError, err)
+		default:
+			b, err := os.ReadFile(d.file)
+			if err != nil {
+				msg.Warnf(msg.DeployError, err)
+				break
+			}
+			// пустой файл — выкладка без имени, ID по времени касания
+			value := strings.TrimSpace(string(b))
+			if value == "" {
+				value = "file-" + strconv.FormatInt(st.ModTime().Unix(), 10)
+			}
+			d.signal(deployFromFile, d.file, value, value+"@"+st.ModTime().String(), st.ModTime(), now)
+		}
+	}
+	if d.client != nil {
+		ann, err := d.client.NodeAnnotations(ctx, d.node)
+		if err != nil {
+			msg.Warnf(msg.DeployError, err)
+			return
+		}
+		value := strings.TrimSpace(ann[d.annotation])
+		d.signal(deployFromKube, d.annotation, value, value, now, now)
+	}
+}
+
+// drain закрывает истёкшие выкладки и возвращает накопленные события
+func (d *deployTracker) drain(now time.Time) []reporter.Event {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.windows) - 1; i >= 0; i-- {
+		if !now.Before(d.windows[i].Until) {
+			d.closeLocked(i, "expired")
+		}
+	}
+	evs := d.pending
+	d.pending = nil
+	return evs
+}
+
+// snapshot — идущие выкладки
+func (d *deployTracker) snapshot() []deployWindow {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return slices.Clone(d.windows)
+}
+
+// marks — идущие выкладки для отчёта
+func (d *deployTracker) marks() []reporter.DeployMark {
+	var out []reporter.DeployMark
+	for _, dw := range d.snapshot() {
+		out = append(out, reporter.DeployMark{ID: dw.ID, Interface: dw.Interface, Source: dw.Source, Since: dw.Since.UTC().Unix(), Until: dw.Until.UTC().Unix()})
+	}
+	return out
+}
+
+// match — выкладка с самым поздним концом, в зону которой попадает iface
+func (d *deployTracker) match(iface string) (deployWindow, bool) {
+	var best deployWindow
+	found := false
+	for _, dw := range d.snapshot() {
+		if dw.matches(iface) && (!found || dw.Until.After(best.Until)) {
+			best, found = dw, true
+		}
+	}
+	return best, found
+}
+
+// deployRequest — тело POST /v1/deploys
+type deployRequest struct {
+	ID        string `json:"id"`
+	Interface string `json:"interface"`
+	Duration  string `json:"duration,omitempty"`
+	By        string `json:"by,omitempty"`
+}
+
+// registerDeployAPI — отметка выкладок из CI/CD: POST перед выкладкой, DELETE после
+func registerDeployAPI(mux *http.ServeMux, deploys *deployTracker, writeToken string) {
+	mux.HandleFunc("GET /v1/deploys", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, append([]deployWindow{}, deploys.snapshot()...))
+	})
+	mux.HandleFunc("POST /v1/deploys", func(w http.ResponseWriter, r *http.Request) {
+		if !privileged(w, r, writeToken) {
+			return
+		}
+		var req deployRequest
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&req); err != nil || req.ID == "" {
+			http.Error(w, "want JSON with id, interface", http.StatusBadRequest)
+			return
+		}
+		dur := deploys.duration
+		if req.Duration != "" {
+			var err error
+			if dur, err = time.ParseDuration(req.Duration); err != nil {
+				http.Error(w, "invalid duration", http.StatusBadRequest)
+				return
+			}
+		}
+		dw, err := deploys.start(req.ID, req.Interface, dur, deployFromAPI, req.By, time.Now())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		writeJSON(w, dw)
+	})
+	mux.HandleFunc("DELETE /v1/deploys/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !privileged(w, r, writeToken) {
+			return
+		}
+		if !deploys.finish(r.PathValue("id"), "finished by "+cmp.Or(r.URL.Query().Get("by"), "unknown")) {
+			http.Error(w, "no such deploy", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"encoding/base64"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// runEncryptionKey создаёт ключ сервера для шифрования отчётов: строку для ENCRYPTION_KEYS
+// агентов и закрытый ключ для reporter.Decrypter на приёме
+func runEncryptionKey(args []string) int {
+	fs := flag.NewFlagSet("encryption-key", flag.ContinueOnError)
+	kid := fs.String("kid", "", "key id, e.g. 2026-10")
+	notBefore := fs.String("not-before", "", "RFC 3339 time agents start using the key (empty — at once)")
+	notAfter := fs.String("not-after", "", "RFC 3339 time agents stop using the key (empty — never)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *kid == "" || strings.ContainsAny(*kid, " \t,") {
+		fmt.Fprintln(os.Stderr, "encryption-key: -kid is required and must not contain spaces or commas")
+		return 2
+	}
+	for _, v := range []string{*notBefore, *notAfter} {
+		if _, err := time.Parse(time.RFC3339, v); v != "" && err != nil {
+			fmt.Fprintf(os.Stderr, "encryption-key: %v\n", err)
+			return 2
+		}
+	}
+
+	priv, err := reporter.GenerateEncryptionKey()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "encryption-key: %v\n", err)
+		return 1
+	}
+	line := *kid + " " + base64.StdEncoding.EncodeToString(priv.PublicKey().Bytes())
+	if *notBefore != "" {
+		line += " not_before=" + *notBefore
+	}
+	if *notAfter != "" {
+		line += " not_after=" + *notAfter
+	}
+	fmt.Printf("# ENCRYPTION_KEYS on agents\n%s\n", line)
+	fmt.Printf("# private key for the ingest server, keep secret\n%s %s\n", *kid, base64.StdEncoding.EncodeToString(priv.Bytes()))
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/enrich"
+	"github.com/iflixer/network-stater/src/pkg/kube"
+)
+
+// этапы обогащения отчёта (ENRICH)
+const (
+	enrichStatic     = "static"
+	enrichCloud      = "cloud"
+	enrichKubernetes = "kubernetes"
+	enrichExec       = "exec"
+)
+
+const (
+	defaultEnrichTimeout = 2 * time.Second
+	defaultK8sNodeLabels = "topology.kubernetes.io/region,topology.kubernetes.io/zone,node.kubernetes.io/instance-type"
+)
+
+// время жизни результата этапа; static читается один раз
+var defaultEnrichTTL = map[string]time.Duration{
+	enrichCloud:      time.Hour,
+	enrichKubernetes: 5 * time.Minute,
+	enrichExec:       5 * time.Minute,
+}
+
+// newEnrichChain собирает цепочку меток отчёта из ENRICH: порядок этапов задаёт приоритет,
+// при совпадении ключа побеждает более поздний этап. Без ENRICH — static и те этапы,
+// для которых задана своя настройка (ENRICH_CLOUD, ENRICH_K8S_LABELS, ENRICH_EXEC)
+func newEnrichChain(cfg *config) (*enrich.Chain, error) {
+	stages := splitList(os.Getenv("ENRICH"))
+	if len(stages) == 0 {
+		stages = []string{enrichStatic}
+		for _, s := range []struct{ name, env string }{
+			{enrichCloud, "ENRICH_CLOUD"}, {enrichKubernetes, "ENRICH_K8S_LABELS"}, {enrichExec, "ENRICH_EXEC"},
+		} {
+			if os.Getenv(s.env) != "" {
+				stages = append(stages, s.name)
+			}
+		}
+	}
+	timeouts, err := parseTimeouts(os.Getenv("ENRICH_TIMEOUTS"))
+	if err != nil {
+		return nil, fmt.Errorf("ENRICH_TIMEOUTS: %w", err)
+	}
+	ttls, err := parseTimeouts(os.Getenv("ENRICH_TTLS"))
+	if err != nil {
+		return nil, fmt.Errorf("ENRICH_TTLS: %w", err)
+	}
+	timeout := envDuration("ENRICH_TIMEOUT", defaultEnrichTimeout)
+
+	chain := &enrich.Chain{}
+	seen := make(map[string]bool)
+	for _, name := range stages {
+		if seen[name] {
+			return nil, fmt.Errorf("ENRICH: stage %q listed twice", name)
+		}
+		seen[name] = true
+		var stage enrich.Stage
+		switch name {
+		case enrichStatic:
+			stage = enrich.Static(cfg.labels)
+		case enrichCloud:
+			p := envString("ENRICH_CLOUD", enrich.CloudAuto)
+			if p != enrich.CloudAuto && p != enrich.CloudAWS && p != enrich.CloudGCP && p != enrich.CloudAzure {
+				return nil, fmt.Errorf("ENRICH_CLOUD: unknown provider %q", p)
+			}
+			stage = &enrich.Cloud{Provider: p, BaseURL: os.Getenv("ENRICH_CLOUD_URL")}
+		case enrichKubernetes:
+			if cfg.nodeName == "" {
+				return nil, fmt.Errorf("ENRICH: kubernetes stage needs NODE_NAME")
+			}
+			client, err := kube.InCluster(os.Getenv("K8S_SERVICE_ACCOUNT_DIR"))
+			if err != nil {
+				return nil, fmt.Errorf("ENRICH: kubernetes: %w", err)
+			}
+			stage = &enrich.Kubernetes{Client: client, Node: cfg.nodeName, Keys: splitList(envString("ENRICH_K8S_LABELS", defaultK8sNodeLabels))}
+		case enrichExec:
+			command := strings.Fields(os.Getenv("ENRICH_EXEC"))
+			if len(command) == 0 {
+				return nil, fmt.Errorf("ENRICH: exec stage needs ENRICH_EXEC")
+			}
+			stage = &enrich.Exec{Command: command}
+		default:
+			return nil, fmt.Errorf("ENRICH: unknown stage %q", name)
+		}
+		step := &enrich.Step{Stage: stage, Timeout: timeout, TTL: defaultEnrichTTL[name]}
+		if t, ok := timeouts[name]; ok {
+			step.Timeout = t
+		}
+		if t, ok := ttls[name]; ok {
+			step.TTL = t
+		}
+		chain.Steps = append(chain.Steps, step)
+	}
+	return chain, nil
+}
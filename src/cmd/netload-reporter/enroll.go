@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/netip"
+	neturl "net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const defaultKeyFile = "agent.key"
+
+// signingKeyPath: SIGNING_KEY или agent.key в STATE_DIR
+func signingKeyPath() string {
+	if p := os.Getenv("SIGNING_KEY"); p != "" {
+		return p
+	}
+	return filepath.Join(envString("STATE_DIR", "."), defaultKeyFile)
+}
+
+// enrollment — то, что агент сообщает серверу при регистрации ключа
+type enrollment struct {
+	Host      string `json:"host"`
+	NodeName  string `json:"node_name,omitempty"`
+	KeyID     string `json:"key_id"`
+	PublicKey string `json:"public_key"`
+}
+
+// runEnroll создаёт ключ агента (если его ещё нет), печатает публичный ключ и,
+// если задан ENROLL_URL, регистрирует его на сервере подписанным запросом
+func runEnroll(args []string) int {
+	fs := flag.NewFlagSet("enroll", flag.ContinueOnError)
+	keyPath := fs.String("key", signingKeyPath(), "private key file")
+	url := fs.String("url", os.Getenv("ENROLL_URL"), "enrollment endpoint (empty — only generate the key)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	signer, created, err := loadOrGenerateKey(*keyPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "enroll: %v\n", err)
+		return 1
+	}
+	if created {
+		fmt.Printf("generated %s\n", *keyPath)
+	} else {
+		fmt.Printf("using existing %s\n", *keyPath)
+	}
+	fmt.Printf("key_id     %s\npublic_key %s\n", signer.KeyID, signer.PublicKey())
+	if *url == "" {
+		return 0
+	}
+
+	if err := register(signer, *url); err != nil {
+		fmt.Fprintf(os.Stderr, "enroll: %v\n", err)
+		return 1
+	}
+	fmt.Printf("registered at %s\n", *url)
+	return 0
+}
+
+// register отправляет публичный ключ на url запросом, подписанным самим ключом
+func register(signer *reporter.Signer, url string) error {
+	sink := reporter.Sink{Name: "enroll", URL: url, APIKey: os.Getenv("API_KEY"), Encoding: reporter.EncodingJSON}
+	body, err := reporter.Encode(enrollment{
+		Host:      hostname(),
+		NodeName:  os.Getenv("NODE_NAME"),
+		KeyID:     signer.KeyID,
+		PublicKey: signer.PublicKey(),
+	}, sink.Encoding)
+	if err != nil {
+		return err
+	}
+	// подпись самим ключом — доказательство, что агент им владеет
+	signer.Sign(&body)
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+	proxy, err := envProxy()
+	if err != nil {
+		return err
+	}
+	src, err := envSource()
+	if err != nil {
+		return err
+	}
+	return sink.Post(ctx, reporter.NewSourceHTTPClient(defaultConnectTimeout, defaultResponseTimeout, proxy, src), body)
+}
+
+// envProxy — PROXY_URL для запросов вне основного цикла (enroll, начальная настройка)
+func envProxy() (*neturl.URL, error) {
+	v := os.Getenv("PROXY_URL")
+	if v == "" {
+		return nil, nil
+	}
+	proxy, err := reporter.ParseProxy(v)
+	if err != nil {
+		return nil, fmt.Errorf("PROXY_URL: %w", err)
+	}
+	return proxy, nil
+}
+
+func loadOrGenerateKey(path string) (*reporter.Signer, bool, error) {
+	s, err := reporter.LoadSigner(path)
+	if err == nil {
+		return s, false, nil
+	}
+	if !errors.Is(err, fs.ErrNotExist) {
+		return nil, false, err
+	}
+	s, err = reporter.GenerateKey(path)
+	return s, err == nil, err
+}
+
+// envSource — SOURCE_ADDR, SOURCE_INTERFACE и PROXY_PROTOCOL: через какую сеть идут соединения агента
+func envSource() (reporter.Source, error) {
+	var src reporter.Source
+	if v := os.Getenv("SOURCE_ADDR"); v != "" {
+		addr, err := netip.ParseAddr(v)
+		if err != nil {
+			return src, fmt.Errorf("SOURCE_ADDR: %w", err)
+		}
+		src.Addr = addr
+	}
+	if v := os.Getenv("SOURCE_INTERFACE"); v != "" {
+		if _, err := net.InterfaceByName(v); err != nil {
+			return src, fmt.Errorf("SOURCE_INTERFACE: %w", err)
+		}
+		src.Device = v
+	}
+	src.ProxyProtocol = os.Getenv("PROXY_PROTOCOL")
+	if !reporter.ValidProxyProtocol(src.ProxyProtocol) {
+		return src, fmt.Errorf("PROXY_PROTOCOL: want v1 or v2, got %q", src.ProxyProtocol)
+	}
+	return src, nil
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/iflixer/network-stater/src/pkg/bus"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// куда уходят отчёты (EXPORTER); canary и события — всегда HTTP
+const (
+	exporterHTTP  = "http"
+	exporterNATS  = "nats"
+	exporterKafka = "kafka"
+)
+
+func loadBusConfig(cfg *config) error {
+	tlsCfg := bus.TLSConfig{
+		Enabled:  envBool("BUS_TLS"),
+		CA:       os.Getenv("BUS_TLS_CA"),
+		Cert:     os.Getenv("BUS_TLS_CERT"),
+		Key:      os.Getenv("BUS_TLS_KEY"),
+		Insecure: envBool("BUS_TLS_INSECURE"),
+	}
+	// заданные сертификаты сами по себе включают TLS
+	tlsCfg.Enabled = tlsCfg.Enabled || tlsCfg.CA != "" || tlsCfg.Cert != ""
+
+	switch cfg.exporter {
+	case exporterNATS:
+		cfg.nats = bus.NATSConfig{
+			URL:      os.Getenv("NATS_URL"),
+			Subject:  envString("NATS_SUBJECT", bus.DefaultNATSSubject),
+			Creds:    os.Getenv("NATS_CREDS"),
+			User:     os.Getenv("NATS_USER"),
+			Password: os.Getenv("NATS_PASSWORD"),
+			Token:    os.Getenv("NATS_TOKEN"),
+			TLS:      tlsCfg,
+		}
+		if cfg.nats.URL == "" {
+			return fmt.Errorf("NATS_URL is required for EXPORTER=nats")
+		}
+	case exporterKafka:
+		cfg.kafka = bus.KafkaConfig{
+			Brokers:       splitList(os.Getenv("KAFKA_BROKERS")),
+			Topic:         envString("KAFKA_TOPIC", bus.DefaultKafkaTopic),
+			SASLMechanism: strings.ToLower(os.Getenv("KAFKA_SASL_MECHANISM")),
+			User:          os.Getenv("KAFKA_USER"),
+			Password:      os.Getenv("KAFKA_PASSWORD"),
+			TLS:           tlsCfg,
+		}
+		if len(cfg.kafka.Brokers) == 0 {
+			return fmt.Errorf("KAFKA_BROKERS is required for EXPORTER=kafka")
+		}
+		switch cfg.kafka.SASLMechanism {
+		case "", bus.SASLPlain, bus.SASLScramSHA256, bus.SASLScramSHA512:
+		default:
+			return fmt.Errorf("KAFKA_SASL_MECHANISM: unknown mechanism %q", cfg.kafka.SASLMechanism)
+		}
+	}
+	return nil
+}
+
+// newPrimaryExporter возвращает основной экспортёр (nil — отчёты никуда не шлются, только API)
+// и его описание для логов
+func newPrimaryExporter(cfg *config, host string, client *http.Client) (reporter.Exporter, string, error) {
+	switch cfg.exporter {
+	case exporterNATS:
+		n, err := bus.NewNATS(cfg.primary.Name, host, cfg.nats)
+		if err != nil {
+			return nil, "", err
+		}
+		return n, "nats " + n.Subject(), nil
+	case exporterKafka:
+		k, err := bus.NewKafka(cfg.primary.Name, host, cfg.kafka)
+		if err != nil {
+			return nil, "", err
+		}
+		return k, "kafka " + k.Topic(), nil
+	}
+	if cfg.primary.URL == "" {
+		return nil, "", nil
+	}
+	return reporter.HTTPExporter{Sink: cfg.primary, Client: client}, cfg.primary.URL, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// порог, выше которого считаем, что трафик ушёл на резервный канал
+const defaultBackupActiveBps = 1024
+
+type failoverWatch struct {
+	patterns     []string
+	thresholdBps float64
+	links        map[string]*reporter.BackupLinkUsage
+}
+
+func newFailoverWatch(patterns []string, thresholdBps float64) *failoverWatch {
+	return &failoverWatch{patterns: patterns, thresholdBps: thresholdBps, links: make(map[string]*reporter.BackupLinkUsage)}
+}
+
+func (f *failoverWatch) observe(ctx context.Context, bus *reporter.EventBus, cur, prev map[string]collector.Counters, sec float64, now time.Time) []reporter.BackupLinkUsage {
+	var out []reporter.BackupLinkUsage
+	for iface, c := range cur {
+		if !collector.MatchAny(f.patterns, iface) {
+			continue
+		}
+		p, ok := prev[iface]
+		if !ok {
+			continue
+		}
+		l := f.links[iface]
+		if l == nil {
+			l = &reporter.BackupLinkUsage{Interface: iface}
+			f.links[iface] = l
+		}
+		drx, dtx := collector.Delta(c.Rx, p.Rx), collector.Delta(c.Tx, p.Tx)
+		l.RxBytesPerSec, l.TxBytesPerSec = drx/sec, dtx/sec
+		l.TotalRxBytes += uint64(drx)
+		l.TotalTxBytes += uint64(dtx)
+
+		busy := l.RxBytesPerSec+l.TxBytesPerSec >= f.thresholdBps
+		switch {
+		case busy && !l.Active:
+			l.Active, l.ActiveSince = true, now.UTC().Unix()
+			l.RxBytes, l.TxBytes = uint64(drx), uint64(dtx)
+			emit(ctx, bus, reporter.Event{
+				Type:      "backup_link_active",
+				Timestamp: l.ActiveSince,
+				Interface: iface,
+				Message:   msg.Text(msg.FailoverActive, iface, l.RxBytesPerSec, l.TxBytesPerSec),
+				Data:      map[string]any{"rx_bytes_per_sec": l.RxBytesPerSec, "tx_bytes_per_sec": l.TxBytesPerSec},
+			})
+		case busy:
+			l.RxBytes += uint64(drx)
+			l.TxBytes += uint64(dtx)
+		case l.Active:
+			l.Active = false
+			l.RxBytes += uint64(drx)
+			l.TxBytes += uint64(dtx)
+			emit(ctx, bus, reporter.Event{
+				Type:      "backup_link_idle",
+				Interface: iface,
+				Message:   msg.Text(msg.FailoverIdle, iface, now.Sub(time.Unix(l.ActiveSince, 0)).Round(time.Second), l.RxBytes, l.TxBytes),
+				Data:      map[string]any{"active_since": l.ActiveSince, "rx_bytes": l.RxBytes, "tx_bytes": l.TxBytes},
+			})
+		}
+		out = append(out, *l)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Interface < out[j].Interface })
+	return out
+}
+
+func emit(ctx context.Context, bus *reporter.EventBus, ev reporter.Event) {
+	if err := bus.Emit(ctx, ev); err != nil {
+		msg.Error(err)
+	}
+}
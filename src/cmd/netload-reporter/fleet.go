@@ -0,0 +1,217 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const (
+	defaultFleetTop    = 10
+	defaultFleetSilent = time.Hour
+)
+
+// fleetHost — узел в сводке: трафик за сутки (rx+tx) и последний отчёт
+type fleetHost struct {
+	Host           string  `json:"host"`
+	Bytes          float64 `json:"bytes"`
+	PeakBitsPerSec float64 `json:"peak_bits_per_sec"`
+	Samples        int     `json:"samples"`
+	LastSeen       int64   `json:"last_seen"`
+}
+
+// fleetChange — изменение трафика узла к предыдущим суткам; prev_bytes 0 — узел новый
+type fleetChange struct {
+	Host      string   `json:"host"`
+	Bytes     float64  `json:"bytes"`
+	PrevBytes float64  `json:"prev_bytes"`
+	ChangePct *float64 `json:"change_pct,omitempty"`
+}
+
+// fleetDigest — суточная сводка по парку
+type fleetDigest struct {
+	Day        string        `json:"day"`
+	Hosts      int           `json:"hosts"`
+	TotalBytes float64       `json:"total_bytes"`
+	Top        []fleetHost   `json:"top"`
+	Changes    []fleetChange `json:"changes"`
+	Silent     []fleetHost   `json:"silent"`
+}
+
+// runFleet — суточная сводка по отчётам многих узлов: самые нагруженные, наибольшие изменения
+// к предыдущим суткам и замолчавшие, разово по выгрузке отчётов (или по metrics/ из STORE_DIR
+// узлов); -webhook отправляет её JSON-ом, например в чат или почтовый шлюз. По расписанию и с API
+// сводку строит серверный режим (runServer)
+func runFleet(args []string) int {
+	fs := flag.NewFlagSet("fleet", flag.ContinueOnError)
+	day := fs.String("day", time.Now().UTC().AddDate(0, 0, -1).Format(time.DateOnly), "UTC day to summarize, YYYY-MM-DD (default yesterday)")
+	top := fs.Int("top", defaultFleetTop, "hosts in each list")
+	silent := fs.Duration("silent", defaultFleetSilent, "host is silent when its last report is older than this at the end of the day")
+	webhook := fs.String("webhook", os.Getenv("FLEET_WEBHOOK_URL"), "POST the digest as JSON here (API_KEY as bearer token)")
+	asJSON := fs.Bool("json", false, "print the digest as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netload-reporter fleet [flags] reports.json...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	start, err := time.Parse(time.DateOnly, *day)
+	if err != nil || fs.NArg() == 0 || *top <= 0 || *silent <= 0 {
+		fs.Usage()
+		return 2
+	}
+
+	var samples []reporter.Payload
+	for _, path := range fs.Args() {
+		s, err := readSamples(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "fleet: %s: %v\n", path, err)
+			return 1
+		}
+		samples = append(samples, s...)
+	}
+	d := buildFleetDigest(samples, start, *top, *silent, time.Now())
+
+	if *asJSON {
+		b, _ := json.MarshalIndent(d, "", "  ")
+		fmt.Println(string(b))
+	} else {
+		printFleetDigest(os.Stdout, d)
+	}
+	if *webhook == "" {
+		return 0
+	}
+	if err := postFleetDigest(context.Background(), *webhook, d); err != nil {
+		fmt.Fprintf(os.Stderr, "fleet: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// postFleetDigest отправляет сводку JSON-ом на url (API_KEY — bearer token)
+func postFleetDigest(ctx context.Context, url string, d fleetDigest) error {
+	proxy, err := envProxy()
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	sink := reporter.Sink{Name: "fleet", URL: url, APIKey: os.Getenv("API_KEY"), Encoding: reporter.EncodingJSON}
+	return sink.SendValue(ctx, reporter.NewHTTPClient(defaultConnectTimeout, defaultResponseTimeout, proxy), d)
+}
+
+// buildFleetDigest считает сводку за сутки [start, start+24h); замолчавшие — узлы, отчитавшиеся
+// в эти или предыдущие сутки, но не за последние silent (для текущих суток — до now)
+func buildFleetDigest(samples []reporter.Payload, start time.Time, top int, silent time.Duration, now time.Time) fleetDigest {
+	end := start.Add(24 * time.Hour)
+	prevStart := start.Add(-24 * time.Hour)
+	cur := make(map[string]*fleetHost)
+	prev := make(map[string]float64)
+	last := make(map[string]int64)
+	type key struct {
+		host string
+		ts   int64
+	}
+	seen := make(map[key]bool, len(samples))
+	for _, s := range samples {
+		// пересекающиеся выгрузки
+		k := key{s.Host, s.Timestamp}
+		t := time.Unix(s.Timestamp, 0)
+		if seen[k] || t.Before(prevStart) || !t.Before(end) {
+			continue
+		}
+		seen[k] = true
+		last[s.Host] = max(last[s.Host], s.Timestamp)
+		bytes := (s.RxBytesPerSec + s.TxBytesPerSec) * sampleSeconds(s)
+		if t.Before(start) {
+			prev[s.Host] += bytes
+			continue
+		}
+		h := cur[s.Host]
+		if h == nil {
+			h = &fleetHost{Host: s.Host}
+			cur[s.Host] = h
+		}
+		h.Bytes += bytes
+		h.PeakBitsPerSec = math.Max(h.PeakBitsPerSec, s.TotalBitsPerSec)
+		h.Samples++
+		h.LastSeen = max(h.LastSeen, s.Timestamp)
+	}
+
+	d := fleetDigest{Day: start.Format(time.DateOnly), Hosts: len(cur), Top: []fleetHost{}, Changes: []fleetChange{}, Silent: []fleetHost{}}
+	for _, h := range cur {
+		d.TotalBytes += h.Bytes
+		d.Top = append(d.Top, *h)
+		c := fleetChange{Host: h.Host, Bytes: h.Bytes, PrevBytes: prev[h.Host]}
+		if c.PrevBytes > 0 {
+			pct := (c.Bytes - c.PrevBytes) / c.PrevBytes * 100
+			c.ChangePct = &pct
+		}
+		d.Changes = append(d.Changes, c)
+	}
+	// ушедшие узлы — это и изменение, и тишина: в изменениях они со своими вчерашними байтами
+	for host, b := range prev {
+		if cur[host] == nil {
+			pct := -100.0
+			d.Changes = append(d.Changes, fleetChange{Host: host, PrevBytes: b, ChangePct: &pct})
+		}
+	}
+	slices.SortFunc(d.Top, func(a, b fleetHost) int { return cmp.Or(cmp.Compare(b.Bytes, a.Bytes), cmp.Compare(a.Host, b.Host)) })
+	// по абсолютному изменению: полторы сотни процентов у почти пустого узла не так важны, как десять у нагруженного
+	slices.SortFunc(d.Changes, func(a, b fleetChange) int {
+		return cmp.Or(cmp.Compare(math.Abs(b.Bytes-b.PrevBytes), math.Abs(a.Bytes-a.PrevBytes)), cmp.Compare(a.Host, b.Host))
+	})
+	d.Top, d.Changes = d.Top[:min(top, len(d.Top))], d.Changes[:min(top, len(d.Changes))]
+
+	cutoff := end
+	if now.Before(end) {
+		cutoff = now
+	}
+	for host, ts := range last {
+		if time.Unix(ts, 0).Before(cutoff.Add(-silent)) {
+			h := fleetHost{Host: host, LastSeen: ts}
+			if c := cur[host]; c != nil {
+				h = *c
+			}
+			d.Silent = append(d.Silent, h)
+		}
+	}
+	// давно молчащие первыми
+	slices.SortFunc(d.Silent, func(a, b fleetHost) int {
+		return cmp.Or(cmp.Compare(a.LastSeen, b.LastSeen), cmp.Compare(a.Host, b.Host))
+	})
+	return d
+}
+
+func printFleetDigest(w io.Writer, d fleetDigest) {
+	fmt.Fprintf(w, "fleet %s: %d hosts, %s total\n", d.Day, d.Hosts, formatSize(d.TotalBytes))
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	io.WriteString(tw, "\nTOP HOST\tTRAFFIC\tPEAK\tSAMPLES\n")
+	for _, h := range d.Top {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\n", h.Host, formatSize(h.Bytes), formatBits(h.PeakBitsPerSec/8), h.Samples)
+	}
+	io.WriteString(tw, "\nCHANGED HOST\tTRAFFIC\tDAY BEFORE\tCHANGE\n")
+	for _, c := range d.Changes {
+		change := "new"
+		if c.ChangePct != nil {
+			change = fmt.Sprintf("%+.0f%%", *c.ChangePct)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", c.Host, formatSize(c.Bytes), formatSize(c.PrevBytes), change)
+	}
+	io.WriteString(tw, "\nSILENT HOST\tLAST REPORT\n")
+	for _, h := range d.Silent {
+		fmt.Fprintf(tw, "%s\t%s\n", h.Host, time.Unix(h.LastSeen, 0).UTC().Format(time.DateTime))
+	}
+	tw.Flush()
+}
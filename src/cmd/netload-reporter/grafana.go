@@ -0,0 +1,213 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const defaultGrafanaUID = "netload-reporter"
+
+// grafanaQuery — запрос панели; %s в expr — селектор по переменным дашборда
+type grafanaQuery struct {
+	metric string
+	expr   string
+	legend string
+}
+
+// grafanaPanelSpec — панель дашборда; без данных (ни одной её метрики у агента) не выводится
+type grafanaPanelSpec struct {
+	title   string
+	unit    string
+	queries []grafanaQuery
+}
+
+func series(metric, legend string) grafanaQuery {
+	return grafanaQuery{metric: metric, expr: metric + "%s", legend: "{{host}} " + legend}
+}
+
+// панели — по метрикам /metrics (reporter.WritePrometheus): netload_<поле отчёта>, у счётчиков _total
+var grafanaPanels = []grafanaPanelSpec{
+	{"Traffic", "bps", []grafanaQuery{series("netload_rx_bits_per_sec", "rx"), series("netload_tx_bits_per_sec", "tx")}},
+	{"Traffic, 5m average", "bps", []grafanaQuery{series("netload_rx_bits_per_sec_5m", "rx"), series("netload_tx_bits_per_sec_5m", "tx")}},
+	{"Fleet total", "bps", []grafanaQuery{{metric: "netload_total_bits_per_sec", expr: "sum(netload_total_bits_per_sec%s)", legend: "total"}}},
+	{"Top hosts", "bps", []grafanaQuery{{metric: "netload_total_bits_per_sec", expr: "topk(10, netload_total_bits_per_sec%s)", legend: "{{host}}"}}},
+	{"IPv4 / IPv6", "bps", []grafanaQuery{
+		series("netload_ipv4_rx_bits_per_sec", "IPv4 rx"), series("netload_ipv4_tx_bits_per_sec", "IPv4 tx"),
+		series("netload_ipv6_rx_bits_per_sec", "IPv6 rx"), series("netload_ipv6_tx_bits_per_sec", "IPv6 tx"),
+	}},
+	{"Link speed downshift", "short", []grafanaQuery{series("netload_link_downshifted", "downshifted")}},
+	{"TCP connections", "short", []grafanaQuery{series("netload_tcp_established", "established"), series("netload_tcp_time_wait", "time_wait")}},
+	{"Conntrack table", "percent", []grafanaQuery{series("netload_conntrack_usage_pct", "used")}},
+	{"Month traffic", "decbytes", []grafanaQuery{
+		series("netload_month_rx_bytes_total", "rx"), series("netload_month_tx_bytes_total", "tx"),
+		series("netload_month_projected_rx_bytes", "rx projected"), series("netload_month_projected_tx_bytes", "tx projected"),
+	}},
+	{"Month quota", "percent", []grafanaQuery{series("netload_month_quota_used_pct", "used")}},
+	{"Burst budget", "percent", []grafanaQuery{series("netload_burst_budget_used_pct", "used")}},
+	{"Burst headroom", "bps", []grafanaQuery{series("netload_burst_headroom_bits_per_sec", "headroom")}},
+	{"Report latency", "ms", []grafanaQuery{series("netload_agent_last_report_latency_ms", "latency")}},
+	{"Report failures", "short", []grafanaQuery{
+		series("netload_agent_consecutive_report_failures", "consecutive failures"),
+		{metric: "netload_agent_samples_dropped_total", expr: "rate(netload_agent_samples_dropped_total%s[5m])", legend: "{{host}} dropped/s"},
+	}},
+}
+
+// runGrafana — готовый дашборд Grafana под метрики /metrics и метки LABELS: печатает его JSON
+// (для provisioning из файла) или с -url загружает через Grafana API. С -api берёт список метрик
+// у работающего агента и оставляет только панели, для которых у него есть данные
+func runGrafana(args []string) int {
+	fs := flag.NewFlagSet("grafana", flag.ContinueOnError)
+	url := fs.String("url", os.Getenv("GRAFANA_URL"), "Grafana base URL to provision the dashboard (empty — print JSON)")
+	token := fs.String("token", os.Getenv("GRAFANA_TOKEN"), "Grafana service account token")
+	folder := fs.String("folder", os.Getenv("GRAFANA_FOLDER"), "Grafana folder UID")
+	uid := fs.String("uid", defaultGrafanaUID, "dashboard UID: provisioning again replaces the dashboard")
+	labels := fs.String("labels", os.Getenv("LABELS"), "static labels that become dashboard variables, as in LABELS")
+	api := fs.String("api", "", "agent API address: only panels with data on this agent")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	names, err := grafanaLabels(*labels)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "grafana: labels: %v\n", err)
+		return 2
+	}
+
+	specs := grafanaPanels
+	if *api != "" {
+		have, err := agentMetrics(*api)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "grafana: %v\n", err)
+			return 1
+		}
+		specs = nil
+		for _, p := range grafanaPanels {
+			if slices.ContainsFunc(p.queries, func(q grafanaQuery) bool { return have[q.metric] }) {
+				specs = append(specs, p)
+			}
+		}
+	}
+	dashboard := grafanaDashboard(*uid, names, specs)
+
+	if *url == "" {
+		body, err := reporter.Encode(dashboard, reporter.EncodingJSON)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "grafana: %v\n", err)
+			return 1
+		}
+		fmt.Println(string(body.Data))
+		return 0
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := provisionGrafana(ctx, *url, *token, *folder, dashboard); err != nil {
+		fmt.Fprintf(os.Stderr, "grafana: %v\n", err)
+		return 1
+	}
+	fmt.Printf("dashboard %s: %d panels provisioned at %s\n", *uid, len(specs), *url)
+	return 0
+}
+
+// grafanaLabels — метки LABELS, которые становятся переменными дашборда (host и node_name уже есть)
+func grafanaLabels(labels string) ([]string, error) {
+	pairs, err := parseLabels(labels)
+	if err != nil {
+		return nil, err
+	}
+	var names []string
+	for k := range pairs {
+		if n := reporter.PromLabelName(k); n != "host" && n != "node_name" && !slices.Contains(names, n) {
+			names = append(names, n)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+// provisionGrafana загружает дашборд через Grafana API; тот же uid заменяет прежний
+func provisionGrafana(ctx context.Context, url, token, folder string, dashboard map[string]any) error {
+	proxy, err := envProxy()
+	if err != nil {
+		return err
+	}
+	sink := reporter.Sink{Name: "grafana", URL: strings.TrimSuffix(url, "/") + "/api/dashboards/db", APIKey: token, Encoding: reporter.EncodingJSON}
+	req := map[string]any{"dashboard": dashboard, "folderUid": folder, "overwrite": true, "message": "provisioned by netload-reporter"}
+	return sink.SendValue(ctx, reporter.NewHTTPClient(defaultConnectTimeout, defaultResponseTimeout, proxy), req)
+}
+
+// agentMetrics — имена метрик, которые агент отдаёт на /metrics
+func agentMetrics(addr string) (map[string]bool, error) {
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	resp, err := (&http.Client{Timeout: 5 * time.Second}).Get("http://" + addr + "/metrics")
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET /metrics: %s", resp.Status)
+	}
+	have := make(map[string]bool)
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		line := sc.Text()
+		if line == "" || line[0] == '#' {
+			continue
+		}
+		if i := strings.IndexAny(line, "{ "); i > 0 {
+			have[line[:i]] = true
+		}
+	}
+	return have, sc.Err()
+}
+
+// grafanaDashboard собирает модель дашборда: источник данных и метки — переменные,
+// панели по две в ряд
+func grafanaDashboard(uid string, labels []string, specs []grafanaPanelSpec) map[string]any {
+	ds := map[string]any{"type": "prometheus", "uid": "${datasource}"}
+	vars := []any{map[string]any{"name": "datasource", "label": "Data source", "type": "datasource", "query": "prometheus"}}
+	selector := []string{`host=~"$host"`}
+	for _, name := range append([]string{"host"}, labels...) {
+		vars = append(vars, map[string]any{
+			"name": name, "type": "query", "datasource": ds,
+			"query":      map[string]any{"query": "label_values(netload_total_bits_per_sec, " + name + ")", "refId": name},
+			"refresh":    2,
+			"includeAll": true, "multi": true, "allValue": ".*",
+			"current": map[string]any{"text": "All", "value": "$__all"},
+		})
+		if name != "host" {
+			selector = append(selector, name+`=~"$`+name+`"`)
+		}
+	}
+	sel := "{" + strings.Join(selector, ",") + "}"
+
+	panels := make([]any, 0, len(specs))
+	for i, p := range specs {
+		targets := make([]any, len(p.queries))
+		for j, q := range p.queries {
+			targets[j] = map[string]any{"refId": string(rune('A' + j)), "datasource": ds, "expr": fmt.Sprintf(q.expr, sel), "legendFormat": q.legend}
+		}
+		panels = append(panels, map[string]any{
+			"id": i + 1, "type": "timeseries", "title": p.title, "datasource": ds,
+			"gridPos":     map[string]int{"x": i % 2 * 12, "y": i / 2 * 8, "w": 12, "h": 8},
+			"fieldConfig": map[string]any{"defaults": map[string]any{"unit": p.unit}, "overrides": []any{}},
+			"targets":     targets,
+		})
+	}
+	return map[string]any{
+		"uid": uid, "title": "netload-reporter", "tags": []string{"netload"},
+		"timezone": "utc", "refresh": "1m", "schemaVersion": 39,
+		"time":       map[string]string{"from": "now-6h", "to": "now"},
+		"templating": map[string]any{"list": vars},
+		"panels":     panels,
+	}
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/kube"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// режимы публикации нагрузки на объект Node (K8S_NODE_PUBLISH)
+const (
+	kubeOff         = "off"
+	kubeAnnotations = "annotations"
+	kubeCondition   = "condition"
+)
+
+const (
+	defaultKubeInterval         = time.Minute
+	defaultKubeAnnotationPrefix = "network-stater.iflixer.com/"
+	defaultKubeConditionType    = "NetworkLoadHigh"
+)
+
+type nodePublisher struct {
+	mode          string
+	node          string
+	interval      time.Duration
+	prefix        string
+	conditionType string
+	highLoadBps   float64 // бит/с, по 5-минутному среднему
+	saDir         string
+
+	client     *kube.Client
+	status     string
+	transition time.Time
+}
+
+// run раз в interval публикует последний отчёт из кольца; ошибки API не фатальны
+func (n *nodePublisher) run(ctx context.Context, ring *payloadRing) {
+	ticker := time.NewTicker(n.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := n.publish(ctx, ring, time.Now()); err != nil {
+				msg.Errorf(msg.KubeError, err)
+			}
+		}
+	}
+}
+
+func (n *nodePublisher) publish(ctx context.Context, ring *payloadRing, now time.Time) error {
+	pl, ok := ring.latest()
+	if !ok {
+		return nil
+	}
+	cur := pl.TotalBitsPerSec
+	avg := average5m(ring, now)
+
+	if n.mode == kubeAnnotations {
+		return n.client.AnnotateNode(ctx, n.node, map[string]string{
+			n.prefix + "rx-bps":       strconv.FormatFloat(pl.RxBitsPerSec, 'f', 0, 64),
+			n.prefix + "tx-bps":       strconv.FormatFloat(pl.TxBitsPerSec, 'f', 0, 64),
+			n.prefix + "total-bps":    strconv.FormatFloat(cur, 'f', 0, 64),
+			n.prefix + "total-bps-5m": strconv.FormatFloat(avg, 'f', 0, 64),
+			n.prefix + "updated-at":   time.Unix(pl.Timestamp, 0).UTC().Format(time.RFC3339),
+		})
+	}
+
+	status, reason := "False", "NetworkLoadNormal"
+	if avg >= n.highLoadBps {
+		status, reason = "True", "NetworkLoadHigh"
+	}
+	if status != n.status {
+		if n.status != "" {
+			msg.Printf(msg.KubeCondition, n.node, n.conditionType, status)
+		}
+		n.status, n.transition = status, now
+	}
+	return n.client.SetNodeCondition(ctx, n.node, kube.Condition{
+		Type:               n.conditionType,
+		Status:             status,
+		Reason:             reason,
+		Message:            msg.Text(msg.KubeHighLoad, cur, avg, n.highLoadBps),
+		LastHeartbeatTime:  now.UTC().Truncate(time.Second),
+		LastTransitionTime: n.transition.UTC().Truncate(time.Second),
+	})
+}
+
+// average5m — среднее total_bits_per_sec за 5 минут: готовое из окна, иначе по истории в кольце
+func average5m(ring *payloadRing, now time.Time) float64 {
+	samples := ring.since(now.Add(-avgWindow))
+	if len(samples) == 0 {
+		return 0
+	}
+	if last := samples[len(samples)-1]; last.WindowAvg != nil {
+		return last.TotalBitsPerSec5m
+	}
+	var bits, sec float64
+	for _, p := range samples {
+		bits += p.TotalBitsPerSec * p.IntervalSeconds
+		sec += p.IntervalSeconds
+	}
+	if sec <= 0 {
+		return 0
+	}
+	return bits / sec
+}
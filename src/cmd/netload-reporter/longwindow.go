@@ -0,0 +1,104 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+	"github.com/iflixer/network-stater/src/pkg/window"
+)
+
+const (
+	defaultLongWindowStep = time.Minute
+	// корзины пишутся на диск не чаще: неделя минутных корзин — сотни килобайт
+	longWindowSaveEvery = 10 * time.Minute
+)
+
+// длинные окна (LONG_WINDOWS)
+const (
+	longWindowDay  = 24 * time.Hour
+	longWindowWeek = 7 * 24 * time.Hour
+)
+
+// longWindows — средние за сутки и неделю по общим корзинам: корзины хранятся за самое
+// длинное из окон, короткое считается по их хвосту. С STATE_DIR корзины переживают перезапуск
+type longWindows struct {
+	daily, weekly bool
+	buckets       *window.Buckets
+	path          string
+	saved         time.Time
+}
+
+// parseLongWindows разбирает "24h,7d" (или 1d, 1w)
+func parseLongWindows(v string) (daily, weekly bool, err error) {
+	for _, w := range splitList(v) {
+		switch w {
+		case "24h", "1d":
+			daily = true
+		case "7d", "1w", "168h":
+			weekly = true
+		default:
+			return false, false, fmt.Errorf("unknown window %q, want 24h or 7d", w)
+		}
+	}
+	return daily, weekly, nil
+}
+
+func newLongWindows(daily, weekly bool, step time.Duration, stateDir string, now time.Time) (*longWindows, error) {
+	size := longWindowDay
+	if weekly {
+		size = longWindowWeek
+	}
+	l := &longWindows{daily: daily, weekly: weekly, buckets: window.NewBuckets(size, step), saved: now}
+	if stateDir == "" {
+		return l, nil
+	}
+	l.path = filepath.Join(stateDir, "long_windows.json")
+	b, err := os.ReadFile(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var st window.BucketsState
+	if err := json.Unmarshal(b, &st); err != nil {
+		return nil, fmt.Errorf("%s: %w", l.path, err)
+	}
+	if !l.buckets.Restore(st, now) {
+		msg.Warnf(msg.LongWindowsResize, l.path)
+	}
+	return l, nil
+}
+
+func (l *longWindows) add(now time.Time, drx, dtx float64, dt time.Duration) (*reporter.DailyAvg, *reporter.WeeklyAvg) {
+	l.buckets.Add(now, drx, dtx, dt)
+	if now.Sub(l.saved) >= longWindowSaveEvery {
+		l.save(now)
+	}
+	var day *reporter.DailyAvg
+	var week *reporter.WeeklyAvg
+	if rx, tx, cov, ok := l.buckets.Average(now, longWindowDay); ok && l.daily {
+		day = reporter.NewDailyAvg(rx, tx, cov)
+	}
+	if rx, tx, cov, ok := l.buckets.Average(now, longWindowWeek); ok && l.weekly {
+		week = reporter.NewWeeklyAvg(rx, tx, cov)
+	}
+	return day, week
+}
+
+func (l *longWindows) save(now time.Time) {
+	l.saved = now
+	if l.path == "" {
+		return
+	}
+	if err := writeFileAtomic(l.path, l.buckets.State()); err != nil {
+		msg.Errorf(msg.LongWindowsSave, l.path, err)
+	}
+}
@@ -0,0 +1,878 @@
+// netload-reporter.go
+package main
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/kube"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/ports"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+	"github.com/iflixer/network-stater/src/pkg/subnets"
+	"github.com/iflixer/network-stater/src/pkg/window"
+)
+
+func main() {
+	if err := godotenv.Load("../.env"); err != nil {
+		msg.Printf(msg.NoDotEnv)
+	}
+	if err := migrateEnv(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if err := loadConfigFile(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "contract":
+			os.Exit(runContract(os.Args[2:]))
+		case "enroll":
+			os.Exit(runEnroll(os.Args[2:]))
+		case "encryption-key":
+			os.Exit(runEncryptionKey(os.Args[2:]))
+		case "top":
+			os.Exit(runTop(os.Args[2:]))
+		case "alerts":
+			os.Exit(runAlerts(os.Args[2:]))
+		case "plan":
+			os.Exit(runPlan(os.Args[2:]))
+		case "fleet":
+			os.Exit(runFleet(os.Args[2:]))
+		case "grafana":
+			os.Exit(runGrafana(os.Args[2:]))
+		case "server":
+			os.Exit(runServer(os.Args[2:]))
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		case "debug-bundle":
+			os.Exit(runDebugBundle(os.Args[2:]))
+		case "messages":
+			os.Exit(runMessages(os.Args[2:]))
+		case "migrate-config":
+			os.Exit(runMigrateConfig(os.Args[2:]))
+		}
+	}
+
+	logs := captureStderr(debugLogLines)
+	configureMessages()
+	if err := bootstrap(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	cfg, err := loadConfig()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	run(cfg, logs)
+}
+
+func run(cfg *config, logs *logRing) {
+	host := hostname()
+	client := reporter.NewSourceHTTPClient(cfg.connectTimeout, cfg.responseTimeout, cfg.proxy, cfg.source)
+	logProxies(client, cfg.primary, cfg.canary, cfg.share, cfg.events)
+	if src := cfg.source; src.Addr.IsValid() || src.Device != "" || src.ProxyProtocol != "" {
+		msg.Printf(msg.SourceBound, src, cmp.Or(src.ProxyProtocol, "off"))
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// профиль — до всего, что трогает систему: этапы меток запускаются уже при Warm
+	if cfg.readOnly {
+		lockDown(cfg)
+	}
+	stats := newSelfStats(time.Now())
+	sinks := newSinkSet(client, cfg.sharePrivacy, cfg.sinksFile)
+	runner := newCollectorRunner(cfg.collectorTimeout, cfg.collectorTimeouts, stats)
+	// в адаптивном режиме отчёты могут идти чаще, окно истории считаем по самому частому
+	ringStep := cfg.interval
+	if cfg.adaptive != nil {
+		ringStep = cfg.adaptive.minInterval
+	}
+	ring := newPayloadRing(int(cfg.historyWindow/ringStep) + 1)
+	if cfg.apiListen != "" {
+		go func() {
+			if err := serveAPI(ctx, cfg, ring, stats, sinks, logs); err != nil {
+				msg.Errorf(msg.APIError, err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	var monthly *monthlyAccounting
+	if cfg.monthly {
+		var err error
+		if monthly, err = newMonthlyAccounting(cfg.stateDir, cfg.monthlyQuota, cfg.quotaAlertPct); err != nil {
+			msg.Errorf(msg.MonthlyError, err)
+			os.Exit(1)
+		}
+	}
+
+	var burst *burstTracker
+	if cfg.burst != nil {
+		var err error
+		if burst, err = newBurstTracker(cfg.burst, cfg.stateDir); err != nil {
+			msg.Errorf(msg.BurstError, err)
+			os.Exit(1)
+		}
+	}
+
+	if cfg.kube != nil {
+		var err error
+		if cfg.kube.client, err = kube.InCluster(cfg.kube.saDir); err != nil {
+			msg.Errorf(msg.KubeError, err)
+			os.Exit(1)
+		}
+		msg.Printf(msg.KubePublishing, cfg.kube.mode, cfg.kube.node, cfg.kube.interval)
+		go cfg.kube.run(ctx, ring)
+	}
+
+	if d := cfg.deploys; d.annotation != "" {
+		var err error
+		if d.client, err = kube.InCluster(d.saDir); err != nil {
+			msg.Errorf(msg.KubeError, err)
+			os.Exit(1)
+		}
+		msg.Printf(msg.DeployWatching, "node annotation "+d.annotation)
+	}
+	if cfg.deploys.file != "" {
+		msg.Printf(msg.DeployWatching, cfg.deploys.file)
+	}
+	go cfg.deploys.run(ctx)
+
+	if steps := cfg.enrich.Steps; len(steps) > 1 {
+		names := make([]string, len(steps))
+		for i, s := range steps {
+			names[i] = s.Stage.Name()
+		}
+		msg.Printf(msg.EnrichStages, strings.Join(names, " -> "))
+	}
+	// первый отчёт — уже с метками всех этапов
+	cfg.enrich.Warm(ctx)
+
+	var signer *reporter.Signer
+	if cfg.signReports {
+		var err error
+		if signer, err = reporter.LoadSigner(cfg.signingKey); err != nil {
+			msg.Errorf(msg.SigningLoad, err)
+			os.Exit(1)
+		}
+		msg.Printf(msg.SigningKey, signer.KeyID)
+	}
+	var encryptor *reporter.Encryptor
+	if cfg.encryptionKeys != "" {
+		var err error
+		if encryptor, err = reporter.NewEncryptor(cfg.encryptionKeys); err != nil {
+			msg.Errorf(msg.EncryptionLoad, err)
+			os.Exit(1)
+		}
+	}
+
+	// события — своей очередью с теми же повторами: оповещение не ждёт в хвосте за повторами отчётов
+	eventQueue := reporter.NewQueue(cfg.retry, cfg.queueSize, func(j reporter.Job) {
+		msg.Printf(msg.EventDropped, j.Exporter.Name(), j.Value.(reporter.Event).Type)
+	})
+	eventQueue.Signer = signer
+	events := &reporter.EventBus{Client: client, Sink: cfg.events, Host: host, NodeName: cfg.nodeName, Encryptor: encryptor, Correlate: cfg.eventCorrelation, Queue: eventQueue}
+	power, metered, failover := cfg.power, cfg.metered, cfg.failover
+
+	probeCapabilities(cfg)
+	plugins, err := startPlugins(cfg.plugins)
+	if err != nil {
+		msg.Errorf(msg.PluginsError, err)
+		os.Exit(1)
+	}
+	defer closePlugins(plugins)
+	if len(plugins) > 0 {
+		msg.Printf(msg.PluginsLoaded, strings.Join(cfg.plugins, ","))
+	}
+	source := netdevSource(cfg)
+	prevIfs, err := source.Read()
+	if err != nil {
+		msg.Errorf(msg.InitInterfaces, err)
+		os.Exit(1)
+	}
+	// если фильтр не выбрал ни одного интерфейса, явно говорим об этом, а не шлём нули молча
+	checkInterfaces := func(matched []string, all map[string]collector.Counters) bool {
+		ok := len(matched) > 0
+		if stats.interfacesMatched(ok, collector.Names(all)) {
+			if ok {
+				msg.Printf(msg.InterfacesMatched, strings.Join(matched, ","))
+			} else {
+				msg.Warnf(msg.InterfacesNone,
+					describePatterns(cfg.interfaces.Include, "en*"), describePatterns(cfg.interfaces.Exclude, ""),
+					strings.Join(collector.Names(all), ","))
+			}
+		}
+		return ok
+	}
+	// членство в bond/bridge перечитываем на каждом интервале: интерфейсы добавляют и убирают на ходу
+	readTopology := func() collector.Topology {
+		// в профиле readonly sysfs не читаем: разбивка остаётся без master/kind
+		if cfg.readOnly || cfg.includeMembers && !cfg.breakdown {
+			return nil
+		}
+		topo, err := collector.ReadTopology(cfg.paths.sysClassNet)
+		if err != nil {
+			msg.Errorf(msg.TopologyError, err)
+		}
+		return topo
+	}
+	aggregate := func(all map[string]collector.Counters, topo collector.Topology) (collector.Counters, []string, []string) {
+		if cfg.includeMembers {
+			topo = nil
+		}
+		return cfg.interfaces.Aggregate(all, topo)
+	}
+	lastMembers := ""
+	logMembers := func(members []string, topo collector.Topology) {
+		var parts []string
+		for _, m := range members {
+			parts = append(parts, m+"@"+topo[m].Master)
+		}
+		if s := strings.Join(parts, ","); s != lastMembers {
+			if s != "" {
+				msg.Printf(msg.InterfacesMembers, s)
+			}
+			lastMembers = s
+		}
+	}
+	topo := readTopology()
+	_, prevMatched, prevMembers := aggregate(prevIfs, topo)
+	checkInterfaces(prevMatched, prevIfs)
+	logMembers(prevMembers, topo)
+	prevAt := time.Now()
+
+	var famPrev collector.FamilyCounters
+	famPrevAt := prevAt
+	if cfg.ipFamily {
+		if famPrev, err = collector.ReadFamilyCounters(cfg.paths.netstat, cfg.paths.snmp6); err != nil {
+			msg.Errorf(msg.InitFamilyCounters, err)
+			os.Exit(1)
+		}
+	}
+
+	// учёт по группам сетей — только если хватает прав; иначе работаем без него
+	var groups *subnets.Accounting
+	var groupsPrev map[string]collector.Counters
+	groupsPrevAt := prevAt
+	if len(cfg.subnetGroups) > 0 {
+		if groups, err = subnets.Open(cfg.subnetGroups, cfg.paths.cgroup); err != nil {
+			msg.Warnf(msg.SubnetsDisabled, err)
+		} else {
+			defer groups.Close()
+			if groupsPrev, err = groups.Read(); err != nil {
+				msg.Errorf(msg.SubnetsInit, err)
+				os.Exit(1)
+			}
+			msg.Printf(msg.SubnetsGroups, strings.Join(groups.Groups(), ","))
+		}
+	}
+	var portGroups *ports.Accounting
+	var portsPrev map[string]collector.Counters
+	portsPrevAt := prevAt
+	if len(cfg.portGroups) > 0 {
+		if portGroups, err = ports.Open(cfg.portGroups, cfg.paths.cgroup); err != nil {
+			msg.Warnf(msg.PortsDisabled, err)
+		} else {
+			defer portGroups.Close()
+			if portsPrev, err = portGroups.Read(); err != nil {
+				msg.Errorf(msg.PortsInit, err)
+				os.Exit(1)
+			}
+			msg.Printf(msg.PortsGroups, strings.Join(portGroups.Groups(), ","))
+		}
+	}
+	var lldp *collector.LLDP
+	var neighbors *neighborWatch
+	if cfg.lldp || cfg.cdp {
+		if lldp, err = collector.ListenLLDP(cfg.cdp); err != nil {
+			msg.Warnf(msg.LLDPDisabled, err)
+		} else {
+			go lldp.Run(ctx)
+			neighbors = newNeighborWatch()
+			msg.Printf(msg.LLDPListening, cfg.cdp)
+		}
+	}
+	var speeds *linkSpeedWatch
+	if cfg.linkSpeed {
+		speeds = newLinkSpeedWatch()
+	}
+	own := newOwnTraffic(cfg)
+	var reach *collector.Reach
+	var reachPrev map[collector.ReachPort]collector.ReachCounters
+	if len(cfg.reachPorts) > 0 {
+		reach = collector.ListenReach(cfg.reachListen, cfg.reachPorts)
+		reachPrev = reach.Counters()
+		var listening []string
+		for _, p := range reach.Ports() {
+			if c := reachPrev[p]; c.Err != "" {
+				msg.Warnf(msg.ReachUnavailable, p, c.Err)
+			} else {
+				listening = append(listening, p.String())
+			}
+		}
+		if len(listening) > 0 {
+			msg.Printf(msg.ReachListening, strings.Join(listening, ","))
+		}
+		go reach.Run(ctx)
+	}
+	asns := cfg.asns
+	if asns != nil {
+		if err := asns.open(cfg.paths.cgroup, prevAt); err != nil {
+			msg.Warnf(msg.ASNDisabled, err)
+			asns = nil
+		} else {
+			defer asns.Close()
+		}
+	}
+	upstreams := cfg.upstreams
+	if upstreams != nil {
+		if err := upstreams.open(ctx, cfg.paths.cgroup, prevAt); err != nil {
+			msg.Warnf(msg.UpstreamDisabled, err)
+			upstreams = nil
+		} else {
+			defer upstreams.Close()
+		}
+	}
+
+	var bootID string
+	if cfg.clockInfo {
+		if bootID, err = collector.BootID(""); err != nil {
+			msg.Warnf(msg.BootIDUnavailable, err)
+		}
+	}
+	var seq uint64
+
+	avg := window.New(avgWindow, prevAt)
+	var long *longWindows
+	if cfg.daily || cfg.weekly {
+		if long, err = newLongWindows(cfg.daily, cfg.weekly, cfg.longWindowStep, cfg.stateDir, prevAt); err != nil {
+			msg.Errorf(msg.LongWindowsError, err)
+			os.Exit(1)
+		}
+	}
+	if cfg.store != nil {
+		if err := cfg.store.open(); err != nil {
+			msg.Errorf(msg.StoreError, err)
+			os.Exit(1)
+		}
+		go cfg.store.run(ctx)
+	}
+	var chain *hashChain
+	if cfg.hashChain {
+		if chain, err = newHashChain(cfg.stateDir); err != nil {
+			msg.Errorf(msg.ChainError, err)
+			os.Exit(1)
+		}
+	}
+	rxEWMA, txEWMA := window.NewEWMA(cfg.halfLife), window.NewEWMA(cfg.halfLife)
+
+	// отправка идёт в отдельной горутине через ограниченную очередь: повторы не задерживают сбор.
+	// В телеметрию идёт только основной эндпоинт, canary на неё не влияет
+	primary, target, err := newPrimaryExporter(cfg, host, client)
+	if err != nil {
+		msg.Errorf(msg.ExporterError, err)
+		os.Exit(1)
+	}
+	if c, ok := primary.(io.Closer); ok {
+		defer c.Close()
+	}
+	if err := sinks.init(cfg, primary, target); err != nil {
+		msg.Errorf(msg.SinksError, err)
+		os.Exit(1)
+	}
+	if cfg.sinksFile != "" {
+		hup := make(chan os.Signal, 1)
+		signal.Notify(hup, syscall.SIGHUP)
+		go func() {
+			for range hup {
+				if err := sinks.reload(); err != nil {
+					msg.Warnf(msg.SinksReload, err)
+				}
+			}
+		}()
+	}
+
+	queue := reporter.NewQueue(cfg.retry, cfg.queueSize, func(j reporter.Job) {
+		msg.Printf(msg.QueueFull, j.Exporter.Name(), j.Samples)
+		if j.Exporter.Name() == cfg.primary.Name {
+			stats.dropped(j.Samples)
+		}
+		sinks.finished(j.Exporter.(*sinkEntry))
+	})
+	queue.Signer = signer
+	sendCtx, cancelSend := context.WithCancel(context.Background())
+	defer cancelSend()
+	sendDone := make(chan struct{})
+	go func() {
+		queue.Run(sendCtx)
+		close(sendDone)
+	}()
+	eventsDone := make(chan struct{})
+	go func() {
+		eventQueue.Run(sendCtx)
+		close(eventsDone)
+	}()
+	send := func(e *sinkEntry, enc string, v any, samples int) {
+		sinks.queued(e)
+		job := reporter.Job{Exporter: e, Encoding: enc, Value: v, Samples: samples}
+		// share уходит сторонним получателям: ключей нашего сервера у них нет
+		if !e.spec.Share {
+			job.Encryptor = encryptor
+		}
+		job.Done = func(latency time.Duration, err error) {
+			if err != nil {
+				msg.Error(err)
+			}
+			if e.Name() == cfg.primary.Name {
+				stats.reportDone(latency, samples, err)
+			}
+			sinks.finished(e)
+		}
+		queue.Push(job)
+	}
+	// получатели берутся заново на каждый отчёт: замена через API или SINKS_FILE действует сразу
+	deliver := func(pl reporter.Payload) {
+		for _, e := range sinks.targets() {
+			if e.spec.Ratio < 1 && rand.Float64() >= e.spec.Ratio {
+				continue
+			}
+			v := pl
+			if e.spec.Share {
+				v = cfg.sharePrivacy.Apply(pl)
+			}
+			if e.Name() == cfg.primary.Name {
+				msg.Printf(msg.Reporting,
+					pl.RxBytesPerSec, pl.TxBytesPerSec, smoothedSummary(pl), e.target)
+			}
+			send(e, e.spec.Encoding, v, 1)
+		}
+	}
+
+	// на лимитном канале — одна сжатая пачка основному получателю вместо отдельных запросов,
+	// остальные пропускаем
+	deliverBatch := func(pls []reporter.Payload) {
+		e := sinks.get(cfg.primary.Name)
+		if e == nil || len(pls) == 0 {
+			return
+		}
+		msg.Printf(msg.ReportingBatch, len(pls), e.target)
+		send(e, reporter.EncodingJSONGzip, pls, len(pls))
+	}
+
+	skipped := 0
+	admit := func(pl *reporter.Payload, now time.Time) bool {
+		ok := true
+		for _, p := range cfg.policies {
+			// без короткого замыкания: каждая политика видит каждый отчёт
+			if !p.allow(*pl, now) {
+				ok = false
+			}
+		}
+		if !ok && len(pl.Events) == 0 {
+			skipped++
+			return false
+		}
+		pl.SkippedSamples, skipped = skipped, 0
+		for _, p := range cfg.policies {
+			p.sent(*pl, now)
+		}
+		return true
+	}
+
+	// в адаптивном режиме тикер задаёт шаг замеров, а интервал отчётов решает adaptive.due
+	adaptive := cfg.adaptive
+	base, tick := cfg.interval, cfg.interval
+	if adaptive != nil {
+		base, tick = adaptive.maxInterval, adaptive.sample
+		if adaptive.highPct > 0 {
+			msg.Printf(msg.AdaptiveLink,
+				adaptive.sample, adaptive.minInterval, adaptive.maxInterval, adaptive.highPct, adaptive.highBps)
+		} else {
+			msg.Printf(msg.AdaptiveMode,
+				adaptive.sample, adaptive.minInterval, adaptive.maxInterval, adaptive.highBps)
+		}
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	curInterval := base
+
+	for {
+		select {
+		case <-ctx.Done():
+			// отложенное в экономном режиме не теряем; на дошедшие до очереди отчёты — 5 секунд
+			for _, p := range power.flush() {
+				deliver(p)
+			}
+			deliverBatch(metered.flush())
+			if long != nil {
+				long.save(time.Now())
+			}
+			if monthly != nil {
+				monthly.save(time.Now())
+			}
+			flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+			// Close ждёт и таймер окна корреляции, который уже отправляет: иначе он писал бы
+			// в закрытую очередь
+			if err := events.Close(flushCtx); err != nil {
+				msg.Error(err)
+			}
+			cancelFlush()
+			queue.Close()
+			eventQueue.Close()
+			deadline := time.After(5 * time.Second)
+			for _, done := range []chan struct{}{sendDone, eventsDone} {
+				select {
+				case <-done:
+				case <-deadline:
+					cancelSend()
+					<-done
+				}
+			}
+			return
+		case <-ticker.C:
+			now := time.Now()
+			if low := power.lowPower(); low != power.low {
+				power.low = low
+				msg.Printf(msg.PowerLow, low)
+			}
+			if m := metered.metered(); m != metered.active {
+				metered.active = m
+				msg.Printf(msg.MeteredUplink, m)
+			}
+			eff := base
+			if power.low {
+				eff *= time.Duration(power.factor)
+			}
+			if metered.active {
+				eff *= time.Duration(metered.factor)
+			}
+			if eff != curInterval {
+				msg.Printf(msg.IntervalChanged, curInterval, eff)
+				if adaptive == nil {
+					ticker.Reset(eff)
+				}
+				curInterval = eff
+			}
+			var curIfs map[string]collector.Counters
+			if !runner.run(collectJob{name: "netdev", read: func() (func(), error) {
+				ifs, err := source.Read()
+				if err != nil {
+					return nil, fmt.Errorf("readInterfaces: %w", err)
+				}
+				return func() { curIfs = ifs }, nil
+			}}) {
+				continue
+			}
+			// прошлые счётчики суммируем по той же топологии, чтобы смена членства не давала скачка
+			topo := readTopology()
+			cur, matched, members := aggregate(curIfs, topo)
+			if adaptive != nil && !adaptive.due(now, cur, now.Sub(prevAt), eff) {
+				continue
+			}
+			// OWN_TRAFFIC=subtract: доставка самих отчётов вычитается на своих интерфейсах из
+			// скоростей (сумма и разбивка) и средних — они от base. Месячный учёт, burst, алерты и
+			// резервный канал считают трафик линка, как провайдер, — от prevIfs
+			base := prevIfs
+			var ownRx, ownTx float64
+			var ownSubtracted bool
+			if own != nil {
+				base, ownRx, ownTx, ownSubtracted = own.observe(curIfs, prevIfs)
+			}
+			prev, _, _ := aggregate(base, topo)
+			noMatch := !checkInterfaces(matched, curIfs)
+			logMembers(members, topo)
+			// time.Time хранит монотонные показания, Sub использует их;
+			// Round(0) отбрасывает их и даёт разницу по стенным часам
+			sec := now.Sub(prevAt).Seconds()
+			wallSec := now.Round(0).Sub(prevAt.Round(0)).Seconds()
+			if sec <= 0 {
+				continue
+			}
+			// скорости считаем только по монотонному интервалу, скачок часов лишь отмечаем событием
+			if step := wallSec - sec; math.Abs(step) >= cfg.clockThreshold.Seconds() {
+				emit(ctx, events, reporter.Event{
+					Type:    "clock_step",
+					Message: msg.Text(msg.ClockStep, step),
+					Data:    map[string]any{"step_seconds": step},
+				})
+			}
+			seq++
+			drx, dtx := collector.Delta(cur.Rx, prev.Rx), collector.Delta(cur.Tx, prev.Tx)
+			rxBps := drx / sec
+			txBps := dtx / sec
+			lineRx, lineTx := drx, dtx
+			if ownSubtracted {
+				line, _, _ := aggregate(prevIfs, topo)
+				lineRx, lineTx = collector.Delta(cur.Rx, line.Rx), collector.Delta(cur.Tx, line.Tx)
+			}
+
+			pl := reporter.Payload{
+				Host:            host,
+				NodeName:        cfg.nodeName,
+				Labels:          cfg.enrich.Labels(ctx),
+				Timestamp:       now.UTC().Unix(),
+				IntervalSeconds: sec,
+
+				IntervalWallSeconds:      wallSec,
+				IntervalMonotonicSeconds: sec,
+				WindowStart:              prevAt.UnixMilli(),
+				WindowEnd:                now.UnixMilli(),
+				Sequence:                 seq,
+			}
+			pl.SetRates(rxBps, txBps)
+
+			if cfg.clockInfo {
+				ci := &reporter.ClockInfo{TimestampMs: now.UnixMilli(), BootID: bootID}
+				if up, err := collector.Uptime(); err == nil {
+					ci.UptimeSeconds = up.Seconds()
+				}
+				pl.ClockInfo = ci
+			}
+
+			if cfg.useWindow {
+				// 5-минутное среднее (если окно ещё нулевой длины, просто берём текущие bps)
+				rx5m, tx5m, ok := avg.Add(now, drx, dtx)
+				if !ok {
+					rx5m, tx5m = rxBps, txBps
+				}
+				pl.WindowAvg = reporter.NewWindowAvg(rx5m, tx5m)
+			}
+			if cfg.useEWMA {
+				dt := now.Sub(prevAt)
+				pl.EWMAAvg = reporter.NewEWMAAvg(rxEWMA.Update(rxBps, dt), txEWMA.Update(txBps, dt))
+			}
+			if long != nil {
+				pl.DailyAvg, pl.WeeklyAvg = long.add(now, drx, dtx, now.Sub(prevAt))
+			}
+
+			// остальные коллекторы независимы: читаем параллельно, каждый со своим таймаутом
+			var jobs []collectJob
+			if cfg.ipFamily {
+				jobs = append(jobs, collectJob{name: "ip_family", read: func() (func(), error) {
+					fc, err := collector.ReadFamilyCounters(cfg.paths.netstat, cfg.paths.snmp6)
+					if err != nil {
+						return nil, fmt.Errorf("readFamilyCounters: %w", err)
+					}
+					return func() {
+						pl.IPFamilyRates = reporter.NewIPFamilyRates(fc, famPrev, now.Sub(famPrevAt).Seconds())
+						famPrev, famPrevAt = fc, now
+					}, nil
+				}})
+			}
+			if groups != nil {
+				jobs = append(jobs, collectJob{name: "subnet_groups", read: func() (func(), error) {
+					gc, err := groups.Read()
+					if err != nil {
+						return nil, fmt.Errorf("readSubnetGroups: %w", err)
+					}
+					return func() {
+						pl.SubnetGroups = reporter.NewGroupRates(groups.Groups(), gc, groupsPrev, now.Sub(groupsPrevAt).Seconds())
+						groupsPrev, groupsPrevAt = gc, now
+					}, nil
+				}})
+			}
+			if portGroups != nil {
+				jobs = append(jobs, collectJob{name: "port_groups", read: func() (func(), error) {
+					pc, err := portGroups.Read()
+					if err != nil {
+						return nil, fmt.Errorf("readPortGroups: %w", err)
+					}
+					return func() {
+						pl.PortGroups = reporter.NewGroupRates(portGroups.Groups(), pc, portsPrev, now.Sub(portsPrevAt).Seconds())
+						portsPrev, portsPrevAt = pc, now
+					}, nil
+				}})
+			}
+			// в экономном режиме необязательные пробы не запускаются: они будят узел (запросы к
+			// upstream-ам и устройствам свои коллекторы делают по сети) и жгут CPU ради полей,
+			// без которых отчёт обойдётся. Счётчики ASN и upstream-ов не продвинуты — первый
+			// замер после выхода из режима покроет весь пропуск
+			optional := !power.low
+			if asns != nil && optional {
+				jobs = append(jobs, collectJob{name: "asns", read: func() (func(), error) {
+					top, advance, err := asns.observe(now)
+					if err != nil {
+						return nil, fmt.Errorf("readASNs: %w", err)
+					}
+					return func() { pl.TopASNs = top; advance() }, nil
+				}})
+			}
+			if upstreams != nil && optional {
+				jobs = append(jobs, collectJob{name: "upstreams", read: func() (func(), error) {
+					cs, advance, err := upstreams.observe(ctx, now)
+					if err != nil {
+						return nil, fmt.Errorf("readUpstreams: %w", err)
+					}
+					return func() { pl.CacheStats = cs; advance() }, nil
+				}})
+			}
+			if cfg.connStats && optional {
+				jobs = append(jobs, collectJob{name: "conn_stats", read: func() (func(), error) {
+					cs, err := collector.ReadConnStats(cfg.paths.conn)
+					if err != nil {
+						return nil, fmt.Errorf("readConnStats: %w", err)
+					}
+					return func() { pl.ConnStats = cs }, nil
+				}})
+			}
+			if optional {
+				for _, p := range plugins {
+					jobs = append(jobs, p.job(ctx, runner, &pl))
+				}
+			}
+			runner.run(jobs...)
+
+			if monthly != nil {
+				u := monthly.add(ctx, events, now, lineRx, lineTx)
+				pl.MonthlyUsage = &u
+			}
+			if burst != nil {
+				pl.BurstUsage = burst.add(ctx, events, now, lineRx, lineTx, now.Sub(prevAt), lineRx/sec, lineTx/sec)
+			}
+
+			// выкладки — до алертов: истёкшая уже не глушит
+			for _, ev := range cfg.deploys.drain(now) {
+				emit(ctx, events, ev)
+			}
+			pl.Deploys = cfg.deploys.marks()
+
+			if failover != nil {
+				pl.BackupLinks = failover.observe(ctx, events, curIfs, prevIfs, sec, now)
+			}
+			if cfg.alerts != nil {
+				pl.Alerts = cfg.alerts.observe(ctx, events, curIfs, prevIfs, sec, now)
+			}
+
+			pl.Metered = metered.active
+			var seen map[string]collector.Neighbor
+			if lldp != nil {
+				seen = lldp.Neighbors()
+				pl.Neighbors = neighbors.observe(ctx, events, seen)
+			}
+			var speed map[string]int
+			if speeds != nil {
+				speed = collector.ReadSpeeds(cfg.paths.sysClassNet, topo.WithMembers(append(matched, members...), curIfs))
+				pl.LinkSpeedChanged, pl.LinkDownshifted = speeds.observe(ctx, events, speed)
+				if adaptive != nil {
+					adaptive.setLinkSpeed(speed, matched)
+				}
+			}
+			if reach != nil {
+				cur := reach.Counters()
+				pl.Reachability = reporter.NewReachPortStats(reach.Ports(), cur, reachPrev)
+				reachPrev = cur
+			}
+			if cfg.breakdown {
+				names := topo.WithMembers(append(matched, members...), curIfs)
+				pl.Interfaces = reporter.NewInterfaceRates(names, matched, topo, curIfs, base, sec)
+				for i := range pl.Interfaces {
+					ir := &pl.Interfaces[i]
+					if nb, ok := seen[ir.Interface]; ok {
+						ir.LLDPNeighbor = nb.Label()
+						ir.Label = ir.LLDPNeighbor
+					}
+					if l, ok := cfg.ifLabels[ir.Interface]; ok {
+						ir.Label = l
+					}
+					if dw, ok := cfg.deploys.match(ir.Interface); ok {
+						ir.DeployID = dw.ID
+					}
+					ir.SpeedMbps = speed[ir.Interface]
+				}
+			}
+			pl.NoInterfacesMatched = noMatch
+			pl.Telemetry = stats.snapshot(now)
+			if own != nil {
+				pl.OwnRxBytesPerSec, pl.OwnTxBytesPerSec, pl.OwnTrafficSubtracted = ownRx/sec, ownTx/sec, ownSubtracted
+			}
+			pl.Events = events.Drain()
+			ring.push(pl)
+			if cfg.store != nil {
+				cfg.store.write(pl, now)
+			}
+
+			// политики отправки (IDLE_*, REPORT_ON_CHANGE_*) могут придержать отчёт; в ring он попадает в любом случае
+			if admit(&pl, now) {
+				// звено — до пачек экономного и лимитного режимов: порядок цепочки — порядок замеров
+				if chain != nil {
+					chain.link(&pl)
+				}
+				// в экономном режиме копим отчёты и шлём пачкой, когда канал уже занят
+				batch := []reporter.Payload{pl}
+				if power.low {
+					batch = power.hold(pl, now)
+				} else if len(power.pending) > 0 {
+					batch = append(power.flush(), pl)
+				}
+				if metered.active {
+					deliverBatch(metered.hold(batch, now))
+				} else {
+					deliverBatch(metered.flush())
+					for _, p := range batch {
+						deliver(p)
+					}
+				}
+			}
+
+			prevIfs, prevAt = curIfs, now
+			if own != nil {
+				own.advance()
+			}
+		}
+	}
+}
+
+// logProxies пишет, через какой прокси (HTTP_PROXY/HTTPS_PROXY/NO_PROXY или PROXY_URL)
+// уходят запросы на каждый настроенный эндпоинт
+func logProxies(client *http.Client, sinks ...reporter.Sink) {
+	for _, s := range sinks {
+		if s.URL == "" {
+			continue
+		}
+		switch p, err := reporter.ProxyFor(client, s.URL); {
+		case err != nil:
+			msg.Warnf(msg.ProxyError, s.Name, err)
+		case p == "":
+			msg.Printf(msg.ProxyDirect, s.Name, s.URL)
+		default:
+			msg.Printf(msg.ProxyVia, s.Name, s.URL, p)
+		}
+	}
+}
+
+func describePatterns(patterns []string, def string) string {
+	if len(patterns) == 0 {
+		return def
+	}
+	return strings.Join(patterns, ",")
+}
+
+func smoothedSummary(pl reporter.Payload) string {
+	var s string
+	if pl.WindowAvg != nil {
+		s += fmt.Sprintf(" | 5m avg rx=%.1fB/s tx=%.1fB/s", pl.RxBytesPerSec5m, pl.TxBytesPerSec5m)
+	}
+	if pl.EWMAAvg != nil {
+		s += fmt.Sprintf(" | ewma rx=%.1fB/s tx=%.1fB/s", pl.RxBytesPerSecEWMA, pl.TxBytesPerSecEWMA)
+	}
+	return s
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// configureMessages — до bootstrap, чтобы перевод и LOG_ASCII действовали с первой строки журнала
+func configureMessages() {
+	msg.SetASCII(envBool("LOG_ASCII"))
+	if err := msg.Load(os.Getenv("MESSAGES_FILE")); err != nil {
+		msg.Warnf(msg.MessagesLoad, err)
+	}
+}
+
+// runMessages печатает каталог сообщений с английскими текстами — заготовку MESSAGES_FILE
+func runMessages(args []string) int {
+	fs := flag.NewFlagSet("messages", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netload-reporter messages > messages.json")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+	// без HTML-экранирования: шаблоны "a -> b" должны читаться как есть
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(msg.Catalog()); err != nil {
+		fmt.Fprintf(os.Stderr, "messages: %v\n", err)
+		return 1
+	}
+	return 0
+}
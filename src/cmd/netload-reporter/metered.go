@@ -0,0 +1,69 @@
+package main
+
+import (
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// режимы учёта лимитного канала (METERED)
+const (
+	meteredOff  = "off"
+	meteredAuto = "auto"
+	meteredOn   = "on"
+)
+
+const (
+	defaultMeteredInterfaces     = "ww*,ppp*,usb*"
+	defaultMeteredIntervalFactor = 5
+	defaultMeteredBatch          = 10
+	defaultMeteredMaxDelay       = 30 * time.Minute
+)
+
+// meteredPolicy: на лимитном канале (LTE-резерв) шлём редко, сжато и пачками,
+// чтобы сам агент не съедал канал, который измеряет
+type meteredPolicy struct {
+	mode      string
+	patterns  []string
+	routePath string
+	factor    int
+	batchSize int
+	maxDelay  time.Duration
+
+	active    bool
+	pending   []reporter.Payload
+	heldSince time.Time // монотонное время первого отложенного отчёта
+}
+
+func (m *meteredPolicy) metered() bool {
+	switch m.mode {
+	case meteredOn:
+		return true
+	case meteredAuto:
+		iface, err := collector.DefaultRouteIface(m.routePath)
+		return err == nil && collector.MatchAny(m.patterns, iface)
+	}
+	return false
+}
+
+// hold копит отчёты и отдаёт пачку, когда она набралась или слишком долго ждёт
+func (m *meteredPolicy) hold(pls []reporter.Payload, now time.Time) []reporter.Payload {
+	if len(m.pending) == 0 {
+		m.heldSince = now
+	}
+	m.pending = append(m.pending, pls...)
+	if len(m.pending) == 0 {
+		return nil
+	}
+	if len(m.pending) < m.batchSize && now.Sub(m.heldSince) < m.maxDelay {
+		return nil
+	}
+	return m.flush()
+}
+
+func (m *meteredPolicy) flush() []reporter.Payload {
+	out := m.pending
+	m.pending = nil
+	return out
+}
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// configMigration — устаревший ключ конфигурации и его замена. Старый ключ продолжает работать:
+// при запуске он переводится в новый с предупреждением — в окружении, .env, CONFIG_FILE и
+// начальной настройке, — а migrate-config переписывает файлы, так что парк обновляется без
+// одновременной смены конфигурации на всех узлах. Ключи — имена переменных окружения
+type configMigration struct {
+	old, new string
+	// convert переводит значение старого ключа в значение нового; "" — старое значение
+	// означало умолчание, ключ просто убирается. nil — значение то же
+	convert func(string) (string, error)
+}
+
+// configMigrations — переименованные ключи; ключ убирают из таблицы, когда парк переведён
+var configMigrations = []configMigration{
+	// *_BPS — бит/с (parseRate); пороги в байт/с назывались так же и значили в 8 раз больше
+	{old: "ADAPTIVE_HIGH_BPS", new: "ADAPTIVE_HIGH_BYTES_PER_SEC"},
+	{old: "IDLE_SUPPRESS_BPS", new: "IDLE_SUPPRESS_BYTES_PER_SEC"},
+	{old: "POWER_WAKE_BPS", new: "POWER_WAKE_BYTES_PER_SEC"},
+	{old: "BACKUP_ACTIVE_BPS", new: "BACKUP_ACTIVE_BYTES_PER_SEC"},
+	{old: "REPORT_ON_CHANGE_BPS", new: "REPORT_ON_CHANGE_BYTES_PER_SEC"},
+	{old: "FAKE_RX_BPS", new: "FAKE_RX_BYTES_PER_SEC"},
+	{old: "FAKE_TX_BPS", new: "FAKE_TX_BYTES_PER_SEC"},
+}
+
+// configChange — что делается с одним устаревшим ключом
+type configChange struct {
+	old, new           string
+	oldValue, newValue string
+	// новый ключ уже задан иначе: старый отбрасывается, его значение не применяется
+	conflict bool
+}
+
+// drop — старый ключ убирается без замены
+func (c configChange) drop() bool { return c.conflict || c.newValue == "" }
+
+// warn пишет точную замену; from — откуда ключ (config, bootstrap или файл)
+func (c configChange) warn(from string) {
+	switch old := c.old + "=" + c.oldValue; {
+	case c.conflict:
+		msg.Warnf(msg.ConfigConflict, from, old, c.new)
+	case c.newValue == "":
+		msg.Warnf(msg.ConfigDropped, from, old, c.new)
+	default:
+		msg.Warnf(msg.ConfigRenamed, from, old, c.new+"="+c.newValue)
+	}
+}
+
+// planMigration — замены для ключей, которые видит lookup (os.LookupEnv или ключи файла)
+func planMigration(lookup func(string) (string, bool)) ([]configChange, error) {
+	var out []configChange
+	for _, m := range configMigrations {
+		v, ok := lookup(m.old)
+		if !ok {
+			continue
+		}
+		c := configChange{old: m.old, new: m.new, oldValue: v, newValue: v}
+		if m.convert != nil {
+			nv, err := m.convert(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", m.old, err)
+			}
+			c.newValue = nv
+		}
+		// новый ключ уже говорит то же самое — старый лишний
+		if cur, set := lookup(m.new); set && c.newValue != "" {
+			c.conflict = cur != c.newValue
+			if !c.conflict {
+				c.newValue = ""
+			}
+		}
+		out = append(out, c)
+	}
+	return out, nil
+}
+
+// migrateEnv переводит устаревшие ключи окружения (и .env) в новые до чтения конфигурации
+// и подкомандами: дальше код видит только новые имена
+func migrateEnv() error {
+	changes, err := planMigration(os.LookupEnv)
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		c.warn("config")
+		if !c.drop() {
+			os.Setenv(c.new, c.newValue)
+		}
+		os.Unsetenv(c.old)
+	}
+	return nil
+}
+
+// migrateValues — то же для ключей, полученных при начальной настройке
+func migrateValues(vals map[string]string, from string) error {
+	changes, err := planMigration(func(k string) (string, bool) { v, ok := vals[k]; return v, ok })
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		c.warn(from)
+		if !c.drop() {
+			vals[c.new] = c.newValue
+		}
+		delete(vals, c.old)
+	}
+	return nil
+}
+
+var dotenvKeyLine = regexp.MustCompile(`^(\s*(?:export\s+)?)([A-Za-z_][A-Za-z0-9_]*)\s*=`)
+
+// configFormat — как в файле выглядят строки ключей: .env или CONFIG_FILE
+type configFormat struct {
+	parse func([]byte) (map[string]string, error)
+	// key — имя переменной окружения в строке ключа и отступ перед ним
+	key    func(line string) (name, indent string, ok bool)
+	render func(indent, name, value string) string
+}
+
+var (
+	dotenvFormat = configFormat{
+		parse: godotenv.UnmarshalBytes,
+		key: func(line string) (string, string, bool) {
+			m := dotenvKeyLine.FindStringSubmatch(line)
+			if m == nil {
+				return "", "", false
+			}
+			return m[2], m[1], true
+		},
+		render: func(indent, name, value string) string { return indent + name + "=" + dotenvQuote(value) },
+	}
+	confFormat = configFormat{
+		parse: parseConfigFile,
+		key: func(line string) (string, string, bool) {
+			m := confKeyLine.FindStringSubmatch(line)
+			if m == nil {
+				return "", "", false
+			}
+			return envKey(m[2]), m[1], true
+		},
+		render: func(indent, name, value string) string { return indent + confKey(name) + " = " + confQuote(value) },
+	}
+)
+
+// formatOf — CONFIG_FILE узнаётся по расширению .conf, остальное — .env
+func formatOf(path string) configFormat {
+	if strings.HasSuffix(path, ".conf") {
+		return confFormat
+	}
+	return dotenvFormat
+}
+
+// migrateFile переписывает файл: строка устаревшего ключа заменяется строкой нового ключа
+// (на её же месте), убираемая — комментируется с причиной; остальное, включая комментарии
+// и порядок, не меняется
+func migrateFile(data []byte, f configFormat) ([]byte, []configChange, error) {
+	vals, err := f.parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	changes, err := planMigration(func(k string) (string, bool) { v, ok := vals[k]; return v, ok })
+	if err != nil || len(changes) == 0 {
+		return data, nil, err
+	}
+	byOld := make(map[string]configChange, len(changes))
+	for _, c := range changes {
+		byOld[c.old] = c
+	}
+	var out bytes.Buffer
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		name, indent, _ := f.key(line)
+		c, ok := byOld[name]
+		switch {
+		case !ok:
+			out.WriteString(line)
+		case c.conflict:
+			fmt.Fprintf(&out, "# %s  # deprecated, ignored: %s is set", line, c.new)
+		case c.newValue == "":
+			fmt.Fprintf(&out, "# %s  # deprecated, no effect: see %s", line, c.new)
+		default:
+			out.WriteString(f.render(indent, c.new, c.newValue))
+		}
+		out.WriteByte('\n')
+	}
+	return out.Bytes(), changes, sc.Err()
+}
+
+// dotenvToConf — CONFIG_FILE с теми же ключами, что в .env (уже переведёнными): ключи в порядке
+// файла, комментарии и пустые строки переносятся, продолжения многострочных значений — нет
+func dotenvToConf(data []byte) ([]byte, []configChange, error) {
+	migrated, changes, err := migrateFile(data, dotenvFormat)
+	if err != nil {
+		return nil, nil, err
+	}
+	vals, err := godotenv.UnmarshalBytes(migrated)
+	if err != nil {
+		return nil, nil, err
+	}
+	var out bytes.Buffer
+	done := make(map[string]bool)
+	sc := bufio.NewScanner(bytes.NewReader(migrated))
+	sc.Buffer(nil, 1<<20)
+	for sc.Scan() {
+		line := sc.Text()
+		if t := strings.TrimSpace(line); t == "" || strings.HasPrefix(t, "#") {
+			out.WriteString(line + "\n")
+			continue
+		}
+		name, _, ok := dotenvFormat.key(line)
+		if v, set := vals[name]; ok && set && !done[name] {
+			done[name] = true
+			out.WriteString(confFormat.render("", name, v) + "\n")
+		}
+	}
+	return out.Bytes(), changes, sc.Err()
+}
+
+var dotenvPlain = regexp.MustCompile(`^[A-Za-z0-9_./:,=@+-]*$`)
+
+// dotenvQuote — значение, которое godotenv прочитает как есть
+func dotenvQuote(v string) string {
+	switch {
+	case dotenvPlain.MatchString(v):
+		return v
+	case !strings.Contains(v, "'"):
+		return "'" + v + "'"
+	}
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "$", `\$`)
+	return `"` + r.Replace(v) + `"`
+}
+
+// runMigrateConfig — перевод .env, CONFIG_FILE (*.conf) или файла начальной настройки на новые
+// ключи: печатает переписанный файл, с -w пишет его на место; -check только проверяет (код 1 —
+// есть устаревшие ключи), например в CI репозитория конфигураций парка. -conf печатает вместо
+// .env равный ему CONFIG_FILE — для переезда с окружения на файл
+func runMigrateConfig(args []string) int {
+	fs := flag.NewFlagSet("migrate-config", flag.ContinueOnError)
+	write := fs.Bool("w", false, "rewrite the file in place instead of printing it")
+	check := fs.Bool("check", false, "only report deprecated keys, exit 1 if there are any")
+	conf := fs.Bool("conf", false, "print the .env file as a CONFIG_FILE")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netload-reporter migrate-config [-w | -check | -conf] file.env|file.conf")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 || *write && *check || *conf && (*write || *check || strings.HasSuffix(fs.Arg(0), ".conf")) {
+		fs.Usage()
+		return 2
+	}
+	path := fs.Arg(0)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate-config: %v\n", err)
+		return 1
+	}
+	var out []byte
+	var changes []configChange
+	if *conf {
+		out, changes, err = dotenvToConf(data)
+	} else {
+		out, changes, err = migrateFile(data, formatOf(path))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "migrate-config: %s: %v\n", path, err)
+		return 1
+	}
+	for _, c := range changes {
+		c.warn(path)
+	}
+	switch {
+	case *conf:
+		os.Stdout.Write(out)
+	case *check:
+		if len(changes) > 0 {
+			return 1
+		}
+	case *write:
+		if len(changes) == 0 {
+			return 0
+		}
+		// права исходного файла: в .env бывают ключи API
+		st, err := os.Stat(path)
+		if err == nil {
+			tmp := path + ".tmp"
+			if err = os.WriteFile(tmp, out, st.Mode().Perm()); err == nil {
+				err = os.Rename(tmp, path)
+			}
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "migrate-config: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(os.Stderr, "migrate-config: %s: %d keys migrated\n", path, len(changes))
+	default:
+		os.Stdout.Write(out)
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const defaultQuotaAlertPct = 80
+
+// monthlySaveEvery — как часто счётчики месяца пишутся в STATE_DIR, если за это время не было
+// смены месяца или алерта; при падении теряется не больше этого, при остановке пишется сразу
+const monthlySaveEvery = 5 * time.Minute
+
+type monthlyState struct {
+	Month        string `json:"month"`
+	RxBytes      uint64 `json:"rx_bytes"`
+	TxBytes      uint64 `json:"tx_bytes"`
+	QuotaAlerted bool   `json:"quota_alerted"`
+}
+
+type monthlyAccounting struct {
+	path     string // пусто — без сохранения между перезапусками
+	quota    uint64 // 0 — без квоты; считается по rx+tx
+	alertPct float64
+	state    monthlyState
+	saved    time.Time
+}
+
+func newMonthlyAccounting(stateDir string, quota uint64, alertPct float64) (*monthlyAccounting, error) {
+	m := &monthlyAccounting{quota: quota, alertPct: alertPct}
+	if stateDir == "" {
+		return m, nil
+	}
+	m.path = filepath.Join(stateDir, "monthly.json")
+	b, err := os.ReadFile(m.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, &m.state); err != nil {
+		return nil, fmt.Errorf("%s: %w", m.path, err)
+	}
+	return m, nil
+}
+
+func monthKey(t time.Time) string { return t.UTC().Format("2006-01") }
+
+func (m *monthlyAccounting) add(ctx context.Context, bus *reporter.EventBus, now time.Time, drx, dtx float64) reporter.MonthlyUsage {
+	// смену месяца и алерт пишем сразу, чтобы после перезапуска не начать месяц заново и не повторить событие
+	changed := false
+	if key := monthKey(now); m.state.Month != key {
+		m.state = monthlyState{Month: key}
+		changed = true
+	}
+	m.state.RxBytes += uint64(drx)
+	m.state.TxBytes += uint64(dtx)
+
+	// проекция на конец месяца по средней скорости с его начала
+	utc := now.UTC()
+	start := time.Date(utc.Year(), utc.Month(), 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+	scale := end.Sub(start).Seconds() / math.Max(utc.Sub(start).Seconds(), 1)
+
+	u := reporter.MonthlyUsage{
+		Month:            m.state.Month,
+		MonthRxBytes:     m.state.RxBytes,
+		MonthTxBytes:     m.state.TxBytes,
+		MonthProjectedRx: uint64(float64(m.state.RxBytes) * scale),
+		MonthProjectedTx: uint64(float64(m.state.TxBytes) * scale),
+	}
+	if m.quota > 0 {
+		u.QuotaUsedPct = float64(m.state.RxBytes+m.state.TxBytes) / float64(m.quota) * 100
+		u.QuotaExceeded = u.QuotaUsedPct >= m.alertPct
+		if u.QuotaExceeded && !m.state.QuotaAlerted {
+			m.state.QuotaAlerted, changed = true, true
+			emit(ctx, bus, reporter.Event{
+				Type:    "quota_exceeded",
+				Message: msg.Text(msg.MonthlyQuota, m.state.Month, u.QuotaUsedPct, m.quota, m.alertPct),
+				Data: map[string]any{"month": m.state.Month, "month_rx_bytes": m.state.RxBytes, "month_tx_bytes": m.state.TxBytes,
+					"quota_bytes": m.quota, "quota_used_pct": u.QuotaUsedPct, "alert_pct": m.alertPct},
+			})
+		}
+	}
+	if changed || now.Sub(m.saved) >= monthlySaveEvery {
+		m.save(now)
+	}
+	return u
+}
+
+// save пишет состояние; ошибка — в лог, следующая попытка через monthlySaveEvery
+func (m *monthlyAccounting) save(now time.Time) {
+	if m.path == "" {
+		return
+	}
+	m.saved = now
+	if err := writeFileAtomic(m.path, m.state); err != nil {
+		msg.Errorf(msg.MonthlySave, m.path, err)
+	}
+}
+
+// writeFileAtomic пишет JSON через временный файл, чтобы не оставить обрывок при падении
+func writeFileAtomic(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
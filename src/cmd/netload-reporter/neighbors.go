@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// neighborWatch помнит последнего известного соседа каждого интерфейса и сообщает
+// о смене коммутатора или порта — так случайная перекоммутация видна сразу.
+// Сосед, пропавший по TTL, остаётся известным: если кабель воткнут в другой порт
+// не сразу, событие смены всё равно будет
+type neighborWatch struct {
+	known   map[string]collector.Neighbor
+	present map[string]bool
+}
+
+func newNeighborWatch() *neighborWatch {
+	return &neighborWatch{known: make(map[string]collector.Neighbor), present: make(map[string]bool)}
+}
+
+// observe сравнивает текущих соседей с известными и возвращает их для отчёта
+func (w *neighborWatch) observe(ctx context.Context, bus *reporter.EventBus, cur map[string]collector.Neighbor) []reporter.LinkNeighbor {
+	out := make([]reporter.LinkNeighbor, 0, len(cur))
+	for iface, nb := range cur {
+		out = append(out, reporter.NewLinkNeighbor(iface, nb))
+		old, ok := w.known[iface]
+		w.known[iface], w.present[iface] = nb, true
+		switch {
+		case !ok:
+			msg.Printf(msg.LLDPConnected, iface, nb.Label(), nb.Protocol)
+		case old.ChassisID != nb.ChassisID || old.PortID != nb.PortID:
+			emit(ctx, bus, reporter.Event{
+				Type:      "neighbor_changed",
+				Interface: iface,
+				Message:   msg.Text(msg.NeighborChange, iface, old.Label(), nb.Label()),
+				Data: map[string]any{
+					"previous": reporter.NewLinkNeighbor(iface, old),
+					"current":  reporter.NewLinkNeighbor(iface, nb),
+				},
+			})
+		}
+	}
+	for iface, nb := range w.known {
+		if _, ok := cur[iface]; !ok && w.present[iface] {
+			w.present[iface] = false
+			emit(ctx, bus, reporter.Event{
+				Type:      "neighbor_lost",
+				Interface: iface,
+				Message:   msg.Text(msg.NeighborLost, iface, nb.Label()),
+				Data:      map[string]any{"previous": reporter.NewLinkNeighbor(iface, nb)},
+			})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Interface < out[j].Interface })
+	return out
+}
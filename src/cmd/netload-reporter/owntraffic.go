@@ -0,0 +1,109 @@
+package main
+
+import (
+	"maps"
+	"net"
+	"net/netip"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// OWN_TRAFFIC: report — собственный трафик агента отдельными полями телеметрии,
+// subtract — ещё и вычесть его из измеренных скоростей
+const (
+	ownTrafficReport   = "report"
+	ownTrafficSubtract = "subtract"
+)
+
+// ownTraffic — трафик агента за интервал отчёта. Отчёт за интервал уходит уже после замера,
+// поэтому в интервал попадает доставка предыдущего — как и на счётчиках интерфейсов
+type ownTraffic struct {
+	counter  *reporter.Traffic
+	subtract bool
+	// SOURCE_INTERFACE: весь трафик агента идёт через него
+	device string
+
+	base, cur map[netip.Addr]collector.Counters
+}
+
+func newOwnTraffic(cfg *config) *ownTraffic {
+	if cfg.source.Traffic == nil {
+		return nil
+	}
+	o := &ownTraffic{counter: cfg.source.Traffic, subtract: cfg.ownTraffic == ownTrafficSubtract, device: cfg.source.Device}
+	o.base = o.counter.ByAddr()
+	return o
+}
+
+// observe — байты агента с начала интервала. С subtract они относятся к интерфейсам по
+// локальному адресу соединений и вычитаются сдвигом прошлых счётчиков этих интерфейсов:
+// возвращённые prev дают за интервал трафик без агента для скоростей и средних — суммы и
+// разбивки. Сами prevIfs не меняются: по ним считается трафик линка для учётов и алертов
+func (o *ownTraffic) observe(curIfs, prevIfs map[string]collector.Counters) (prev map[string]collector.Counters, rx, tx float64, subtracted bool) {
+	o.cur = o.counter.ByAddr()
+	byIface := make(map[string]collector.Counters)
+	var names map[netip.Addr]string
+	if o.subtract && o.device == "" {
+		names = interfaceAddrs()
+	}
+	for a, c := range o.cur {
+		d := collector.Counters{Rx: c.Rx - o.base[a].Rx, Tx: c.Tx - o.base[a].Tx}
+		rx, tx = rx+float64(d.Rx), tx+float64(d.Tx)
+		name := o.device
+		if name == "" {
+			name = names[a]
+		}
+		if name != "" {
+			s := byIface[name]
+			byIface[name] = collector.Counters{Rx: s.Rx + d.Rx, Tx: s.Tx + d.Tx}
+		}
+	}
+	if !o.subtract {
+		return prevIfs, rx, tx, false
+	}
+	prev = maps.Clone(prevIfs)
+	for name, d := range byIface {
+		c, okc := curIfs[name]
+		p, okp := prev[name]
+		if !okc || !okp || d == (collector.Counters{}) {
+			continue
+		}
+		// сброс счётчика (c < p) Delta и так считает нулём; больше измеренного не вычитаем
+		if c.Rx >= p.Rx {
+			p.Rx = min(p.Rx+d.Rx, c.Rx)
+		}
+		if c.Tx >= p.Tx {
+			p.Tx = min(p.Tx+d.Tx, c.Tx)
+		}
+		prev[name] = p
+		subtracted = true
+	}
+	return prev, rx, tx, subtracted
+}
+
+// advance закрывает интервал: следующий считается от показаний последнего observe
+func (o *ownTraffic) advance() {
+	o.base = o.cur
+}
+
+// interfaceAddrs — какому интерфейсу принадлежит локальный адрес
+func interfaceAddrs() map[netip.Addr]string {
+	out := make(map[netip.Addr]string)
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return out
+	}
+	for _, ifc := range ifs {
+		addrs, err := ifc.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, a := range addrs {
+			if p, err := netip.ParsePrefix(a.String()); err == nil {
+				out[p.Addr().Unmap()] = ifc.Name
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,292 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const defaultPlanThreshold = 0.9
+
+// planResult — итог прогона истории через одну ёмкость канала
+type planResult struct {
+	CapacityBitsPerSec float64 `json:"capacity_bits_per_sec"`
+	SaturatedSeconds   float64 `json:"saturated_seconds"`
+	SaturatedPct       float64 `json:"saturated_pct"`
+	P95Utilization     float64 `json:"p95_utilization"`
+	PeakUtilization    float64 `json:"peak_utilization"`
+	LostBytes          float64 `json:"lost_bytes"`
+	MaxQueueDelayMs    float64 `json:"max_queue_delay_ms"`
+}
+
+// linkSim — одно направление канала ёмкостью capacity байт/с с очередью на buffer байт:
+// что не помещается ни в канал, ни в очередь, теряется (при buffer=0 — полисер)
+type linkSim struct {
+	capacity, buffer float64
+	queue            float64
+	lost, maxDelay   float64
+}
+
+// step пропускает demand байт/с за dt секунд и возвращает загрузку канала
+func (l *linkSim) step(demand, dt float64) float64 {
+	in := demand*dt + l.queue
+	served := math.Min(in, l.capacity*dt)
+	rest := in - served
+	l.queue = math.Min(rest, l.buffer)
+	l.lost += rest - l.queue
+	l.maxDelay = math.Max(l.maxDelay, l.queue/l.capacity)
+	return served / (l.capacity * dt)
+}
+
+// runPlan — планирование ёмкости по уже собранной истории: прогоняет выгруженные отчёты
+// (ответ /v1/history, пачки или JSON-строки отчётов, можно .gz) через каналы другой ёмкости
+// и с другой очередью шейпера и печатает, сколько часов канал был бы насыщен
+func runPlan(args []string) int {
+	fs := flag.NewFlagSet("plan", flag.ContinueOnError)
+	caps := fs.String("capacity", "", "link capacities to simulate, bits/s: 1G,2.5G,500M")
+	buffer := fs.Duration("buffer", 0, "shaper queue depth as time at link rate (0 — police, drop excess)")
+	threshold := fs.Float64("threshold", defaultPlanThreshold, "utilization at which an interval counts as saturated")
+	growth := fs.Float64("growth", 0, "scale historical demand by this many percent")
+	direction := fs.String("direction", "both", "rx, tx, both (full duplex, each direction separately) or total (shared medium)")
+	asJSON := fs.Bool("json", false, "print results as JSON")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: netload-reporter plan -capacity 1G,2G [flags] history.json...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *caps == "" || fs.NArg() == 0 || *threshold <= 0 || *threshold > 1 {
+		fs.Usage()
+		return 2
+	}
+	if d := *direction; d != "rx" && d != "tx" && d != "both" && d != "total" {
+		fmt.Fprintf(os.Stderr, "plan: unknown direction %q\n", d)
+		return 2
+	}
+	var capacities []float64
+	for _, c := range splitList(*caps) {
+		bps, err := parseRate(c)
+		if err != nil || bps <= 0 {
+			fmt.Fprintf(os.Stderr, "plan: capacity %q: invalid rate\n", c)
+			return 2
+		}
+		capacities = append(capacities, bps)
+	}
+
+	var samples []reporter.Payload
+	for _, path := range fs.Args() {
+		s, err := readSamples(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "plan: %s: %v\n", path, err)
+			return 1
+		}
+		samples = append(samples, s...)
+	}
+	samples = dedupSamples(samples)
+	if len(samples) == 0 {
+		fmt.Fprintln(os.Stderr, "plan: no samples")
+		return 1
+	}
+
+	scale := 1 + *growth/100
+	results := make([]planResult, 0, len(capacities))
+	var span float64
+	for _, bps := range capacities {
+		r, total := simulatePlan(samples, bps, *buffer, *threshold, scale, *direction)
+		results = append(results, r)
+		span = total
+	}
+
+	if *asJSON {
+		out := map[string]any{"samples": len(samples), "span_seconds": span, "results": results}
+		b, _ := json.MarshalIndent(out, "", "  ")
+		fmt.Println(string(b))
+		return 0
+	}
+	first, last := time.Unix(samples[0].Timestamp, 0).UTC(), time.Unix(samples[len(samples)-1].Timestamp, 0).UTC()
+	var peakRx, peakTx float64
+	for _, s := range samples {
+		peakRx, peakTx = math.Max(peakRx, s.RxBytesPerSec*scale), math.Max(peakTx, s.TxBytesPerSec*scale)
+	}
+	fmt.Printf("%d samples, %s .. %s (%s), peak rx %s tx %s", len(samples),
+		first.Format(time.DateTime), last.Format(time.DateTime), (time.Duration(span) * time.Second).Round(time.Second),
+		formatBits(peakRx), formatBits(peakTx))
+	if *growth != 0 {
+		fmt.Printf(", demand %+.0f%%", *growth)
+	}
+	fmt.Printf("\nsaturated: utilization >= %.0f%%, direction %s, buffer %s\n\n", *threshold*100, *direction, *buffer)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	io.WriteString(tw, "CAPACITY\tSATURATED\t%TIME\tP95 UTIL\tPEAK UTIL\tLOST\tMAX DELAY\n")
+	for _, r := range results {
+		delay := "-"
+		if *buffer > 0 {
+			delay = fmt.Sprintf("%.0fms", r.MaxQueueDelayMs)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%.1f%%\t%.1f%%\t%.1f%%\t%s\t%s\n", formatBits(r.CapacityBitsPerSec/8),
+			(time.Duration(r.SaturatedSeconds) * time.Second).Round(time.Minute), r.SaturatedPct,
+			r.P95Utilization*100, r.PeakUtilization*100, formatSize(r.LostBytes), delay)
+	}
+	tw.Flush()
+	return 0
+}
+
+// simulatePlan прогоняет samples через канал bitsPerSec; второй результат — покрытое время, с
+func simulatePlan(samples []reporter.Payload, bitsPerSec float64, buffer time.Duration, threshold, scale float64, direction string) (planResult, float64) {
+	c := bitsPerSec / 8
+	newLink := func() *linkSim { return &linkSim{capacity: c, buffer: c * buffer.Seconds()} }
+	rx, tx := newLink(), newLink()
+	r := planResult{CapacityBitsPerSec: bitsPerSec}
+	var total float64
+	utils := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		dt := sampleSeconds(s)
+		if dt <= 0 {
+			continue
+		}
+		var u float64
+		switch direction {
+		case "rx":
+			u = rx.step(s.RxBytesPerSec*scale, dt)
+		case "tx":
+			u = tx.step(s.TxBytesPerSec*scale, dt)
+		case "total":
+			u = rx.step((s.RxBytesPerSec+s.TxBytesPerSec)*scale, dt)
+		default:
+			u = math.Max(rx.step(s.RxBytesPerSec*scale, dt), tx.step(s.TxBytesPerSec*scale, dt))
+		}
+		total += dt
+		if u >= threshold {
+			r.SaturatedSeconds += dt
+		}
+		r.PeakUtilization = math.Max(r.PeakUtilization, u)
+		utils = append(utils, u)
+	}
+	if total > 0 {
+		r.SaturatedPct = r.SaturatedSeconds / total * 100
+	}
+	if len(utils) > 0 {
+		sort.Float64s(utils)
+		r.P95Utilization = utils[min(int(float64(len(utils))*0.95), len(utils)-1)]
+	}
+	r.LostBytes = rx.lost + tx.lost
+	r.MaxQueueDelayMs = math.Max(rx.maxDelay, tx.maxDelay) * 1000
+	return r, total
+}
+
+// sampleSeconds — длительность отчёта: по окну, если оно есть, иначе интервал
+func sampleSeconds(s reporter.Payload) float64 {
+	if s.WindowEnd > s.WindowStart && s.WindowStart > 0 {
+		return float64(s.WindowEnd-s.WindowStart) / 1000
+	}
+	return s.IntervalSeconds
+}
+
+// readSamples читает отчёты из файла: массивы, отдельные объекты или их поток, gzip по сигнатуре
+func readSamples(path string) ([]reporter.Payload, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return decodeSamples(f)
+}
+
+// decodeSamples — readSamples для потока: файл или ответ API
+func decodeSamples(src io.Reader) ([]reporter.Payload, error) {
+	br := bufio.NewReader(src)
+	var r io.Reader = br
+	if magic, _ := br.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	dec := json.NewDecoder(r)
+	var out []reporter.Payload
+	for {
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); errors.Is(err, io.EOF) {
+			return out, nil
+		} else if err != nil {
+			return nil, err
+		}
+		if len(raw) > 0 && raw[0] == '[' {
+			var batch []reporter.Payload
+			if err := json.Unmarshal(raw, &batch); err != nil {
+				return nil, err
+			}
+			out = append(out, batch...)
+			continue
+		}
+		var pl reporter.Payload
+		if err := json.Unmarshal(raw, &pl); err != nil {
+			return nil, err
+		}
+		out = append(out, pl)
+	}
+}
+
+// dedupSamples сортирует по времени и убирает повторы (пересекающиеся выгрузки истории)
+func dedupSamples(samples []reporter.Payload) []reporter.Payload {
+	sort.SliceStable(samples, func(i, j int) bool { return samples[i].Timestamp < samples[j].Timestamp })
+	out := samples[:0]
+	for i, s := range samples {
+		if i > 0 && s.Timestamp == samples[i-1].Timestamp && s.Host == samples[i-1].Host {
+			continue
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+// parseRate разбирает скорость в битах/с: "1G", "2.5Gbit", "500mbps", "100000"
+func parseRate(s string) (float64, error) {
+	s = strings.TrimSpace(strings.ToUpper(s))
+	for _, suffix := range []string{"BIT/S", "BPS", "BIT"} {
+		s = strings.TrimSuffix(s, suffix)
+	}
+	mult := 1.0
+	for _, u := range []struct {
+		suffix string
+		mult   float64
+	}{{"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"K", 1e3}} {
+		if strings.HasSuffix(s, u.suffix) {
+			s, mult = strings.TrimSuffix(s, u.suffix), u.mult
+			break
+		}
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil || f < 0 {
+		return 0, fmt.Errorf("invalid rate %q", s)
+	}
+	return f * mult, nil
+}
+
+// formatSize: байты → "12.3 GB"
+func formatSize(b float64) string {
+	for _, u := range []struct {
+		div  float64
+		name string
+	}{{1e12, "TB"}, {1e9, "GB"}, {1e6, "MB"}, {1e3, "KB"}} {
+		if b >= u.div {
+			return fmt.Sprintf("%.1f %s", b/u.div, u.name)
+		}
+	}
+	return fmt.Sprintf("%.0f B", b)
+}
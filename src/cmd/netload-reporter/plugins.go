@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+	"github.com/iflixer/network-stater/src/pkg/sdk"
+)
+
+// имена встроенных коллекторов: COLLECTOR_TIMEOUTS у них со своими общий
+var builtinCollectors = []string{"netdev", "ip_family", "subnet_groups", "port_groups", "asns", "upstreams", "conn_stats"}
+
+// pluginNames разбирает PLUGINS: пусто — все вкомпилированные коллекторы pkg/sdk, none — ни одного
+func pluginNames(v string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return sdk.Registered(), nil
+	}
+	if strings.TrimSpace(v) == "none" {
+		return nil, nil
+	}
+	names := splitList(v)
+	for _, name := range names {
+		if !slices.Contains(sdk.Registered(), name) {
+			return nil, fmt.Errorf("collector %q is not compiled in (have: %s)", name, strings.Join(sdk.Registered(), ","))
+		}
+	}
+	return names, nil
+}
+
+// plugin — созданный свой коллектор
+type plugin struct {
+	name string
+	c    sdk.Collector
+}
+
+// startPlugins создаёт коллекторы с настройками PLUGIN_<NAME>_*
+func startPlugins(names []string) ([]plugin, error) {
+	var out []plugin
+	for _, name := range names {
+		if slices.Contains(builtinCollectors, name) {
+			return nil, fmt.Errorf("collector %q clashes with a built-in collector", name)
+		}
+		c, err := sdk.New(name, sdk.EnvConfig(name))
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, plugin{name: name, c: c})
+	}
+	return out, nil
+}
+
+func closePlugins(plugins []plugin) {
+	for _, p := range plugins {
+		if c, ok := p.c.(io.Closer); ok {
+			c.Close()
+		}
+	}
+}
+
+// job — чтение коллектора в collectorRunner; значения, не прошедшие sdk.Validate, в отчёт не идут
+func (p plugin) job(ctx context.Context, runner *collectorRunner, pl *reporter.Payload) collectJob {
+	return collectJob{name: p.name, read: func() (func(), error) {
+		cctx, cancel := context.WithTimeout(ctx, runner.timeoutOf(p.name))
+		defer cancel()
+		samples, err := p.c.Collect(cctx)
+		if err == nil {
+			err = sdk.Validate(samples)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("plugin %s: %w", p.name, err)
+		}
+		return func() {
+			for _, s := range samples {
+				pl.Plugins = append(pl.Plugins, reporter.PluginSample{Collector: p.name, Sample: s})
+			}
+		}, nil
+	}}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// sendPolicy решает, нужно ли отправлять очередной отчёт. Отчёт уходит, только если
+// все политики согласны; отчёты с событиями уходят всегда
+type sendPolicy interface {
+	allow(pl reporter.Payload, now time.Time) bool
+	// sent вызывается, когда отчёт всё-таки отправлен
+	sent(pl reporter.Payload, now time.Time)
+}
+
+const (
+	defaultIdleAfter     = 3
+	defaultIdleHeartbeat = 15 * time.Minute
+)
+
+// idlePolicy глушит отчёты простаивающего узла: после after подряд отчётов ниже
+// floorBps (байт/с, rx+tx) отправляется только heartbeat раз в heartbeat
+type idlePolicy struct {
+	floorBps  float64
+	after     int
+	heartbeat time.Duration
+
+	idle     int
+	lastSent time.Time
+}
+
+func (p *idlePolicy) allow(pl reporter.Payload, now time.Time) bool {
+	if pl.TotalBytesPerSec >= p.floorBps {
+		if p.idle > p.after {
+			msg.Printf(msg.IdleResumed)
+		}
+		p.idle = 0
+		return true
+	}
+	p.idle++
+	if p.idle == p.after+1 {
+		msg.Printf(msg.IdleHeartbeat, p.floorBps, p.after, p.heartbeat)
+	}
+	return p.idle <= p.after || now.Sub(p.lastSent) >= p.heartbeat
+}
+
+func (p *idlePolicy) sent(_ reporter.Payload, now time.Time) {
+	p.lastSent = now
+}
+
+const defaultMaxSilence = 10 * time.Minute
+
+// changePolicy отправляет отчёт, только если rx или tx изменились относительно последнего
+// отправленного больше чем на pct процентов или на bps байт/с (что задано), но не реже maxSilence
+type changePolicy struct {
+	pct        float64
+	bps        float64
+	maxSilence time.Duration
+
+	lastRx, lastTx float64
+	lastSent       time.Time
+}
+
+func (p *changePolicy) allow(pl reporter.Payload, now time.Time) bool {
+	if p.lastSent.IsZero() || now.Sub(p.lastSent) >= p.maxSilence {
+		return true
+	}
+	return p.changed(pl.RxBytesPerSec, p.lastRx) || p.changed(pl.TxBytesPerSec, p.lastTx)
+}
+
+func (p *changePolicy) changed(cur, last float64) bool {
+	d := math.Abs(cur - last)
+	if p.bps > 0 && d >= p.bps {
+		return true
+	}
+	return p.pct > 0 && d > 0 && d >= last*p.pct/100
+}
+
+func (p *changePolicy) sent(pl reporter.Payload, now time.Time) {
+	p.lastRx, p.lastTx, p.lastSent = pl.RxBytesPerSec, pl.TxBytesPerSec, now
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// режимы энергосбережения (POWER_MODE)
+const (
+	powerOff  = "off"
+	powerAuto = "auto"
+	powerOn   = "on"
+)
+
+const (
+	defaultPowerIntervalFactor = 4
+	defaultPowerMaxDelay       = 15 * time.Minute
+	// ниже этой скорости считаем, что радиомодуль спит и будить его ради отчёта не стоит
+	defaultPowerWakeBytesPerSec = 2048
+)
+
+// powerPolicy решает, когда работать в экономном режиме и когда
+// отправлять накопленные отчёты: пачкой, вместе с уже идущим трафиком
+type powerPolicy struct {
+	mode          string
+	supplyPath    string
+	factor        int
+	maxDelay      time.Duration
+	wakeBytesPerS float64
+
+	low     bool
+	pending []reporter.Payload
+	// по монотонным часам: скачок системного времени не должен ни держать, ни сбрасывать пачку
+	heldSince time.Time
+}
+
+func (p *powerPolicy) lowPower() bool {
+	switch p.mode {
+	case powerOn:
+		return true
+	case powerAuto:
+		return collector.OnBattery(p.supplyPath)
+	}
+	return false
+}
+
+// hold откладывает отчёт и возвращает пачку, если пора её отправить
+func (p *powerPolicy) hold(pl reporter.Payload, now time.Time) []reporter.Payload {
+	if len(p.pending) == 0 {
+		p.heldSince = now
+	}
+	p.pending = append(p.pending, pl)
+	radioAwake := pl.TotalBytesPerSec >= p.wakeBytesPerS
+	if !radioAwake && now.Sub(p.heldSince) < p.maxDelay {
+		return nil
+	}
+	return p.flush()
+}
+
+func (p *powerPolicy) flush() []reporter.Payload {
+	out := p.pending
+	p.pending = nil
+	return out
+}
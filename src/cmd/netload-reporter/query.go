@@ -0,0 +1,199 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	neturl "net/url"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const (
+	defaultQueryLast  = time.Hour
+	defaultQueryWidth = 40
+)
+
+// queryHost — строка таблицы query: последний отчёт, среднее и пик за окно, история rx+tx
+type queryHost struct {
+	host     string
+	last     reporter.Payload
+	avg      float64
+	peak     float64
+	hist     []float64
+	reports  int
+	ifaces   map[string][]float64
+	ifaceCur map[string]reporter.InterfaceRates
+}
+
+// runQuery — картина по парку в терминале без браузера: берёт отчёты за окно у сервера приёма
+// (GET /v1/history?window=&host= — та же форма, что у API агента, поэтому подойдёт и сам агент)
+// и печатает по узлу текущие скорости, среднее, пик и спарклайн; с -host — ещё и по интерфейсам
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	server := fs.String("server", os.Getenv("QUERY_URL"), "server base URL (default — scheme and host of REPORT_URL)")
+	host := fs.String("host", "", "only this host (empty — all hosts)")
+	last := fs.Duration("last", defaultQueryLast, "window to show")
+	width := fs.Int("width", defaultQueryWidth, "sparkline width")
+	asJSON := fs.Bool("json", false, "print the reports as JSON instead of tables")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	base := *server
+	if base == "" {
+		if u, err := neturl.Parse(os.Getenv("REPORT_URL")); err == nil && u.Host != "" {
+			base = u.Scheme + "://" + u.Host
+		}
+	}
+	if base == "" || *last <= 0 || *width <= 0 || fs.NArg() > 0 {
+		fmt.Fprintln(os.Stderr, "usage: netload-reporter query [-server URL] [-host name] [-last 1h] [-json]")
+		fs.PrintDefaults()
+		return 2
+	}
+
+	samples, err := fetchHistory(base, *host, *last)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "query: %v\n", err)
+		return 1
+	}
+	// сервер мог не знать host (или это API самого агента): фильтр всё равно на нашей стороне
+	if *host != "" {
+		samples = slices.DeleteFunc(samples, func(s reporter.Payload) bool { return s.Host != *host })
+	}
+	if len(samples) == 0 {
+		if *host != "" {
+			fmt.Fprintf(os.Stderr, "query: no reports from %s in the last %s\n", *host, *last)
+		} else {
+			fmt.Fprintf(os.Stderr, "query: no reports in the last %s\n", *last)
+		}
+		return 1
+	}
+	if *asJSON {
+		b, _ := json.MarshalIndent(samples, "", "  ")
+		fmt.Println(string(b))
+		return 0
+	}
+	hosts := summarizeQuery(samples)
+	printQuery(os.Stdout, hosts, *last, *width, *host != "", time.Now())
+	return 0
+}
+
+// fetchHistory — отчёты за окно; API_KEY — bearer-токен, как у отправки
+func fetchHistory(base, host string, last time.Duration) ([]reporter.Payload, error) {
+	q := neturl.Values{"window": {last.String()}}
+	if host != "" {
+		q.Set("host", host)
+	}
+	url := strings.TrimSuffix(base, "/") + "/v1/history?" + q.Encode()
+	proxy, err := envProxy()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if key := os.Getenv("API_KEY"); key != "" {
+		req.Header.Set("Authorization", "Bearer "+key)
+	}
+	resp, err := reporter.NewHTTPClient(defaultConnectTimeout, defaultResponseTimeout, proxy).Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("GET %s: %s %s", url, resp.Status, strings.TrimSpace(string(b)))
+	}
+	samples, err := decodeSamples(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("GET %s: %w", url, err)
+	}
+	return dedupSamples(samples), nil
+}
+
+// summarizeQuery раскладывает отчёты (по времени) по узлам; самые нагруженные за окно — первыми
+func summarizeQuery(samples []reporter.Payload) []*queryHost {
+	byHost := make(map[string]*queryHost)
+	secs := make(map[string]float64)
+	var out []*queryHost
+	for _, s := range samples {
+		h := byHost[s.Host]
+		if h == nil {
+			h = &queryHost{host: s.Host, ifaces: make(map[string][]float64), ifaceCur: make(map[string]reporter.InterfaceRates)}
+			byHost[s.Host] = h
+			out = append(out, h)
+		}
+		sec := sampleSeconds(s)
+		h.avg += s.TotalBytesPerSec * sec
+		secs[s.Host] += sec
+		h.peak = max(h.peak, s.TotalBytesPerSec)
+		h.hist = append(h.hist, s.TotalBytesPerSec)
+		h.reports++
+		h.last = s
+		for _, ir := range s.Interfaces {
+			h.ifaces[ir.Interface] = append(h.ifaces[ir.Interface], ir.RxBytesPerSec+ir.TxBytesPerSec)
+			h.ifaceCur[ir.Interface] = ir
+		}
+	}
+	for _, h := range out {
+		if secs[h.host] > 0 {
+			h.avg /= secs[h.host]
+		}
+	}
+	slices.SortFunc(out, func(a, b *queryHost) int { return cmp.Or(cmp.Compare(b.avg, a.avg), cmp.Compare(a.host, b.host)) })
+	return out
+}
+
+func printQuery(w io.Writer, hosts []*queryHost, last time.Duration, width int, detail bool, now time.Time) {
+	fmt.Fprintf(w, "last %s: %d hosts\n\n", last, len(hosts))
+	tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+	io.WriteString(tw, "HOST\tLAST REPORT\tRX\tTX\tAVG\tPEAK\tREPORTS\tRX+TX\n")
+	for _, h := range hosts {
+		ago := now.Sub(time.Unix(h.last.Timestamp, 0)).Round(time.Second)
+		fmt.Fprintf(tw, "%s\t%s ago\t%s\t%s\t%s\t%s\t%d\t%s\n", h.host, ago,
+			formatBits(h.last.RxBytesPerSec), formatBits(h.last.TxBytesPerSec), formatBits(h.avg), formatBits(h.peak),
+			h.reports, sparkline(h.hist, width))
+	}
+	tw.Flush()
+	if !detail {
+		return
+	}
+	// по интерфейсам — только для одного узла: у парка их слишком много
+	for _, h := range hosts {
+		if len(h.ifaces) == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s interfaces:\n", h.host)
+		tw := tabwriter.NewWriter(w, 0, 0, 3, ' ', 0)
+		io.WriteString(tw, "INTERFACE\tRX\tTX\tSPEED\tRX+TX\n")
+		names := make([]string, 0, len(h.ifaces))
+		for name := range h.ifaces {
+			names = append(names, name)
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			ir := h.ifaceCur[name]
+			label, speed := name, "-"
+			if ir.Label != "" {
+				label += " (" + ir.Label + ")"
+			}
+			if ir.SpeedMbps > 0 {
+				speed = formatBits(float64(ir.SpeedMbps) * 1e6 / 8)
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", label, formatBits(ir.RxBytesPerSec), formatBits(ir.TxBytesPerSec), speed, sparkline(h.ifaces[name], width))
+		}
+		tw.Flush()
+	}
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/smtp"
+	neturl "net/url"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const (
+	defaultServerListen = ":9106"
+	defaultFleetAt      = "00:05"
+)
+
+// fleetMail — сводка письмом (FLEET_MAIL_TO через SMTP_ADDR); to пуст — без почты
+type fleetMail struct {
+	addr, from     string
+	user, password string
+	to             []string
+}
+
+func (m fleetMail) send(d fleetDigest) error {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\nTo: %s\r\nSubject: netload fleet digest %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n",
+		m.from, strings.Join(m.to, ", "), d.Day)
+	var text bytes.Buffer
+	printFleetDigest(&text, d)
+	b.Write(bytes.ReplaceAll(text.Bytes(), []byte("\n"), []byte("\r\n")))
+	var auth smtp.Auth
+	if m.user != "" {
+		host, _, _ := net.SplitHostPort(m.addr)
+		auth = smtp.PlainAuth("", m.user, m.password, host)
+	}
+	return smtp.SendMail(m.addr, auth, m.from, m.to, b.Bytes())
+}
+
+// fleetServer — серверный режим: раз в сутки строит сводку по парку из истории отчётов,
+// держит последнюю для GET /v1/fleet/digest, рассылает её и следит, чтобы в Grafana был дашборд
+type fleetServer struct {
+	// история: API сервера приёма (или агента) GET /v1/history либо выгрузки отчётов,
+	// перечитываемые при каждом запуске
+	history string
+	files   []string
+	top     int
+	silent  time.Duration
+	webhook string
+	mail    fleetMail
+	// STATE_DIR/fleet-digest.json: после перезапуска сводка отдаётся сразу; пусто — без сохранения
+	path string
+
+	// GRAFANA_URL: дашборд загружается при старте, неудача повторяется со следующей сводкой
+	grafanaURL, grafanaToken, grafanaFolder string
+	grafanaLabels                           []string
+	grafanaDone                             bool
+
+	mu   sync.RWMutex
+	last *fleetDigest
+}
+
+// runServer — серверный режим: суточная сводка по парку по расписанию (-at, UTC) с API и
+// рассылкой, плюс provisioning дашборда Grafana. Отчёты принимает и хранит свой сервер приёма,
+// этот режим только читает его историю
+func runServer(args []string) int {
+	flags := flag.NewFlagSet("server", flag.ContinueOnError)
+	listen := flags.String("listen", envString("SERVER_LISTEN", defaultServerListen), "API address: GET /v1/fleet/digest")
+	history := flags.String("history", os.Getenv("FLEET_HISTORY_URL"), "history API base URL, GET /v1/history (default — QUERY_URL or scheme and host of REPORT_URL)")
+	at := flags.String("at", envString("FLEET_AT", defaultFleetAt), "UTC time of day to build the digest of the previous day, HH:MM")
+	top := flags.Int("top", envInt("FLEET_TOP", defaultFleetTop, 1), "hosts in each list")
+	silent := flags.Duration("silent", envDuration("FLEET_SILENT", defaultFleetSilent), "host is silent when its last report is older than this at the end of the day")
+	webhook := flags.String("webhook", os.Getenv("FLEET_WEBHOOK_URL"), "POST each digest as JSON here (API_KEY as bearer token)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: netload-reporter server [flags] [reports.json...]")
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	runAt, err := time.Parse("15:04", *at)
+	s := &fleetServer{history: *history, files: flags.Args(), top: *top, silent: *silent, webhook: *webhook}
+	if s.history == "" && len(s.files) == 0 {
+		s.history = os.Getenv("QUERY_URL")
+		if u, err := neturl.Parse(os.Getenv("REPORT_URL")); s.history == "" && err == nil && u.Host != "" {
+			s.history = u.Scheme + "://" + u.Host
+		}
+	}
+	if err != nil || s.history == "" && len(s.files) == 0 || *top <= 0 || *silent <= 0 {
+		flags.Usage()
+		return 2
+	}
+	s.mail = fleetMail{addr: os.Getenv("SMTP_ADDR"), from: envString("SMTP_FROM", "netload-reporter@"+hostname()),
+		user: os.Getenv("SMTP_USER"), password: os.Getenv("SMTP_PASSWORD"), to: splitList(os.Getenv("FLEET_MAIL_TO"))}
+	if len(s.mail.to) > 0 && s.mail.addr == "" {
+		fmt.Fprintln(os.Stderr, "server: FLEET_MAIL_TO needs SMTP_ADDR")
+		return 2
+	}
+	if s.grafanaURL = os.Getenv("GRAFANA_URL"); s.grafanaURL != "" {
+		s.grafanaToken, s.grafanaFolder = os.Getenv("GRAFANA_TOKEN"), os.Getenv("GRAFANA_FOLDER")
+		if s.grafanaLabels, err = grafanaLabels(os.Getenv("LABELS")); err != nil {
+			fmt.Fprintf(os.Stderr, "server: LABELS: %v\n", err)
+			return 2
+		}
+	}
+	if dir := os.Getenv("STATE_DIR"); dir != "" {
+		s.path = filepath.Join(dir, "fleet-digest.json")
+		if err := s.load(); err != nil {
+			fmt.Fprintf(os.Stderr, "server: %v\n", err)
+			return 1
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	ln, err := net.Listen("tcp", *listen)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "server: %v\n", err)
+		return 1
+	}
+	srv := &http.Server{Handler: s.handler(), ReadHeaderTimeout: 5 * time.Second, WriteTimeout: time.Minute, IdleTimeout: time.Minute}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+	go s.schedule(ctx, runAt)
+	msg.Printf(msg.ServerListening, ln.Addr())
+	if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		msg.Errorf(msg.ServerError, err)
+		return 1
+	}
+	return 0
+}
+
+func (s *fleetServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/fleet/digest", func(w http.ResponseWriter, r *http.Request) {
+		s.mu.RLock()
+		d := s.last
+		s.mu.RUnlock()
+		if d == nil {
+			http.Error(w, "no digest yet", http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, d)
+	})
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, map[string]string{"status": "ok"})
+	})
+	return mux
+}
+
+// nextFleetRun — ближайшее после now время at (часы и минуты, UTC)
+func nextFleetRun(now, at time.Time) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), at.Hour(), at.Minute(), 0, 0, time.UTC)
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// schedule строит сводку в at каждые сутки; при старте — сразу, если вчерашней ещё нет
+func (s *fleetServer) schedule(ctx context.Context, at time.Time) {
+	s.provision(ctx)
+	now := time.Now()
+	if s.lastDay() != fleetDay(now).Format(time.DateOnly) {
+		s.run(ctx, now)
+	}
+	for {
+		next := nextFleetRun(time.Now(), at)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(time.Until(next)):
+		}
+		s.provision(ctx)
+		s.run(ctx, time.Now())
+	}
+}
+
+// fleetDay — сутки сводки: вчерашние по UTC
+func fleetDay(now time.Time) time.Time {
+	now = now.UTC()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+}
+
+func (s *fleetServer) lastDay() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.last == nil {
+		return ""
+	}
+	return s.last.Day
+}
+
+// run строит сводку за вчера и рассылает её; ошибка рассылки не отменяет сводку в API
+func (s *fleetServer) run(ctx context.Context, now time.Time) {
+	day := fleetDay(now)
+	samples, err := s.samples(day, now)
+	if err != nil {
+		msg.Errorf(msg.FleetFailed, day.Format(time.DateOnly), err)
+		return
+	}
+	d := buildFleetDigest(samples, day, s.top, s.silent, now)
+	s.mu.Lock()
+	s.last = &d
+	s.mu.Unlock()
+	msg.Printf(msg.FleetBuilt, d.Day, d.Hosts, len(d.Silent))
+	if s.path != "" {
+		if err := writeFileAtomic(s.path, d); err != nil {
+			msg.Errorf(msg.FleetDeliver, d.Day, s.path, err)
+		}
+	}
+	if s.webhook != "" {
+		if err := postFleetDigest(ctx, s.webhook, d); err != nil {
+			msg.Errorf(msg.FleetDeliver, d.Day, "webhook", err)
+		}
+	}
+	if len(s.mail.to) > 0 {
+		if err := s.mail.send(d); err != nil {
+			msg.Errorf(msg.FleetDeliver, d.Day, "mail", err)
+		}
+	}
+}
+
+// samples — отчёты за сутки day и предыдущие (для изменений)
+func (s *fleetServer) samples(day, now time.Time) ([]reporter.Payload, error) {
+	if s.history != "" {
+		return fetchHistory(s.history, "", now.Sub(day.AddDate(0, 0, -1)).Round(time.Minute)+time.Minute)
+	}
+	var out []reporter.Payload
+	for _, path := range s.files {
+		samples, err := readSamples(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		out = append(out, samples...)
+	}
+	return out, nil
+}
+
+// provision загружает дашборд Grafana, пока это не удастся
+func (s *fleetServer) provision(ctx context.Context) {
+	if s.grafanaURL == "" || s.grafanaDone {
+		return
+	}
+	pctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+	if err := provisionGrafana(pctx, s.grafanaURL, s.grafanaToken, s.grafanaFolder, grafanaDashboard(defaultGrafanaUID, s.grafanaLabels, grafanaPanels)); err != nil {
+		msg.Warnf(msg.GrafanaFailed, err)
+		return
+	}
+	s.grafanaDone = true
+	msg.Printf(msg.GrafanaLoaded, defaultGrafanaUID, s.grafanaURL)
+}
+
+func (s *fleetServer) load() error {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var d fleetDigest
+	if err := json.Unmarshal(b, &d); err != nil {
+		return fmt.Errorf("%s: %w", s.path, err)
+	}
+	s.last = &d
+	return nil
+}
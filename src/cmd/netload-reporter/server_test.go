@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextFleetRun(t *testing.T) {
+	at, _ := time.Parse("15:04", "00:05")
+	tests := []struct {
+		now, want string
+	}{
+		{"2026-10-14T00:00:00Z", "2026-10-14T00:05:00Z"},
+		{"2026-10-14T00:05:00Z", "2026-10-15T00:05:00Z"},
+		{"2026-10-14T12:00:00Z", "2026-10-15T00:05:00Z"},
+		{"2026-10-14T02:00:00+03:00", "2026-10-14T00:05:00Z"},
+	}
+	for _, tt := range tests {
+		now, _ := time.Parse(time.RFC3339, tt.now)
+		if got := nextFleetRun(now, at).Format(time.RFC3339); got != tt.want {
+			t.Errorf("nextFleetRun(%s) = %s, want %s", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestFleetDay(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2026-10-14T01:00:00+03:00")
+	if got := fleetDay(now).Format(time.DateOnly); got != "2026-10-12" {
+		t.Errorf("fleetDay = %s, want 2026-10-12", got)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+)
+
+// COLLECTOR: откуда берутся счётчики интерфейсов
+const (
+	collectorKernel = "kernel" // ядро, см. NETDEV_SOURCE
+	collectorFake   = "fake"
+	collectorReplay = "replay"
+)
+
+const (
+	defaultFakeInterfaces    = "eno1"
+	defaultFakeRxBps         = 1_250_000 // 10 Мбит/с
+	defaultFakeTxBps         = 5_000_000 // 40 Мбит/с
+	defaultFakeDiurnalPct    = 50
+	defaultFakePeakHour      = 20
+	defaultFakeNoisePct      = 10
+	defaultFakeBurstEvery    = 30 * time.Minute
+	defaultFakeBurstDuration = 2 * time.Minute
+	defaultFakeBurstFactor   = 5
+)
+
+// loadSimulated собирает генератор или проигрыватель для дашбордов, демо и
+// интеграционных тестов; nil — читаем ядро
+func loadSimulated() (collector.Source, error) {
+	switch mode := envString("COLLECTOR", collectorKernel); mode {
+	case collectorKernel:
+		return nil, nil
+	case collectorFake:
+		return &collector.Fake{
+			Interfaces:    splitList(envString("FAKE_INTERFACES", defaultFakeInterfaces)),
+			RxBps:         envFloat("FAKE_RX_BYTES_PER_SEC", defaultFakeRxBps, 0, -1),
+			TxBps:         envFloat("FAKE_TX_BYTES_PER_SEC", defaultFakeTxBps, 0, -1),
+			DiurnalPct:    envFloat("FAKE_DIURNAL_PCT", defaultFakeDiurnalPct, 0, 100),
+			PeakHour:      envFloat("FAKE_PEAK_HOUR", defaultFakePeakHour, 0, 24),
+			NoisePct:      envFloat("FAKE_NOISE_PCT", defaultFakeNoisePct, 0, 100),
+			BurstEvery:    envDuration("FAKE_BURST_EVERY", defaultFakeBurstEvery),
+			BurstDuration: envDuration("FAKE_BURST_DURATION", defaultFakeBurstDuration),
+			BurstFactor:   envFloat("FAKE_BURST_FACTOR", defaultFakeBurstFactor, 1, -1),
+			Seed:          uint64(envInt("FAKE_SEED", 0, 0)),
+		}, nil
+	case collectorReplay:
+		dir := os.Getenv("REPLAY_DIR")
+		if dir == "" {
+			return nil, fmt.Errorf("COLLECTOR=replay: REPLAY_DIR is required")
+		}
+		return &collector.Replay{Dir: dir, Loop: envBool("REPLAY_LOOP")}, nil
+	default:
+		return nil, fmt.Errorf("COLLECTOR: unknown collector %q", mode)
+	}
+}
@@ -0,0 +1,354 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"sync"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// откуда получатель: из окружения, SINKS_FILE или API
+const (
+	sinkFromEnv  = "env"
+	sinkFromFile = "file"
+	sinkFromAPI  = "api"
+)
+
+var sinkName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// sinkSpec — получатель отчётов в SINKS_FILE и API
+type sinkSpec struct {
+	Name     string  `json:"name"`
+	URL      string  `json:"url"`
+	APIKey   string  `json:"api_key,omitempty"`
+	Encoding string  `json:"encoding,omitempty"`
+	Ratio    float64 `json:"ratio"` // доля отчётов (0, 1], по умолчанию 1
+	Share    bool    `json:"share,omitempty"`
+}
+
+// sinkStatus — получатель в ответе GET /v1/sinks; ключ не показывается, секреты в URL вырезаны
+type sinkStatus struct {
+	Name     string  `json:"name"`
+	Target   string  `json:"target"`
+	Encoding string  `json:"encoding"`
+	Ratio    float64 `json:"ratio"`
+	Share    bool    `json:"share,omitempty"`
+	Source   string  `json:"source"`
+	State    string  `json:"state"` // active, draining
+	Pending  int     `json:"pending"`
+}
+
+// sinkEntry — получатель в работе. Сам он и есть экспортёр задания в очереди: задание
+// доходит туда, куда было поставлено, даже если получатель уже заменён или удалён
+type sinkEntry struct {
+	spec     sinkSpec
+	exporter reporter.Exporter
+	target   string
+	source   string
+	// NATS/Kafka (EXPORTER): меняется только перезапуском
+	fixed   bool
+	pending int
+}
+
+func (e *sinkEntry) Name() string { return e.spec.Name }
+
+func (e *sinkEntry) Export(ctx context.Context, body reporter.Body) error {
+	return e.exporter.Export(ctx, body)
+}
+
+// sinkSet — получатели отчётов, которые можно менять без перезапуска: через API или
+// SINKS_FILE с SIGHUP. Заменённый или удалённый получатель дошлёт уже поставленные
+// ему отчёты (с повторами) и только потом пропадёт из списка
+type sinkSet struct {
+	client  *http.Client
+	privacy reporter.Privacy
+	file    string
+
+	mu        sync.Mutex
+	active    map[string]*sinkEntry
+	draining  []*sinkEntry
+	fromFile  map[string]bool
+	shareable bool
+}
+
+func newSinkSet(client *http.Client, privacy reporter.Privacy, file string) *sinkSet {
+	return &sinkSet{
+		client:    client,
+		privacy:   privacy,
+		file:      file,
+		active:    make(map[string]*sinkEntry),
+		fromFile:  make(map[string]bool),
+		shareable: privacy.Epsilon > 0 || privacy.Round > 0,
+	}
+}
+
+// init — получатели из окружения, потом SINKS_FILE поверх них
+func (s *sinkSet) init(cfg *config, primary reporter.Exporter, target string) error {
+	s.mu.Lock()
+	if primary != nil {
+		e := &sinkEntry{spec: sinkSpec{Name: cfg.primary.Name, URL: cfg.primary.URL, APIKey: cfg.primary.APIKey, Encoding: cfg.primary.Encoding, Ratio: 1},
+			exporter: primary, target: target, source: sinkFromEnv, fixed: cfg.exporter != exporterHTTP}
+		s.active[e.spec.Name] = e
+	}
+	if cfg.canary.URL != "" && cfg.canaryRatio > 0 {
+		s.active[cfg.canary.Name] = s.entry(sinkSpec{Name: cfg.canary.Name, URL: cfg.canary.URL, APIKey: cfg.canary.APIKey, Encoding: cfg.canary.Encoding, Ratio: cfg.canaryRatio}, sinkFromEnv)
+	}
+	if cfg.share.URL != "" {
+		s.active[cfg.share.Name] = s.entry(sinkSpec{Name: cfg.share.Name, URL: cfg.share.URL, APIKey: cfg.share.APIKey, Encoding: cfg.share.Encoding, Ratio: 1, Share: true}, sinkFromEnv)
+	}
+	s.mu.Unlock()
+	if s.file == "" {
+		return nil
+	}
+	return s.reload()
+}
+
+func (s *sinkSet) entry(spec sinkSpec, source string) *sinkEntry {
+	sink := reporter.Sink{Name: spec.Name, URL: spec.URL, APIKey: spec.APIKey, Encoding: spec.Encoding}
+	return &sinkEntry{spec: spec, exporter: reporter.HTTPExporter{Sink: sink, Client: s.client}, target: spec.URL, source: source}
+}
+
+func (s *sinkSet) validate(spec *sinkSpec) error {
+	if !sinkName.MatchString(spec.Name) {
+		return fmt.Errorf("invalid sink name %q", spec.Name)
+	}
+	if u, err := url.Parse(spec.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%s: want http(s) URL", spec.Name)
+	}
+	spec.Encoding = cmp.Or(spec.Encoding, reporter.EncodingJSON)
+	if !reporter.ValidEncoding(spec.Encoding) {
+		return fmt.Errorf("%s: unknown encoding %q", spec.Name, spec.Encoding)
+	}
+	if spec.Ratio <= 0 || spec.Ratio > 1 {
+		return fmt.Errorf("%s: ratio must be in (0, 1]", spec.Name)
+	}
+	if spec.Share && !s.shareable {
+		return fmt.Errorf("%s: share needs SHARE_NOISE_EPSILON or SHARE_ROUND", spec.Name)
+	}
+	return nil
+}
+
+// put добавляет или заменяет получателя
+func (s *sinkSet) put(spec sinkSpec, source string) error {
+	if err := s.validate(&spec); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	old := s.active[spec.Name]
+	if old != nil && old.fixed {
+		return fmt.Errorf("%s: set by EXPORTER, change it with a restart", spec.Name)
+	}
+	if old != nil && old.spec == spec {
+		old.source = source
+		return nil
+	}
+	s.retire(old)
+	s.active[spec.Name] = s.entry(spec, source)
+	if old == nil {
+		msg.Printf(msg.SinkAdded, spec.Name, redactURLs(spec.URL), source)
+	} else {
+		msg.Printf(msg.SinkChanged, spec.Name, redactURLs(spec.URL), source)
+	}
+	logProxies(s.client, reporter.Sink{Name: spec.Name, URL: spec.URL})
+	return nil
+}
+
+// remove убирает получателя; false — такого нет или его нельзя убрать
+func (s *sinkSet) remove(name, source string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.active[name]
+	if e == nil {
+		return false, nil
+	}
+	if e.fixed {
+		return false, fmt.Errorf("%s: set by EXPORTER, change it with a restart", name)
+	}
+	delete(s.active, name)
+	s.retire(e)
+	msg.Printf(msg.SinkRemoved, name, source, e.pending)
+	return true, nil
+}
+
+// retire — под mu: получатель с недосланными отчётами досылает их
+func (s *sinkSet) retire(e *sinkEntry) {
+	if e != nil && e.pending > 0 {
+		s.draining = append(s.draining, e)
+	}
+}
+
+// targets — активные получатели по имени
+func (s *sinkSet) targets() []*sinkEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]*sinkEntry, 0, len(s.active))
+	for _, e := range s.active {
+		out = append(out, e)
+	}
+	slices.SortFunc(out, func(a, b *sinkEntry) int { return cmp.Compare(a.spec.Name, b.spec.Name) })
+	return out
+}
+
+func (s *sinkSet) get(name string) *sinkEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.active[name]
+}
+
+func (s *sinkSet) queued(e *sinkEntry) {
+	s.mu.Lock()
+	e.pending++
+	s.mu.Unlock()
+}
+
+// finished — задание получателя отправлено или выброшено
+func (s *sinkSet) finished(e *sinkEntry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e.pending--
+	if e.pending > 0 {
+		return
+	}
+	if i := slices.Index(s.draining, e); i >= 0 {
+		s.draining = slices.Delete(s.draining, i, i+1)
+		msg.Printf(msg.SinkDrained, e.spec.Name, redactURLs(e.target))
+	}
+}
+
+func (s *sinkSet) list() []sinkStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := []sinkStatus{}
+	add := func(e *sinkEntry, state string) {
+		out = append(out, sinkStatus{Name: e.spec.Name, Target: redactURLs(e.target), Encoding: e.spec.Encoding,
+			Ratio: e.spec.Ratio, Share: e.spec.Share, Source: e.source, State: state, Pending: e.pending})
+	}
+	for _, e := range s.active {
+		add(e, "active")
+	}
+	for _, e := range s.draining {
+		add(e, "draining")
+	}
+	slices.SortFunc(out, func(a, b sinkStatus) int { return cmp.Or(cmp.Compare(a.Name, b.Name), cmp.Compare(a.State, b.State)) })
+	return out
+}
+
+// reload перечитывает SINKS_FILE: получатели из него добавляются или заменяются, запись
+// с пустым url убирает получателя, пропавшие из файла убираются. Ошибка в файле не меняет ничего
+func (s *sinkSet) reload() error {
+	b, err := os.ReadFile(s.file)
+	if err != nil {
+		return err
+	}
+	var raw []json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return fmt.Errorf("%s: %w", s.file, err)
+	}
+	specs := make([]sinkSpec, 0, len(raw))
+	names := map[string]bool{}
+	for _, r := range raw {
+		spec := sinkSpec{Ratio: 1}
+		if err := json.Unmarshal(r, &spec); err != nil {
+			return fmt.Errorf("%s: %w", s.file, err)
+		}
+		if names[spec.Name] {
+			return fmt.Errorf("%s: duplicate sink %q", s.file, spec.Name)
+		}
+		names[spec.Name] = true
+		if spec.URL != "" {
+			if err := s.validate(&spec); err != nil {
+				return fmt.Errorf("%s: %w", s.file, err)
+			}
+		}
+		specs = append(specs, spec)
+	}
+
+	s.mu.Lock()
+	gone := []string{}
+	for name := range s.fromFile {
+		if !names[name] {
+			gone = append(gone, name)
+		}
+	}
+	s.mu.Unlock()
+	var errs []error
+	for _, name := range gone {
+		if _, err := s.remove(name, sinkFromFile); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	fromFile := map[string]bool{}
+	for _, spec := range specs {
+		if spec.URL == "" {
+			if _, err := s.remove(spec.Name, sinkFromFile); err != nil {
+				errs = append(errs, err)
+			}
+			continue
+		}
+		if err := s.put(spec, sinkFromFile); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		fromFile[spec.Name] = true
+	}
+	s.mu.Lock()
+	s.fromFile = fromFile
+	s.mu.Unlock()
+	return errors.Join(errs...)
+}
+
+// registerSinkAPI — получатели отчётов без перезапуска: GET /v1/sinks,
+// PUT /v1/sinks/{name}, DELETE /v1/sinks/{name}; всё только для привилегированных
+func registerSinkAPI(mux *http.ServeMux, sinks *sinkSet, writeToken string) {
+	mux.HandleFunc("GET /v1/sinks", func(w http.ResponseWriter, r *http.Request) {
+		if privileged(w, r, writeToken) {
+			writeJSON(w, sinks.list())
+		}
+	})
+	mux.HandleFunc("PUT /v1/sinks/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if !privileged(w, r, writeToken) {
+			return
+		}
+		spec := sinkSpec{Ratio: 1}
+		if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(&spec); err != nil {
+			http.Error(w, "want JSON with url", http.StatusBadRequest)
+			return
+		}
+		spec.Name = r.PathValue("name")
+		if err := sinks.put(spec, sinkFromAPI); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		for _, st := range sinks.list() {
+			if st.Name == spec.Name && st.State == "active" {
+				writeJSON(w, st)
+			}
+		}
+	})
+	mux.HandleFunc("DELETE /v1/sinks/{name}", func(w http.ResponseWriter, r *http.Request) {
+		if !privileged(w, r, writeToken) {
+			return
+		}
+		ok, err := sinks.remove(r.PathValue("name"), sinkFromAPI)
+		switch {
+		case err != nil:
+			http.Error(w, err.Error(), http.StatusConflict)
+		case !ok:
+			http.Error(w, "no such sink", http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusNoContent)
+		}
+	})
+}
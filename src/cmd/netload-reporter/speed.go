@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"sort"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// linkSpeedWatch помнит скорость линка каждого интерфейса (LINK_SPEED) и сообщает о её смене:
+// 10G, тихо пересогласованный в 1G после замены SFP или кабеля, — частая скрытая потеря ёмкости.
+// Линк без скорости (упал) прежнюю не стирает: поднявшись на меньшей, он всё равно даст событие
+type linkSpeedWatch struct {
+	known map[string]int
+	// наибольшая виденная с запуска: ниже неё интерфейс считается понизившим скорость
+	best map[string]int
+}
+
+func newLinkSpeedWatch() *linkSpeedWatch {
+	return &linkSpeedWatch{known: make(map[string]int), best: make(map[string]int)}
+}
+
+// observe сравнивает скорости с известными; changed — была смена, downshifted — какой-то
+// интерфейс сейчас медленнее, чем бывал
+func (w *linkSpeedWatch) observe(ctx context.Context, bus *reporter.EventBus, cur map[string]int) (changed, downshifted bool) {
+	names := make([]string, 0, len(cur))
+	for iface := range cur {
+		names = append(names, iface)
+	}
+	sort.Strings(names)
+	for _, iface := range names {
+		speed := cur[iface]
+		old, ok := w.known[iface]
+		w.known[iface] = speed
+		w.best[iface] = max(w.best[iface], speed)
+		if ok && old != speed {
+			changed = true
+			emit(ctx, bus, reporter.Event{
+				Type:      "link_speed_changed",
+				Interface: iface,
+				Message:   msg.Text(msg.LinkSpeedChanged, iface, old, speed),
+				Data:      map[string]any{"previous_mbps": old, "current_mbps": speed, "downshift": speed < old},
+			})
+		}
+		if speed < w.best[iface] {
+			downshifted = true
+		}
+	}
+	return changed, downshifted
+}
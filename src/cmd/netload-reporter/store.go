@@ -0,0 +1,351 @@
+package main
+
+import (
+	"cmp"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// виды данных локального хранилища
+const (
+	storeMetrics = "metrics"
+	storeEvents  = "events"
+	storeFlows   = "flows"
+)
+
+var storeKinds = []string{storeMetrics, storeEvents, storeFlows}
+
+const (
+	// корзина — час данных одного вида в одном файле: срок хранения и предел диска
+	// снимают файлы целиком, без переписывания
+	storeBucket         = time.Hour
+	storeBucketLayout   = "20060102T15"
+	defaultStoreMax     = 1 << 30
+	defaultStoreCompact = 10 * time.Minute
+)
+
+var defaultStoreRetention = map[string]time.Duration{
+	storeMetrics: 7 * 24 * time.Hour,
+	storeEvents:  30 * 24 * time.Hour,
+	storeFlows:   24 * time.Hour,
+}
+
+// flowSample — строка вида flows: трафик по удалённым сетям, портам и ASN за один отчёт.
+// Он самый объёмный и нужен для разбора недавнего, поэтому хранится отдельно и недолго
+type flowSample struct {
+	Timestamp    int64                 `json:"timestamp"`
+	Host         string                `json:"host"`
+	SubnetGroups []reporter.GroupRates `json:"subnet_groups,omitempty"`
+	PortGroups   []reporter.GroupRates `json:"port_groups,omitempty"`
+	TopASNs      []reporter.ASNRates   `json:"top_asns,omitempty"`
+}
+
+// localStore пишет отчёты на диск (STORE_DIR) — по виду данных в свой каталог, по часу в файл
+// JSON Lines: metrics — отчёты без событий и срезов трафика, events — события, flows — срезы.
+// Фоновое уплотнение сжимает закрытые корзины в .gz, удаляет корзины старше срока своего вида
+// (STORE_RETENTION) и держит всё хранилище в пределах STORE_MAX_BYTES
+type localStore struct {
+	dir       string
+	retention map[string]time.Duration
+	maxBytes  uint64 // 0 — без предела
+	compact   time.Duration
+
+	mu      sync.Mutex
+	files   map[string]*storeFile // открытая корзина каждого вида
+	failing bool
+}
+
+type storeFile struct {
+	path string
+	f    *os.File
+}
+
+// storeBucketFile — корзина на диске
+type storeBucketFile struct {
+	kind  string
+	path  string
+	start time.Time
+	size  int64
+	gz    bool
+}
+
+// parseRetention разбирает "metrics=7d,events=30d,flows=24h"; неупомянутые виды — по умолчанию
+func parseRetention(v string) (map[string]time.Duration, error) {
+	pairs, err := parseLabels(v)
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]time.Duration, len(storeKinds))
+	for k, d := range defaultStoreRetention {
+		out[k] = d
+	}
+	for kind, s := range pairs {
+		if !slices.Contains(storeKinds, kind) {
+			return nil, fmt.Errorf("unknown data type %q, want %s", kind, strings.Join(storeKinds, ", "))
+		}
+		d, err := parseDays(s)
+		if err != nil || d < storeBucket {
+			return nil, fmt.Errorf("%s: invalid retention %q, want at least 1h", kind, s)
+		}
+		out[kind] = d
+	}
+	return out, nil
+}
+
+// parseDays — time.ParseDuration, который понимает ещё и дни: "7d", "1.5d"
+func parseDays(s string) (time.Duration, error) {
+	if d, ok := strings.CutSuffix(s, "d"); ok {
+		f, err := strconv.ParseFloat(d, 64)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(f * float64(24*time.Hour)), nil
+	}
+	return time.ParseDuration(s)
+}
+
+// open создаёт каталоги видов
+func (s *localStore) open() error {
+	for _, kind := range storeKinds {
+		if err := os.MkdirAll(filepath.Join(s.dir, kind), 0o755); err != nil {
+			return err
+		}
+	}
+	s.files = make(map[string]*storeFile)
+	return nil
+}
+
+// write раскладывает отчёт по видам. Ошибку диска пишет в журнал один раз до восстановления:
+// переполненный диск не должен топить журнал, а сбор — останавливаться
+func (s *localStore) write(pl reporter.Payload, now time.Time) {
+	flow := flowSample{Timestamp: pl.Timestamp, Host: pl.Host, SubnetGroups: pl.SubnetGroups, PortGroups: pl.PortGroups, TopASNs: pl.TopASNs}
+	events := pl.Events
+	pl.Events, pl.SubnetGroups, pl.PortGroups, pl.TopASNs = nil, nil, nil, nil
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := s.append(storeMetrics, now, pl)
+	for _, ev := range events {
+		err = errors.Join(err, s.append(storeEvents, now, ev))
+	}
+	if flow.SubnetGroups != nil || flow.PortGroups != nil || flow.TopASNs != nil {
+		err = errors.Join(err, s.append(storeFlows, now, flow))
+	}
+	switch {
+	case err != nil && !s.failing:
+		msg.Warnf(msg.StoreError, err)
+		s.failing = true
+	case err == nil && s.failing:
+		msg.Printf(msg.StoreRecovered, s.dir)
+		s.failing = false
+	}
+}
+
+// append — под mu: строка в текущую корзину вида; при смене часа прежняя закрывается
+func (s *localStore) append(kind string, now time.Time, v any) error {
+	path := filepath.Join(s.dir, kind, now.UTC().Format(storeBucketLayout)+".jsonl")
+	sf := s.files[kind]
+	if sf == nil || sf.path != path {
+		if sf != nil {
+			sf.f.Close()
+			delete(s.files, kind)
+		}
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return err
+		}
+		sf = &storeFile{path: path, f: f}
+		s.files[kind] = sf
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = sf.f.Write(append(b, '\n'))
+	return err
+}
+
+// run уплотняет хранилище сразу и затем раз в compact
+func (s *localStore) run(ctx context.Context) {
+	ticker := time.NewTicker(s.compact)
+	defer ticker.Stop()
+	for {
+		if err := s.compactOnce(time.Now()); err != nil {
+			msg.Warnf(msg.StoreError, err)
+		}
+		select {
+		case <-ctx.Done():
+			s.close()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *localStore) close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for kind, sf := range s.files {
+		sf.f.Close()
+		delete(s.files, kind)
+	}
+}
+
+// compactOnce: сжать закрытые корзины, удалить просроченные, затем, если хранилище больше
+// maxBytes, — удалять корзины по старшинству относительно срока их вида: при нехватке места
+// каждый вид теряет ту же долю своей глубины, и короткие flows не вытесняют долгие events целиком
+func (s *localStore) compactOnce(now time.Time) error {
+	buckets, err := s.list()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	open := make(map[string]bool, len(s.files))
+	for _, sf := range s.files {
+		open[sf.path] = true
+	}
+	s.mu.Unlock()
+
+	var expired, capped, compressed int
+	var errs []error
+	kept := buckets[:0]
+	for _, b := range buckets {
+		switch {
+		case !b.start.Add(storeBucket).After(now.Add(-s.retention[b.kind])):
+			if err := os.Remove(b.path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			expired++
+		case !b.gz && !open[b.path] && !b.start.Add(storeBucket).After(now):
+			size, err := gzipBucket(b.path)
+			if err != nil {
+				errs = append(errs, err)
+				kept = append(kept, b)
+				continue
+			}
+			b.path, b.size, b.gz = b.path+".gz", size, true
+			compressed++
+			kept = append(kept, b)
+		default:
+			kept = append(kept, b)
+		}
+	}
+
+	var total uint64
+	for _, b := range kept {
+		total += uint64(b.size)
+	}
+	if s.maxBytes > 0 && total > s.maxBytes {
+		age := func(b storeBucketFile) float64 {
+			return float64(now.Sub(b.start)) / float64(s.retention[b.kind])
+		}
+		slices.SortFunc(kept, func(a, b storeBucketFile) int { return cmp.Compare(age(b), age(a)) })
+		for _, b := range kept {
+			if total <= s.maxBytes {
+				break
+			}
+			if open[b.path] {
+				continue
+			}
+			if err := os.Remove(b.path); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			total -= uint64(b.size)
+			capped++
+		}
+	}
+	if expired+capped+compressed > 0 {
+		msg.Printf(msg.StoreCompacted, expired, capped, compressed, formatSize(float64(total)))
+	}
+	return errors.Join(errs...)
+}
+
+// list — корзины всех видов; чужие файлы в каталогах не трогаются
+func (s *localStore) list() ([]storeBucketFile, error) {
+	var out []storeBucketFile
+	for _, kind := range storeKinds {
+		entries, err := os.ReadDir(filepath.Join(s.dir, kind))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			name, gz := strings.CutSuffix(e.Name(), ".gz")
+			stem, ok := strings.CutSuffix(name, ".jsonl")
+			if !ok || !e.Type().IsRegular() {
+				continue
+			}
+			start, err := time.Parse(storeBucketLayout, stem)
+			if err != nil {
+				continue
+			}
+			info, err := e.Info()
+			if err != nil {
+				continue
+			}
+			out = append(out, storeBucketFile{kind: kind, path: filepath.Join(s.dir, kind, e.Name()), start: start, size: info.Size(), gz: gz})
+		}
+	}
+	return out, nil
+}
+
+// gzipBucket сжимает корзину в path.gz и удаляет исходную. Если .gz уже есть (часы отступили
+// назад, и час записан повторно), дописывает к нему ещё один член gzip — читается как одно целое
+func gzipBucket(path string) (int64, error) {
+	src, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer src.Close()
+	target, flags := path+".gz", os.O_WRONLY|os.O_CREATE|os.O_APPEND
+	// новый .gz пишется во временный файл: оборванное сжатие не оставит битой корзины
+	out := target
+	if _, err := os.Stat(target); errors.Is(err, fs.ErrNotExist) {
+		out, flags = target+".tmp", os.O_WRONLY|os.O_CREATE|os.O_TRUNC
+	}
+	dst, err := os.OpenFile(out, flags, 0o644)
+	if err != nil {
+		return 0, err
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = dst.Sync()
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil && out != target {
+		err = os.Rename(out, target)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("compress %s: %w", path, err)
+	}
+	st, err := os.Stat(target)
+	if err != nil {
+		return 0, err
+	}
+	return st.Size(), os.Remove(path)
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// selfStats — счётчики самого агента: ошибки доставки, задержка отправки, потери, ошибки чтения /proc
+type selfStats struct {
+	start time.Time
+
+	mu                  sync.Mutex
+	consecutiveFailures int
+	lastLatency         time.Duration
+	samplesDropped      uint64
+	readErrors          uint64
+	collectorTimeouts   uint64
+	// длительность последнего чтения каждого коллектора
+	collectorDurations map[string]time.Duration
+
+	// для /healthz: фильтр интерфейсов ничего не выбрал
+	noInterfaces bool
+	available    []string
+}
+
+func newSelfStats(start time.Time) *selfStats {
+	return &selfStats{start: start}
+}
+
+// reportDone учитывает одну отправку на основной эндпоинт; samples — сколько отчётов в ней
+func (s *selfStats) reportDone(latency time.Duration, samples int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastLatency = latency
+	if err != nil {
+		s.consecutiveFailures++
+		s.samplesDropped += uint64(samples)
+		return
+	}
+	s.consecutiveFailures = 0
+}
+
+// dropped — отчёты выброшены из переполненной очереди отправки
+func (s *selfStats) dropped(samples int) {
+	s.mu.Lock()
+	s.samplesDropped += uint64(samples)
+	s.mu.Unlock()
+}
+
+func (s *selfStats) readError() {
+	s.mu.Lock()
+	s.readErrors++
+	s.mu.Unlock()
+}
+
+func (s *selfStats) collectorDone(name string, d time.Duration) {
+	s.mu.Lock()
+	if s.collectorDurations == nil {
+		s.collectorDurations = make(map[string]time.Duration)
+	}
+	s.collectorDurations[name] = d
+	s.mu.Unlock()
+}
+
+func (s *selfStats) collectorTimeout() {
+	s.mu.Lock()
+	s.collectorTimeouts++
+	s.mu.Unlock()
+}
+
+// interfacesMatched запоминает результат фильтра; true — состояние изменилось
+func (s *selfStats) interfacesMatched(matched bool, available []string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	changed := s.noInterfaces == matched
+	s.noInterfaces, s.available = !matched, available
+	return changed
+}
+
+func (s *selfStats) health() (ok bool, available []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.noInterfaces, s.available
+}
+
+func (s *selfStats) snapshot(now time.Time) *reporter.Telemetry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t := &reporter.Telemetry{
+		ConsecutiveReportFailures: s.consecutiveFailures,
+		LastReportLatencyMs:       float64(s.lastLatency.Microseconds()) / 1000,
+		SamplesDropped:            s.samplesDropped,
+		CollectorReadErrors:       s.readErrors,
+		CollectorTimeouts:         s.collectorTimeouts,
+		UptimeSeconds:             now.Sub(s.start).Seconds(),
+	}
+	if len(s.collectorDurations) > 0 {
+		t.CollectorDurationsMs = make(map[string]float64, len(s.collectorDurations))
+		for name, d := range s.collectorDurations {
+			t.CollectorDurationsMs[name] = float64(d.Microseconds()) / 1000
+		}
+	}
+	return t
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const (
+	defaultTopRefresh = time.Second
+	topWindow         = 5 * time.Minute
+)
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// topIface — строка top: скорость за последний шаг и история rx+tx за окно
+type topIface struct {
+	name   string
+	rx, tx float64
+	hist   []float64
+}
+
+// topView — состояние экрана top между обновлениями
+type topView struct {
+	filter  collector.Filter
+	all     bool
+	byName  bool
+	maxHist int
+	ifaces  map[string]*topIface
+	host    string
+	refresh time.Duration
+}
+
+// runTop — живая картина нагрузки для SRE на узле: скорости по интерфейсам, спарклайны
+// за 5 минут и состояние доставки работающего агента (через его API_LISTEN). Счётчики
+// читает сам, агент для этого не нужен. Не в терминале — печатает один снимок и выходит
+func runTop(args []string) int {
+	fs := flag.NewFlagSet("top", flag.ContinueOnError)
+	refresh := fs.Duration("interval", defaultTopRefresh, "refresh interval")
+	all := fs.Bool("all", false, "show all interfaces, not only INTERFACES/INTERFACES_EXCLUDE")
+	api := fs.String("api", os.Getenv("API_LISTEN"), "agent API address for delivery status (empty — don't show)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *refresh <= 0 {
+		*refresh = defaultTopRefresh
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	source := collector.ProcNetDev{Path: os.Getenv("PROC_NET_DEV")}
+	v := &topView{
+		filter:  collector.Filter{Include: splitList(os.Getenv("INTERFACES")), Exclude: splitList(os.Getenv("INTERFACES_EXCLUDE"))},
+		all:     *all,
+		maxHist: max(int(topWindow / *refresh), 1),
+		ifaces:  make(map[string]*topIface),
+		host:    hostname(),
+		refresh: *refresh,
+	}
+	prev, err := source.Read()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "top: %v\n", err)
+		return 1
+	}
+	prevAt := time.Now()
+	status := newDeliveryStatus(*api)
+
+	in := int(os.Stdin.Fd())
+	old, err := unix.IoctlGetTermios(in, unix.TCGETS)
+	_, outErr := unix.IoctlGetTermios(int(os.Stdout.Fd()), unix.TCGETS)
+	interactive := err == nil && outErr == nil
+	keys := make(chan byte, 8)
+	if interactive {
+		// без эха и построчного ввода, но с ISIG: Ctrl-C приходит сигналом и терминал восстанавливается
+		raw := *old
+		raw.Lflag &^= unix.ECHO | unix.ICANON
+		raw.Cc[unix.VMIN], raw.Cc[unix.VTIME] = 1, 0
+		if err := unix.IoctlSetTermios(in, unix.TCSETS, &raw); err != nil {
+			interactive = false
+		} else {
+			defer unix.IoctlSetTermios(in, unix.TCSETS, old)
+			fmt.Print("\x1b[?1049h\x1b[?25l")
+			defer fmt.Print("\x1b[?25h\x1b[?1049l")
+			go func() {
+				b := make([]byte, 1)
+				for {
+					if n, err := os.Stdin.Read(b); err != nil || n == 0 {
+						return
+					}
+					keys <- b[0]
+				}
+			}()
+		}
+	}
+
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return 0
+		case k := <-keys:
+			switch k {
+			case 'q', 'Q':
+				return 0
+			case 's':
+				v.byName = !v.byName
+			case 'a':
+				v.all = !v.all
+			}
+		case <-ticker.C:
+			cur, err := source.Read()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "top: %v\n", err)
+				continue
+			}
+			now := time.Now()
+			v.update(cur, prev, now.Sub(prevAt).Seconds())
+			prev, prevAt = cur, now
+			status.poll()
+		}
+		cols, rows := 100, 40
+		if ws, err := unix.IoctlGetWinsize(int(os.Stdout.Fd()), unix.TIOCGWINSZ); err == nil && ws.Col > 0 {
+			cols, rows = int(ws.Col), int(ws.Row)
+		}
+		frame := v.render(status.line(), cols, rows, interactive)
+		if !interactive {
+			fmt.Print(frame)
+			return 0
+		}
+		fmt.Print("\x1b[H\x1b[2J" + frame)
+	}
+}
+
+func (v *topView) update(cur, prev map[string]collector.Counters, sec float64) {
+	if sec <= 0 {
+		return
+	}
+	for name := range v.ifaces {
+		if _, ok := cur[name]; !ok {
+			delete(v.ifaces, name)
+		}
+	}
+	for name, c := range cur {
+		p, ok := prev[name]
+		if !ok {
+			continue
+		}
+		ti := v.ifaces[name]
+		if ti == nil {
+			ti = &topIface{name: name}
+			v.ifaces[name] = ti
+		}
+		ti.rx, ti.tx = collector.Delta(c.Rx, p.Rx)/sec, collector.Delta(c.Tx, p.Tx)/sec
+		ti.hist = append(ti.hist, ti.rx+ti.tx)
+		if len(ti.hist) > v.maxHist {
+			ti.hist = ti.hist[len(ti.hist)-v.maxHist:]
+		}
+	}
+}
+
+func (v *topView) render(delivery string, cols, rows int, interactive bool) string {
+	var shown []*topIface
+	var rx, tx float64
+	for _, ti := range v.ifaces {
+		match := v.filter.Match(ti.name)
+		if match {
+			rx += ti.rx
+			tx += ti.tx
+		}
+		if match || v.all {
+			shown = append(shown, ti)
+		}
+	}
+	sort.Slice(shown, func(i, j int) bool {
+		a, b := shown[i], shown[j]
+		if !v.byName && a.rx+a.tx != b.rx+b.tx {
+			return a.rx+a.tx > b.rx+b.tx
+		}
+		return a.name < b.name
+	})
+
+	var b strings.Builder
+	sortBy := "rate"
+	if v.byName {
+		sortBy = "name"
+	}
+	fmt.Fprintf(&b, "network-stater top — %s   refresh %s   sort: %s", v.host, v.refresh, sortBy)
+	if interactive {
+		b.WriteString("   [q] quit [s] sort [a] all interfaces")
+	}
+	b.WriteString("\r\n")
+	fmt.Fprintf(&b, "total   rx %s   tx %s\r\n", formatBits(rx), formatBits(tx))
+	b.WriteString(delivery + "\r\n\r\n")
+
+	const fixed = 16 + 2*14 + 2
+	width := max(cols-fixed, 10)
+	fmt.Fprintf(&b, "%-16s%14s%14s  %s\r\n", "INTERFACE", "RX", "TX", "5m rx+tx")
+	for i, ti := range shown {
+		// шапка занимает 5 строк
+		if interactive && i >= rows-5 {
+			break
+		}
+		name := ti.name
+		if !v.filter.Match(name) {
+			name += "*"
+		}
+		fmt.Fprintf(&b, "%-16s%14s%14s  %s\r\n", name, formatBits(ti.rx), formatBits(ti.tx), sparkline(ti.hist, width))
+	}
+	if v.all {
+		b.WriteString("* not counted in the total\r\n")
+	}
+	if !interactive {
+		return strings.ReplaceAll(b.String(), "\r\n", "\n")
+	}
+	return b.String()
+}
+
+// sparkline сжимает историю до width столбцов средними по корзинам; масштаб — максимум окна
+func sparkline(hist []float64, width int) string {
+	n := len(hist)
+	if n == 0 {
+		return ""
+	}
+	buckets := min(width, n)
+	vals := make([]float64, buckets)
+	var top float64
+	for i := range vals {
+		lo, hi := i*n/buckets, (i+1)*n/buckets
+		var sum float64
+		for _, h := range hist[lo:hi] {
+			sum += h
+		}
+		vals[i] = sum / float64(hi-lo)
+		top = max(top, vals[i])
+	}
+	out := make([]rune, buckets)
+	for i, x := range vals {
+		idx := 0
+		if top > 0 {
+			idx = min(int(x/top*float64(len(sparkBlocks)-1)+0.5), len(sparkBlocks)-1)
+		}
+		out[i] = sparkBlocks[idx]
+	}
+	return string(out)
+}
+
+// formatBits: байт/с → "12.3 Mbit/s"
+func formatBits(bytesPerSec float64) string {
+	bits := bytesPerSec * 8
+	for _, u := range []struct {
+		div  float64
+		name string
+	}{{1e9, "Gbit/s"}, {1e6, "Mbit/s"}, {1e3, "kbit/s"}} {
+		if bits >= u.div {
+			return fmt.Sprintf("%.1f %s", bits/u.div, u.name)
+		}
+	}
+	return fmt.Sprintf("%.0f bit/s", bits)
+}
+
+// deliveryStatus берёт телеметрию доставки у работающего агента через его API
+type deliveryStatus struct {
+	url    string
+	client *http.Client
+	last   *reporter.Payload
+	err    error
+}
+
+func newDeliveryStatus(addr string) *deliveryStatus {
+	if addr == "" {
+		return &deliveryStatus{}
+	}
+	if strings.HasPrefix(addr, ":") {
+		addr = "127.0.0.1" + addr
+	}
+	return &deliveryStatus{url: "http://" + addr + "/v1/current", client: &http.Client{Timeout: 300 * time.Millisecond}}
+}
+
+func (d *deliveryStatus) poll() {
+	if d.url == "" {
+		return
+	}
+	resp, err := d.client.Get(d.url)
+	if err != nil {
+		d.last, d.err = nil, err
+		return
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		d.last, d.err = nil, fmt.Errorf("agent API: %s", resp.Status)
+		return
+	}
+	var pl reporter.Payload
+	if err := json.NewDecoder(resp.Body).Decode(&pl); err != nil {
+		d.last, d.err = nil, err
+		return
+	}
+	d.last, d.err = &pl, nil
+}
+
+func (d *deliveryStatus) line() string {
+	switch {
+	case d.url == "":
+		return "delivery: unknown (agent API not configured, set API_LISTEN or -api)"
+	case d.err != nil:
+		return "delivery: agent not reachable: " + d.err.Error()
+	case d.last == nil:
+		return "delivery: waiting for agent"
+	}
+	pl := d.last
+	s := fmt.Sprintf("delivery: last sample %s ago (#%d)", time.Since(time.Unix(pl.Timestamp, 0)).Round(time.Second), pl.Sequence)
+	if t := pl.Telemetry; t != nil {
+		state := "ok"
+		if t.ConsecutiveReportFailures > 0 {
+			state = fmt.Sprintf("FAILING (%d in a row)", t.ConsecutiveReportFailures)
+		}
+		s += fmt.Sprintf(", %s, latency %.1fms, dropped %d, read errors %d", state, t.LastReportLatencyMs, t.SamplesDropped, t.CollectorReadErrors)
+	}
+	return s
+}
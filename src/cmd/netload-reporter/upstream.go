@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/msg"
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+	"github.com/iflixer/network-stater/src/pkg/subnets"
+)
+
+// группа upstream-ов в отдельной eBPF-программе учёта; всё остальное — клиенты
+const upstreamGroup = "upstream"
+
+const defaultUpstreamResolve = 5 * time.Minute
+
+// upstreamWatch делит трафик CDN-узла на обмен с origin-ами (UPSTREAMS) и с клиентами.
+// Имена хостов перерезолвливаются раз в resolveEvery: адреса origin-ов за балансировщиками меняются
+type upstreamWatch struct {
+	nets         []netip.Prefix
+	hosts        []string
+	resolveEvery time.Duration
+
+	acct       *subnets.Accounting
+	hostAddrs  map[string][]netip.Prefix
+	resolved   []netip.Prefix
+	resolvedAt time.Time
+	prev       map[string]collector.Counters
+	prevAt     time.Time
+}
+
+// newUpstreamWatch разбирает список сетей, адресов и имён хостов
+func newUpstreamWatch(list []string, resolveEvery time.Duration) (*upstreamWatch, error) {
+	u := &upstreamWatch{resolveEvery: resolveEvery}
+	for _, s := range list {
+		if p, err := netip.ParsePrefix(s); err == nil {
+			u.nets = append(u.nets, p.Masked())
+		} else if a, err := netip.ParseAddr(s); err == nil {
+			u.nets = append(u.nets, netip.PrefixFrom(a, a.BitLen()))
+		} else if strings.ContainsAny(s, "/:") {
+			return nil, fmt.Errorf("invalid upstream %q", s)
+		} else {
+			u.hosts = append(u.hosts, s)
+		}
+	}
+	if len(u.nets) == 0 && len(u.hosts) == 0 {
+		return nil, fmt.Errorf("no upstreams configured")
+	}
+	return u, nil
+}
+
+// open резолвит хосты и подключает учёт к cgroup
+func (u *upstreamWatch) open(ctx context.Context, cgroupPath string, now time.Time) error {
+	prefixes := u.resolve(ctx, now)
+	acct, err := subnets.Open([]subnets.Group{{Name: upstreamGroup, Prefixes: prefixes}}, cgroupPath)
+	if err != nil {
+		return err
+	}
+	if u.prev, err = acct.Read(); err != nil {
+		acct.Close()
+		return err
+	}
+	u.acct, u.prevAt = acct, now
+	msg.Printf(msg.UpstreamPrefixes, describePrefixes(prefixes))
+	return nil
+}
+
+func (u *upstreamWatch) Close() error {
+	return u.acct.Close()
+}
+
+// resolve возвращает сети и адреса хостов; хост, который не резолвится, пропускается
+// с предупреждением (до следующей попытки остаются его прежние адреса)
+func (u *upstreamWatch) resolve(ctx context.Context, now time.Time) []netip.Prefix {
+	if u.hostAddrs == nil {
+		u.hostAddrs = make(map[string][]netip.Prefix, len(u.hosts))
+	}
+	out := slices.Clone(u.nets)
+	for _, h := range u.hosts {
+		rctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+		addrs, err := net.DefaultResolver.LookupNetIP(rctx, "ip", h)
+		cancel()
+		if err != nil {
+			msg.Warnf(msg.UpstreamError, h, err)
+		} else {
+			ps := make([]netip.Prefix, 0, len(addrs))
+			for _, a := range addrs {
+				a = a.Unmap()
+				ps = append(ps, netip.PrefixFrom(a, a.BitLen()))
+			}
+			u.hostAddrs[h] = ps
+		}
+		out = append(out, u.hostAddrs[h]...)
+	}
+	slices.SortFunc(out, func(a, b netip.Prefix) int { return strings.Compare(a.String(), b.String()) })
+	out = slices.Compact(out)
+	u.resolved, u.resolvedAt = out, now
+	return out
+}
+
+// observe — доли origin и клиентов за интервал; prev, как у asnWatch, сдвигает только advance
+func (u *upstreamWatch) observe(ctx context.Context, now time.Time) (cs *reporter.CacheStats, advance func(), err error) {
+	if len(u.hosts) > 0 && now.Sub(u.resolvedAt) >= u.resolveEvery {
+		before := u.resolved
+		if after := u.resolve(ctx, now); !slices.Equal(before, after) {
+			if err := u.acct.SetPrefixes(upstreamGroup, after); err != nil {
+				return nil, nil, err
+			}
+			msg.Printf(msg.UpstreamPrefixes, describePrefixes(after))
+		}
+	}
+	cur, err := u.acct.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	cs = reporter.NewCacheStats(cur[upstreamGroup], u.prev[upstreamGroup], cur[subnets.OtherGroup], u.prev[subnets.OtherGroup], now.Sub(u.prevAt).Seconds())
+	return cs, func() { u.prev, u.prevAt = cur, now }, nil
+}
+
+func describePrefixes(prefixes []netip.Prefix) string {
+	if len(prefixes) == 0 {
+		return "(none resolved)"
+	}
+	s := make([]string, len(prefixes))
+	for i, p := range prefixes {
+		s[i] = p.String()
+	}
+	return strings.Join(s, ",")
+}
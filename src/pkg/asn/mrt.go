@@ -0,0 +1,142 @@
+package asn
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/netip"
+)
+
+// MRT (RFC 6396): заголовок — timestamp(4) type(2) subtype(2) length(4)
+const (
+	mrtHeaderLen   = 12
+	mrtTableDumpV2 = 13
+
+	ribIPv4Unicast = 2
+	ribIPv6Unicast = 4
+
+	attrASPath = 2
+	asSequence = 2
+
+	// запись MRT больше этого — значит, поток испорчен
+	maxRecordLen = 16 << 20
+)
+
+// readMRT собирает origin ASN по RIB-записям TABLE_DUMP_V2: берётся последний AS
+// в AS_PATH первой записи о префиксе (AS_PATH в TABLE_DUMP_V2 всегда 4-байтный).
+// Прочие типы и подтипы (PEER_INDEX_TABLE, ADD-PATH) пропускаются
+func readMRT(r io.Reader) (Table, error) {
+	t := make(Table)
+	hdr := make([]byte, mrtHeaderLen)
+	var buf []byte
+	for {
+		if _, err := io.ReadFull(r, hdr); err != nil {
+			if err == io.EOF {
+				return t, nil
+			}
+			return nil, fmt.Errorf("mrt header: %w", err)
+		}
+		typ, sub := binary.BigEndian.Uint16(hdr[4:]), binary.BigEndian.Uint16(hdr[6:])
+		n := binary.BigEndian.Uint32(hdr[8:])
+		if n > maxRecordLen {
+			return nil, fmt.Errorf("mrt record of %d bytes", n)
+		}
+		if cap(buf) < int(n) {
+			buf = make([]byte, n)
+		}
+		rec := buf[:n]
+		if _, err := io.ReadFull(r, rec); err != nil {
+			return nil, fmt.Errorf("mrt record: %w", err)
+		}
+		if typ != mrtTableDumpV2 || (sub != ribIPv4Unicast && sub != ribIPv6Unicast) {
+			continue
+		}
+		p, origin, err := parseRIB(rec, sub == ribIPv6Unicast)
+		if err != nil {
+			return nil, err
+		}
+		if origin != 0 {
+			t[origin] = append(t[origin], p)
+		}
+	}
+}
+
+var errShort = errors.New("mrt: truncated RIB entry")
+
+// parseRIB: sequence(4) prefix_len(1) prefix entry_count(2),
+// записи: peer_index(2) originated(4) attr_len(2) атрибуты
+func parseRIB(b []byte, v6 bool) (netip.Prefix, uint32, error) {
+	if len(b) < 5 {
+		return netip.Prefix{}, 0, errShort
+	}
+	bits := int(b[4])
+	size := 4
+	if v6 {
+		size = 16
+	}
+	plen := (bits + 7) / 8
+	if bits > size*8 || len(b) < 5+plen+2 {
+		return netip.Prefix{}, 0, errShort
+	}
+	var raw [16]byte
+	copy(raw[:], b[5:5+plen])
+	addr := netip.AddrFrom16(raw)
+	if !v6 {
+		addr = netip.AddrFrom4([4]byte(raw[:4]))
+	}
+	p := netip.PrefixFrom(addr, bits).Masked()
+
+	b = b[5+plen:]
+	count := binary.BigEndian.Uint16(b)
+	b = b[2:]
+	for i := 0; i < int(count); i++ {
+		if len(b) < 8 {
+			return p, 0, errShort
+		}
+		alen := int(binary.BigEndian.Uint16(b[6:]))
+		if len(b) < 8+alen {
+			return p, 0, errShort
+		}
+		if origin := originAS(b[8 : 8+alen]); origin != 0 {
+			return p, origin, nil
+		}
+		b = b[8+alen:]
+	}
+	return p, 0, nil
+}
+
+// originAS — последний AS последнего сегмента AS_SEQUENCE; 0 — не найден
+func originAS(attrs []byte) uint32 {
+	for len(attrs) >= 3 {
+		flags, typ := attrs[0], attrs[1]
+		hl, l := 3, int(attrs[2])
+		if flags&0x10 != 0 { // extended length
+			if len(attrs) < 4 {
+				return 0
+			}
+			hl, l = 4, int(binary.BigEndian.Uint16(attrs[2:]))
+		}
+		if len(attrs) < hl+l {
+			return 0
+		}
+		val := attrs[hl : hl+l]
+		attrs = attrs[hl+l:]
+		if typ != attrASPath {
+			continue
+		}
+		var origin uint32
+		for len(val) >= 2 {
+			segType, n := val[0], int(val[1])
+			if len(val) < 2+4*n {
+				return 0
+			}
+			if segType == asSequence && n > 0 {
+				origin = binary.BigEndian.Uint32(val[2+4*(n-1):])
+			}
+			val = val[2+4*n:]
+		}
+		return origin
+	}
+	return 0
+}
@@ -0,0 +1,119 @@
+// Package asn загружает локальную таблицу «префикс → origin ASN» из MRT-дампа
+// (TABLE_DUMP_V2, RouteViews/RIS) или текстового файла для учёта трафика по ASN
+package asn
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/netip"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Table — префиксы по origin ASN
+type Table map[uint32][]netip.Prefix
+
+// Prefixes — всего префиксов в таблице
+func (t Table) Prefixes() int {
+	n := 0
+	for _, ps := range t {
+		n += len(ps)
+	}
+	return n
+}
+
+// ASNs — номера по возрастанию
+func (t Table) ASNs() []uint32 {
+	out := make([]uint32, 0, len(t))
+	for a := range t {
+		out = append(out, a)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i] < out[j] })
+	return out
+}
+
+// Load читает таблицу из path: .gz и .bz2 распаковываются, формат (MRT или текст)
+// определяется по содержимому
+func Load(path string) (Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch {
+	case strings.HasSuffix(path, ".gz"):
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		defer zr.Close()
+		r = zr
+	case strings.HasSuffix(path, ".bz2"):
+		r = bzip2.NewReader(f)
+	}
+	br := bufio.NewReaderSize(r, 1<<20)
+
+	head, err := br.Peek(mrtHeaderLen)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	var t Table
+	if isMRT(head) {
+		t, err = readMRT(br)
+	} else {
+		t, err = readText(br)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return t, nil
+}
+
+// readText: строки "203.0.113.0/24 64500" (или "AS64500"); # — комментарий
+func readText(r io.Reader) (Table, error) {
+	t := make(Table)
+	sc := bufio.NewScanner(r)
+	for line := 1; sc.Scan(); line++ {
+		s, _, _ := strings.Cut(sc.Text(), "#")
+		fields := strings.Fields(s)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want \"prefix asn\"", line)
+		}
+		p, err := netip.ParsePrefix(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		a, err := ParseASN(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		t[a] = append(t[a], p.Masked())
+	}
+	return t, sc.Err()
+}
+
+// ParseASN разбирает "64500" или "AS64500"
+func ParseASN(s string) (uint32, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "AS"), "as")
+	n, err := strconv.ParseUint(s, 10, 32)
+	if err != nil || n == 0 {
+		return 0, fmt.Errorf("invalid ASN %q", s)
+	}
+	return uint32(n), nil
+}
+
+// isMRT: в заголовке MRT тип 13 (TABLE_DUMP_V2) — в тексте таких байт не бывает
+func isMRT(head []byte) bool {
+	return len(head) >= mrtHeaderLen && bytes.Equal(head[4:6], []byte{0, mrtTableDumpV2})
+}
@@ -0,0 +1,52 @@
+// Package bus — экспортёры отчётов в шины сообщений (NATS, Kafka) вместо HTTP.
+// Тело сообщения то же, что у HTTP-отчёта (reporter.Encode), Content-Type и
+// Content-Encoding передаются заголовками сообщения
+package bus
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// TLSConfig — пути к сертификатам; пустой CA — системные корневые
+type TLSConfig struct {
+	Enabled  bool
+	CA       string
+	Cert     string
+	Key      string
+	Insecure bool // без проверки сертификата сервера, только для стендов
+}
+
+func (c TLSConfig) Build() (*tls.Config, error) {
+	if !c.Enabled {
+		return nil, nil
+	}
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12, InsecureSkipVerify: c.Insecure}
+	if c.CA != "" {
+		pem, err := os.ReadFile(c.CA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("%s: no certificates found", c.CA)
+		}
+	}
+	if c.Cert != "" || c.Key != "" {
+		cert, err := tls.LoadX509KeyPair(c.Cert, c.Key)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// temporaryError помечает ошибку публикации как временную для reporter.Retryable
+type temporaryError struct{ err error }
+
+func (e temporaryError) Error() string   { return e.err.Error() }
+func (e temporaryError) Unwrap() error   { return e.err }
+func (e temporaryError) Temporary() bool { return true }
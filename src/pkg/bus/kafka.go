@@ -0,0 +1,112 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+const DefaultKafkaTopic = "netload"
+
+// SASL-механизмы (KAFKA_SASL_MECHANISM)
+const (
+	SASLPlain       = "plain"
+	SASLScramSHA256 = "scram-sha-256"
+	SASLScramSHA512 = "scram-sha-512"
+)
+
+type KafkaConfig struct {
+	Brokers       []string
+	Topic         string
+	SASLMechanism string // пусто — без SASL
+	User          string
+	Password      string
+	TLS           TLSConfig
+}
+
+// Kafka пишет отчёты в topic с ключом = имя узла, так что отчёты одного узла
+// попадают в одну партицию и читаются по порядку
+type Kafka struct {
+	name   string
+	key    []byte
+	writer *kafka.Writer
+}
+
+func NewKafka(name, host string, c KafkaConfig) (*Kafka, error) {
+	if len(c.Brokers) == 0 {
+		return nil, fmt.Errorf("kafka: no brokers")
+	}
+	transport := &kafka.Transport{ClientID: "network-stater"}
+	var err error
+	if transport.TLS, err = c.TLS.Build(); err != nil {
+		return nil, fmt.Errorf("kafka tls: %w", err)
+	}
+	if transport.SASL, err = saslMechanism(c); err != nil {
+		return nil, err
+	}
+	topic := c.Topic
+	if topic == "" {
+		topic = DefaultKafkaTopic
+	}
+	return &Kafka{
+		name: name,
+		key:  []byte(host),
+		writer: &kafka.Writer{
+			Addr:         kafka.TCP(c.Brokers...),
+			Topic:        topic,
+			Balancer:     &kafka.Hash{},
+			RequiredAcks: kafka.RequireAll,
+			// повторы делает очередь отправки, здесь — одна попытка на вызов
+			MaxAttempts:  1,
+			BatchTimeout: 10 * time.Millisecond,
+			Transport:    transport,
+		},
+	}, nil
+}
+
+func saslMechanism(c KafkaConfig) (sasl.Mechanism, error) {
+	switch strings.ToLower(c.SASLMechanism) {
+	case "":
+		return nil, nil
+	case SASLPlain:
+		return plain.Mechanism{Username: c.User, Password: c.Password}, nil
+	case SASLScramSHA256:
+		return scram.Mechanism(scram.SHA256, c.User, c.Password)
+	case SASLScramSHA512:
+		return scram.Mechanism(scram.SHA512, c.User, c.Password)
+	}
+	return nil, fmt.Errorf("kafka: unknown SASL mechanism %q", c.SASLMechanism)
+}
+
+func (k *Kafka) Name() string  { return k.name }
+func (k *Kafka) Topic() string { return k.writer.Topic }
+
+func (k *Kafka) Export(ctx context.Context, body reporter.Body) error {
+	msg := kafka.Message{
+		Key:     k.key,
+		Value:   body.Data,
+		Headers: []kafka.Header{{Key: "Content-Type", Value: []byte(body.ContentType)}},
+	}
+	if body.ContentEncoding != "" {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: "Content-Encoding", Value: []byte(body.ContentEncoding)})
+	}
+	for k, v := range body.Headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	if err := k.writer.WriteMessages(ctx, msg); err != nil {
+		return temporaryError{fmt.Errorf("kafka write %s: %w", k.writer.Topic, err)}
+	}
+	return nil
+}
+
+func (k *Kafka) Close() error {
+	return k.writer.Close()
+}
@@ -0,0 +1,87 @@
+package bus
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/iflixer/network-stater/src/pkg/reporter"
+)
+
+// DefaultNATSSubject — {host} заменяется на имя узла
+const DefaultNATSSubject = "netload.{host}"
+
+type NATSConfig struct {
+	URL      string
+	Subject  string
+	Creds    string // .creds-файл (JWT + nkey)
+	User     string
+	Password string
+	Token    string
+	TLS      TLSConfig
+}
+
+// NATS публикует отчёты в subject узла; разрыв соединения переживает сам клиент
+type NATS struct {
+	name    string
+	subject string
+	conn    *nats.Conn
+}
+
+func NewNATS(name, host string, c NATSConfig) (*NATS, error) {
+	// агент стартует и при недоступном сервере: до подключения публикации падают и уходят в повторы
+	opts := []nats.Option{nats.Name("network-stater " + host), nats.MaxReconnects(-1), nats.RetryOnFailedConnect(true)}
+	switch {
+	case c.Creds != "":
+		opts = append(opts, nats.UserCredentials(c.Creds))
+	case c.Token != "":
+		opts = append(opts, nats.Token(c.Token))
+	case c.User != "":
+		opts = append(opts, nats.UserInfo(c.User, c.Password))
+	}
+	tlsCfg, err := c.TLS.Build()
+	if err != nil {
+		return nil, fmt.Errorf("nats tls: %w", err)
+	}
+	if tlsCfg != nil {
+		opts = append(opts, nats.Secure(tlsCfg))
+	}
+	conn, err := nats.Connect(c.URL, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("nats connect %s: %w", c.URL, err)
+	}
+	subject := c.Subject
+	if subject == "" {
+		subject = DefaultNATSSubject
+	}
+	return &NATS{name: name, subject: strings.ReplaceAll(subject, "{host}", host), conn: conn}, nil
+}
+
+func (n *NATS) Name() string    { return n.name }
+func (n *NATS) Subject() string { return n.subject }
+
+// Export публикует сообщение и ждёт подтверждения сервера (flush), чтобы ошибка дошла до повторов
+func (n *NATS) Export(ctx context.Context, body reporter.Body) error {
+	msg := &nats.Msg{Subject: n.subject, Data: body.Data, Header: nats.Header{}}
+	msg.Header.Set("Content-Type", body.ContentType)
+	if body.ContentEncoding != "" {
+		msg.Header.Set("Content-Encoding", body.ContentEncoding)
+	}
+	for k, v := range body.Headers {
+		msg.Header.Set(k, v)
+	}
+	if err := n.conn.PublishMsg(msg); err != nil {
+		return temporaryError{fmt.Errorf("nats publish %s: %w", n.subject, err)}
+	}
+	if err := n.conn.FlushWithContext(ctx); err != nil {
+		return temporaryError{fmt.Errorf("nats publish %s: %w", n.subject, err)}
+	}
+	return nil
+}
+
+// Close дожидается отправки буфера и закрывает соединение
+func (n *NATS) Close() error {
+	return n.conn.Drain()
+}
@@ -0,0 +1,97 @@
+// Package collector читает счётчики сетевых интерфейсов и сопутствующие
+// метрики ядра из /proc и /sys.
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Counters — накопленные счётчики байт (как их отдаёт ядро)
+type Counters struct{ Rx, Tx uint64 }
+
+// Source — источник счётчиков по интерфейсам
+type Source interface {
+	Read() (map[string]Counters, error)
+}
+
+// Delta считает прирост; сброс/переполнение счётчика даёт 0
+func Delta(cur, prev uint64) float64 {
+	if cur >= prev {
+		return float64(cur - prev)
+	}
+	return 0
+}
+
+// readKeyedLine возвращает поля строки, начинающейся с prefix, без самого префикса
+func readKeyedLine(path, prefix string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) > 0 && fields[0] == prefix {
+			return fields[1:], nil
+		}
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: no %s line", path, prefix)
+}
+
+// readHeaderedSection: в /proc/net/snmp и /proc/net/netstat строки идут
+// парами — заголовок и значения; возвращает значения по именам
+func readHeaderedSection(path, prefix string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	sc.Buffer(make([]byte, 64*1024), 1024*1024)
+	var header []string
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || fields[0] != prefix {
+			continue
+		}
+		if header == nil {
+			header = fields
+			continue
+		}
+		out := make(map[string]string, len(header))
+		for i := 1; i < len(header) && i < len(fields); i++ {
+			out[header[i]] = fields[i]
+		}
+		return out, nil
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return nil, fmt.Errorf("%s: no %s section", path, prefix)
+}
+
+func readUintFile(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseUint(strings.TrimSpace(string(b)), 10, 64)
+}
+
+func readSysfs(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
@@ -0,0 +1,90 @@
+package collector
+
+import (
+	"fmt"
+	"path/filepath"
+	"strconv"
+)
+
+const (
+	DefaultProcNetSockstat = "/proc/net/sockstat"
+	DefaultProcNetSnmp     = "/proc/net/snmp"
+	DefaultNetfilterPath   = "/proc/sys/net/netfilter"
+)
+
+// ConnStats — число TCP-сокетов и заполненность таблицы conntrack
+type ConnStats struct {
+	TCPEstablished uint64 `json:"tcp_established"`
+	TCPTimeWait    uint64 `json:"tcp_time_wait"`
+	TCPInUse       uint64 `json:"tcp_inuse"`
+	TCPOrphan      uint64 `json:"tcp_orphan"`
+
+	// без модуля nf_conntrack полей нет
+	*ConntrackStats
+}
+
+type ConntrackStats struct {
+	ConntrackCount    uint64  `json:"conntrack_count"`
+	ConntrackMax      uint64  `json:"conntrack_max"`
+	ConntrackUsagePct float64 `json:"conntrack_usage_pct"`
+}
+
+// ConnStatsPaths — пути к файлам ядра; пустые поля — значения по умолчанию
+type ConnStatsPaths struct {
+	Sockstat  string
+	Snmp      string
+	Netfilter string
+}
+
+// ReadConnStats: established из Tcp CurrEstab (/proc/net/snmp),
+// остальное из /proc/net/sockstat, conntrack из /proc/sys/net/netfilter
+func ReadConnStats(p ConnStatsPaths) (*ConnStats, error) {
+	if p.Sockstat == "" {
+		p.Sockstat = DefaultProcNetSockstat
+	}
+	if p.Snmp == "" {
+		p.Snmp = DefaultProcNetSnmp
+	}
+	if p.Netfilter == "" {
+		p.Netfilter = DefaultNetfilterPath
+	}
+
+	cs := &ConnStats{}
+	sockstat, err := readKeyedLine(p.Sockstat, "TCP:")
+	if err != nil {
+		return nil, err
+	}
+	// "TCP: inuse 4 orphan 0 tw 4 alloc 4 mem 0" — пары имя/значение
+	for i := 0; i+1 < len(sockstat); i += 2 {
+		v, err := strconv.ParseUint(sockstat[i+1], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch sockstat[i] {
+		case "inuse":
+			cs.TCPInUse = v
+		case "orphan":
+			cs.TCPOrphan = v
+		case "tw":
+			cs.TCPTimeWait = v
+		}
+	}
+
+	tcp, err := readHeaderedSection(p.Snmp, "Tcp:")
+	if err != nil {
+		return nil, err
+	}
+	if cs.TCPEstablished, err = strconv.ParseUint(tcp["CurrEstab"], 10, 64); err != nil {
+		return nil, fmt.Errorf("%s: Tcp CurrEstab not found", p.Snmp)
+	}
+
+	count, errCount := readUintFile(filepath.Join(p.Netfilter, "nf_conntrack_count"))
+	max, errMax := readUintFile(filepath.Join(p.Netfilter, "nf_conntrack_max"))
+	if errCount == nil && errMax == nil {
+		cs.ConntrackStats = &ConntrackStats{ConntrackCount: count, ConntrackMax: max}
+		if max > 0 {
+			cs.ConntrackUsagePct = float64(count) / float64(max) * 100
+		}
+	}
+	return cs, nil
+}
@@ -0,0 +1,98 @@
+package collector
+
+import (
+	"math"
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Fake генерирует правдоподобные счётчики без настоящего трафика — для дашбордов,
+// демо и интеграционных тестов. Скорость каждого интерфейса — база RxBps/TxBps (байт/с),
+// суточная синусоида с пиком в PeakHour (UTC), шум и редкие всплески в BurstFactor раз.
+// Нулевые значения настроек выключают соответствующую составляющую
+type Fake struct {
+	Interfaces []string
+	RxBps      float64
+	TxBps      float64
+	// размах суточного цикла в процентах от базы
+	DiurnalPct float64
+	PeakHour   float64
+	NoisePct   float64
+	// всплески: в среднем раз в BurstEvery на BurstDuration
+	BurstEvery    time.Duration
+	BurstDuration time.Duration
+	BurstFactor   float64
+	Seed          uint64 // 0 — случайный
+	Now           func() time.Time
+
+	mu         sync.Mutex
+	rng        *rand.Rand
+	counters   map[string]Counters
+	last       time.Time
+	burstUntil time.Time
+}
+
+func (f *Fake) Read() (map[string]Counters, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	now := time.Now()
+	if f.Now != nil {
+		now = f.Now()
+	}
+	if f.counters == nil {
+		seed := f.Seed
+		if seed == 0 {
+			seed = uint64(now.UnixNano())
+		}
+		f.rng = rand.New(rand.NewPCG(seed, seed>>32|1))
+		f.counters = make(map[string]Counters, len(f.Interfaces))
+		for _, iface := range f.Interfaces {
+			f.counters[iface] = Counters{}
+		}
+		f.last = now
+	}
+
+	if dt := now.Sub(f.last).Seconds(); dt > 0 {
+		m := f.multiplier(now, dt)
+		for _, iface := range f.Interfaces {
+			c := f.counters[iface]
+			c.Rx += uint64(f.RxBps * m * f.noise() * dt)
+			c.Tx += uint64(f.TxBps * m * f.noise() * dt)
+			f.counters[iface] = c
+		}
+		f.last = now
+	}
+
+	out := make(map[string]Counters, len(f.counters))
+	for iface, c := range f.counters {
+		out[iface] = c
+	}
+	return out, nil
+}
+
+// multiplier — доля базовой скорости в момент now с учётом суток и всплеска
+func (f *Fake) multiplier(now time.Time, dt float64) float64 {
+	u := now.UTC()
+	hour := float64(u.Hour()) + float64(u.Minute())/60 + float64(u.Second())/3600
+	m := math.Max(0, 1+f.DiurnalPct/100*math.Cos(2*math.Pi*(hour-f.PeakHour)/24))
+
+	if f.BurstEvery > 0 && f.BurstFactor > 0 {
+		// старт всплеска — пуассоновский поток со средним интервалом BurstEvery
+		if !now.Before(f.burstUntil) && f.rng.Float64() < 1-math.Exp(-dt/f.BurstEvery.Seconds()) {
+			f.burstUntil = now.Add(f.BurstDuration)
+		}
+		if now.Before(f.burstUntil) {
+			m *= f.BurstFactor
+		}
+	}
+	return m
+}
+
+func (f *Fake) noise() float64 {
+	if f.NoisePct <= 0 {
+		return 1
+	}
+	return math.Max(0, 1+f.NoisePct/100*(2*f.rng.Float64()-1))
+}
@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Filter выбирает интерфейсы, которые идут в суммарный трафик.
+// Маски в стиле filepath.Match; пустой Include — uplink-и по IsUplink
+type Filter struct {
+	Include []string
+	Exclude []string
+}
+
+func (f Filter) Match(iface string) bool {
+	if len(f.Include) == 0 {
+		if !IsUplink(iface) {
+			return false
+		}
+	} else if !MatchAny(f.Include, iface) {
+		return false
+	}
+	return !MatchAny(f.Exclude, iface)
+}
+
+// Sum складывает счётчики подходящих интерфейсов и возвращает их имена по алфавиту
+func (f Filter) Sum(all map[string]Counters) (c Counters, matched []string) {
+	for iface, ic := range all {
+		if !f.Match(iface) {
+			continue
+		}
+		c.Rx += ic.Rx
+		c.Tx += ic.Tx
+		matched = append(matched, iface)
+	}
+	sort.Strings(matched)
+	return c, matched
+}
+
+// Aggregate — Sum с учётом топологии: член bond/bridge не суммируется, если под фильтр
+// подходит и его master, иначе трафик посчитан дважды. members — такие исключённые члены.
+// С пустой topo работает как Sum
+func (f Filter) Aggregate(all map[string]Counters, topo Topology) (c Counters, matched, members []string) {
+	for iface, ic := range all {
+		if !f.Match(iface) {
+			continue
+		}
+		if f.aggregatedAbove(iface, all, topo) {
+			members = append(members, iface)
+			continue
+		}
+		c.Rx += ic.Rx
+		c.Tx += ic.Tx
+		matched = append(matched, iface)
+	}
+	sort.Strings(matched)
+	sort.Strings(members)
+	return c, matched, members
+}
+
+// aggregatedAbove: учтён ли уже кто-то из master-ов интерфейса (eno1 → bond0 → br0)
+func (f Filter) aggregatedAbove(iface string, all map[string]Counters, topo Topology) bool {
+	for depth, m := 0, topo[iface].Master; m != "" && depth < 8; depth, m = depth+1, topo[m].Master {
+		if _, ok := all[m]; ok && f.Match(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// Names — имена интерфейсов по алфавиту (для сообщений об ошибках конфигурации)
+func Names(all map[string]Counters) []string {
+	names := make([]string, 0, len(all))
+	for iface := range all {
+		names = append(names, iface)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// MatchAny — подходит ли имя хотя бы под одну маску filepath.Match
+func MatchAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := filepath.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
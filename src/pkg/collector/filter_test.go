@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter Filter
+		iface  string
+		want   bool
+	}{
+		{"default uplink", Filter{}, "eno1", true},
+		{"default loopback", Filter{}, "lo", false},
+		{"default veth", Filter{}, "veth1234", false},
+		{"include", Filter{Include: []string{"bond*"}}, "bond0", true},
+		{"include misses", Filter{Include: []string{"bond*"}}, "eno1", false},
+		{"exclude", Filter{Exclude: []string{"enp0s*"}}, "enp0s3", false},
+		{"include and exclude", Filter{Include: []string{"eth*"}, Exclude: []string{"eth1"}}, "eth1", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter.Match(tt.iface); got != tt.want {
+				t.Errorf("Match(%s) = %v, want %v", tt.iface, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterAggregate(t *testing.T) {
+	all := map[string]Counters{
+		"eno1":  {Rx: 10, Tx: 1},
+		"eno2":  {Rx: 20, Tx: 2},
+		"bond0": {Rx: 30, Tx: 3},
+		"br0":   {Rx: 30, Tx: 3},
+		"enp9":  {Rx: 5, Tx: 5},
+		"lo":    {Rx: 100, Tx: 100},
+	}
+	topo := Topology{"eno1": {Master: "bond0"}, "eno2": {Master: "bond0"}, "bond0": {Master: "br0"}}
+	tests := []struct {
+		name             string
+		filter           Filter
+		topo             Topology
+		want             Counters
+		matched, members []string
+	}{
+		{"no topology", Filter{}, nil, Counters{Rx: 35, Tx: 8}, []string{"eno1", "eno2", "enp9"}, nil},
+		{"bond members", Filter{Include: []string{"en*", "bond*"}}, topo, Counters{Rx: 35, Tx: 8}, []string{"bond0", "enp9"}, []string{"eno1", "eno2"}},
+		{"bridge over bond", Filter{Include: []string{"en*", "bond*", "br*"}}, topo, Counters{Rx: 35, Tx: 8}, []string{"br0", "enp9"}, []string{"bond0", "eno1", "eno2"}},
+		{"master excluded", Filter{Include: []string{"en*"}}, topo, Counters{Rx: 35, Tx: 8}, []string{"eno1", "eno2", "enp9"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, matched, members := tt.filter.Aggregate(all, tt.topo)
+			if c != tt.want || !slices.Equal(matched, tt.matched) || !slices.Equal(members, tt.members) {
+				t.Errorf("got %+v %v %v, want %+v %v %v", c, matched, members, tt.want, tt.matched, tt.members)
+			}
+		})
+	}
+}
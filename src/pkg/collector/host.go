@@ -0,0 +1,95 @@
+package collector
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	DefaultProcNetRoute    = "/proc/net/route"
+	DefaultPowerSupplyPath = "/sys/class/power_supply"
+	DefaultBootIDPath      = "/proc/sys/kernel/random/boot_id"
+)
+
+// Uptime — время с загрузки по CLOCK_BOOTTIME (монотонные часы, учитывающие сон)
+func Uptime() (time.Duration, error) {
+	var ts unix.Timespec
+	if err := unix.ClockGettime(unix.CLOCK_BOOTTIME, &ts); err != nil {
+		return 0, err
+	}
+	return time.Duration(ts.Nano()), nil
+}
+
+// BootID — идентификатор текущей загрузки ядра
+func BootID(path string) (string, error) {
+	if path == "" {
+		path = DefaultBootIDPath
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// DefaultRouteIface возвращает интерфейс маршрута по умолчанию с наименьшей метрикой
+func DefaultRouteIface(path string) (string, error) {
+	if path == "" {
+		path = DefaultProcNetRoute
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	best, bestMetric := "", uint64(0)
+	for lineNum := 0; sc.Scan(); lineNum++ {
+		fields := strings.Fields(sc.Text())
+		if lineNum == 0 || len(fields) < 8 {
+			continue
+		}
+		// Destination и Mask нулевые — маршрут по умолчанию
+		if fields[1] != "00000000" || fields[7] != "00000000" {
+			continue
+		}
+		metric, err := strconv.ParseUint(fields[6], 10, 32)
+		if err != nil {
+			continue
+		}
+		if best == "" || metric < bestMetric {
+			best, bestMetric = fields[0], metric
+		}
+	}
+	return best, sc.Err()
+}
+
+// OnBattery: есть батарея в состоянии Discharging, либо все внешние источники offline
+func OnBattery(path string) bool {
+	if path == "" {
+		path = DefaultPowerSupplyPath
+	}
+	dirs, _ := filepath.Glob(filepath.Join(path, "*"))
+	var mains, mainsOnline, discharging bool
+	for _, d := range dirs {
+		switch readSysfs(filepath.Join(d, "type")) {
+		case "Battery":
+			if readSysfs(filepath.Join(d, "status")) == "Discharging" {
+				discharging = true
+			}
+		case "Mains", "USB":
+			mains = true
+			if readSysfs(filepath.Join(d, "online")) == "1" {
+				mainsOnline = true
+			}
+		}
+	}
+	return discharging || (mains && !mainsOnline)
+}
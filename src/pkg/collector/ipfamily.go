@@ -0,0 +1,76 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+const (
+	DefaultProcNetNetstat = "/proc/net/netstat"
+	DefaultProcNetSnmp6   = "/proc/net/snmp6"
+)
+
+// FamilyCounters — байты по семействам IP; счётчики ядра общие на хост (включая lo)
+type FamilyCounters struct{ V4, V6 Counters }
+
+// ReadFamilyCounters читает IpExt InOctets/OutOctets из netstatPath
+// и Ip6InOctets/Ip6OutOctets из snmp6Path; пустые пути — значения по умолчанию
+func ReadFamilyCounters(netstatPath, snmp6Path string) (c FamilyCounters, err error) {
+	if netstatPath == "" {
+		netstatPath = DefaultProcNetNetstat
+	}
+	if snmp6Path == "" {
+		snmp6Path = DefaultProcNetSnmp6
+	}
+	if c.V4, err = readIPExtOctets(netstatPath); err != nil {
+		return c, err
+	}
+	// без IPv6 в ядре snmp6 нет — это не ошибка, просто нули
+	if _, statErr := os.Stat(snmp6Path); statErr != nil {
+		return c, nil
+	}
+	c.V6, err = readSnmp6Octets(snmp6Path)
+	return c, err
+}
+
+func readIPExtOctets(path string) (c Counters, err error) {
+	vals, err := readHeaderedSection(path, "IpExt:")
+	if err != nil {
+		return c, err
+	}
+	rx, err1 := strconv.ParseUint(vals["InOctets"], 10, 64)
+	tx, err2 := strconv.ParseUint(vals["OutOctets"], 10, 64)
+	if err1 != nil || err2 != nil {
+		return c, fmt.Errorf("%s: IpExt InOctets/OutOctets not found", path)
+	}
+	return Counters{Rx: rx, Tx: tx}, nil
+}
+
+func readSnmp6Octets(path string) (c Counters, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return c, err
+	}
+	defer f.Close()
+
+	sc := bufio.NewScanner(f)
+	for sc.Scan() {
+		fields := strings.Fields(sc.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		switch fields[0] {
+		case "Ip6InOctets":
+			c.Rx, err = strconv.ParseUint(fields[1], 10, 64)
+		case "Ip6OutOctets":
+			c.Tx, err = strconv.ParseUint(fields[1], 10, 64)
+		}
+		if err != nil {
+			return c, fmt.Errorf("%s: parse %s: %w", path, fields[0], err)
+		}
+	}
+	return c, sc.Err()
+}
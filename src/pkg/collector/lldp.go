@@ -0,0 +1,293 @@
+package collector
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	ethPLLDP = 0x88cc
+	// кадры 802.3 с LLC (CDP приходит так, без EtherType)
+	ethP8022 = 0x0004
+)
+
+var (
+	// групповой адрес nearest bridge, на который коммутаторы шлют LLDP
+	lldpMulticast = []byte{0x01, 0x80, 0xc2, 0x00, 0x00, 0x0e}
+	cdpMulticast  = []byte{0x01, 0x00, 0x0c, 0xcc, 0xcc, 0xcc}
+	// LLC/SNAP: DSAP, SSAP, control, OUI Cisco, протокол CDP
+	cdpSNAP = []byte{0xaa, 0xaa, 0x03, 0x00, 0x00, 0x0c, 0x20, 0x00}
+)
+
+// TLV LLDP (IEEE 802.1AB)
+const (
+	tlvEnd        = 0
+	tlvChassisID  = 1
+	tlvPortID     = 2
+	tlvTTL        = 3
+	tlvPortDesc   = 4
+	tlvSystemName = 5
+)
+
+// TLV CDP
+const (
+	cdpDeviceID = 1
+	cdpPortID   = 3
+)
+
+// Neighbor — сосед по LLDP или CDP на одном интерфейсе
+type Neighbor struct {
+	Protocol   string // lldp, cdp
+	ChassisID  string
+	PortID     string
+	PortDesc   string
+	SystemName string
+	expires    time.Time
+}
+
+// Label — "коммутатор/порт" для подписи интерфейса
+func (n Neighbor) Label() string {
+	sys := n.SystemName
+	if sys == "" {
+		sys = n.ChassisID
+	}
+	port := n.PortID
+	if port == "" {
+		port = n.PortDesc
+	}
+	return sys + "/" + port
+}
+
+// LLDP слушает кадры LLDP (и, если включено, CDP) на всех интерфейсах и помнит соседей
+// до истечения их TTL. Нужен CAP_NET_RAW; сетевые карты, которые сами обрабатывают LLDP
+// (агент в прошивке), кадры не отдают
+type LLDP struct {
+	mu        sync.Mutex
+	neighbors map[string]Neighbor
+	sockets   []discoverySocket
+}
+
+type discoverySocket struct {
+	fd        int
+	multicast []byte
+	parse     func(frame []byte) (Neighbor, uint16, bool)
+}
+
+// ListenLLDP открывает сокеты AF_PACKET и подписывает интерфейсы на групповые адреса LLDP
+// и, с cdp, CDP
+func ListenLLDP(cdp bool) (*LLDP, error) {
+	l := &LLDP{neighbors: make(map[string]Neighbor)}
+	fd, err := openDiscovery(ethPLLDP)
+	if err != nil {
+		return nil, fmt.Errorf("lldp socket: %w", err)
+	}
+	l.sockets = append(l.sockets, discoverySocket{fd: fd, multicast: lldpMulticast, parse: func(f []byte) (Neighbor, uint16, bool) {
+		return parseLLDP(f[14:])
+	}})
+	if cdp {
+		if fd, err = openDiscovery(ethP8022); err != nil {
+			unix.Close(l.sockets[0].fd)
+			return nil, fmt.Errorf("cdp socket: %w", err)
+		}
+		l.sockets = append(l.sockets, discoverySocket{fd: fd, multicast: cdpMulticast, parse: parseCDPFrame})
+	}
+	l.join()
+	return l, nil
+}
+
+func openDiscovery(proto uint16) (int, error) {
+	fd, err := unix.Socket(unix.AF_PACKET, unix.SOCK_RAW|unix.SOCK_CLOEXEC, int(htons(proto)))
+	if err != nil {
+		return -1, err
+	}
+	// таймаут чтения, чтобы Run замечал отмену контекста
+	tv := unix.Timeval{Sec: 1}
+	if err := unix.SetsockoptTimeval(fd, unix.SOL_SOCKET, unix.SO_RCVTIMEO, &tv); err != nil {
+		unix.Close(fd)
+		return -1, err
+	}
+	return fd, nil
+}
+
+// join подписывает на групповые адреса все интерфейсы с MAC-адресом; повторная подписка безвредна
+func (l *LLDP) join() {
+	ifs, err := net.Interfaces()
+	if err != nil {
+		return
+	}
+	for _, ifc := range ifs {
+		if len(ifc.HardwareAddr) != 6 {
+			continue
+		}
+		for _, s := range l.sockets {
+			mreq := unix.PacketMreq{Ifindex: int32(ifc.Index), Type: unix.PACKET_MR_MULTICAST, Alen: 6}
+			copy(mreq.Address[:], s.multicast)
+			unix.SetsockoptPacketMreq(s.fd, unix.SOL_PACKET, unix.PACKET_ADD_MEMBERSHIP, &mreq)
+		}
+	}
+}
+
+// Run принимает кадры до отмены ctx и закрывает сокеты
+func (l *LLDP) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, s := range l.sockets[1:] {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			l.receive(ctx, s, false)
+		}()
+	}
+	l.receive(ctx, l.sockets[0], true)
+	wg.Wait()
+}
+
+// receive читает кадры одного сокета; с rejoin он же раз в минуту подписывает новые
+// интерфейсы (например, поднятые после старта)
+func (l *LLDP) receive(ctx context.Context, s discoverySocket, rejoin bool) {
+	defer unix.Close(s.fd)
+	buf := make([]byte, 9216)
+	lastJoin := time.Now()
+	for ctx.Err() == nil {
+		if rejoin && time.Since(lastJoin) >= time.Minute {
+			l.join()
+			lastJoin = time.Now()
+		}
+		n, from, err := unix.Recvfrom(s.fd, buf, 0)
+		if err != nil {
+			if err != unix.EAGAIN && err != unix.EINTR {
+				time.Sleep(time.Second)
+			}
+			continue
+		}
+		sll, ok := from.(*unix.SockaddrLinklayer)
+		// свои исходящие кадры (если на узле работает lldpd) не считаем
+		if !ok || sll.Pkttype == unix.PACKET_OUTGOING || n < 14 {
+			continue
+		}
+		ifc, err := net.InterfaceByIndex(sll.Ifindex)
+		if err != nil {
+			continue
+		}
+		nb, ttl, ok := s.parse(buf[:n])
+		if !ok {
+			continue
+		}
+		l.mu.Lock()
+		if ttl == 0 {
+			delete(l.neighbors, ifc.Name) // сосед уходит (shutdown LLDPDU)
+		} else {
+			nb.expires = time.Now().Add(time.Duration(ttl) * time.Second)
+			l.neighbors[ifc.Name] = nb
+		}
+		l.mu.Unlock()
+	}
+}
+
+// Neighbors — живые соседи по имени интерфейса
+func (l *LLDP) Neighbors() map[string]Neighbor {
+	now := time.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	out := make(map[string]Neighbor, len(l.neighbors))
+	for iface, nb := range l.neighbors {
+		if now.Before(nb.expires) {
+			out[iface] = nb
+		}
+	}
+	return out
+}
+
+// parseLLDP разбирает LLDPDU (после Ethernet-заголовка); обязательны Chassis ID, Port ID и TTL
+func parseLLDP(b []byte) (nb Neighbor, ttl uint16, ok bool) {
+	var seen int
+	for len(b) >= 2 {
+		h := binary.BigEndian.Uint16(b)
+		typ, l := int(h>>9), int(h&0x1ff)
+		if len(b) < 2+l {
+			return nb, 0, false
+		}
+		v := b[2 : 2+l]
+		b = b[2+l:]
+		switch typ {
+		case tlvEnd:
+			return nb, ttl, seen == 3
+		case tlvChassisID:
+			nb.Protocol = "lldp"
+			nb.ChassisID, seen = lldpID(v, 4), seen+1
+		case tlvPortID:
+			nb.PortID, seen = lldpID(v, 3), seen+1
+		case tlvTTL:
+			if len(v) < 2 {
+				return nb, 0, false
+			}
+			ttl, seen = binary.BigEndian.Uint16(v), seen+1
+		case tlvPortDesc:
+			nb.PortDesc = printable(v)
+		case tlvSystemName:
+			nb.SystemName = printable(v)
+		}
+	}
+	return nb, ttl, seen == 3
+}
+
+// parseCDPFrame разбирает кадр 802.3 с LLC/SNAP и CDP (версии 1 и 2): Device ID, Port ID
+// и TTL в заголовке; остальные TLV (адреса, платформа, VLAN) пропускаем
+func parseCDPFrame(f []byte) (nb Neighbor, ttl uint16, ok bool) {
+	if len(f) < 14+len(cdpSNAP)+4 || !bytes.Equal(f[:6], cdpMulticast) || !bytes.Equal(f[14:14+len(cdpSNAP)], cdpSNAP) {
+		return nb, 0, false
+	}
+	b := f[14+len(cdpSNAP):]
+	if v := b[0]; v != 1 && v != 2 {
+		return nb, 0, false
+	}
+	ttl = uint16(b[1])
+	nb.Protocol = "cdp"
+	for b = b[4:]; len(b) >= 4; {
+		typ, l := binary.BigEndian.Uint16(b), int(binary.BigEndian.Uint16(b[2:]))
+		if l < 4 || len(b) < l {
+			return nb, 0, false
+		}
+		v := b[4:l]
+		b = b[l:]
+		switch typ {
+		case cdpDeviceID:
+			nb.ChassisID = printable(v)
+			nb.SystemName = nb.ChassisID
+		case cdpPortID:
+			nb.PortID = printable(v)
+		}
+	}
+	return nb, ttl, nb.ChassisID != "" && nb.PortID != ""
+}
+
+// lldpID: первый байт — подтип; подтип MAC-адреса (4 у chassis, 3 у port) печатаем как MAC
+func lldpID(v []byte, macSubtype byte) string {
+	if len(v) < 2 {
+		return ""
+	}
+	if len(v) == 7 && v[0] == macSubtype {
+		return net.HardwareAddr(v[1:]).String()
+	}
+	return printable(v[1:])
+}
+
+func printable(v []byte) string {
+	return strings.TrimSpace(strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, string(v)))
+}
+
+func htons(v uint16) uint16 {
+	return v<<8 | v>>8
+}
@@ -0,0 +1,70 @@
+package collector
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// DefaultProcNetDev — стандартный путь к счётчикам интерфейсов
+const DefaultProcNetDev = "/proc/net/dev"
+
+// ProcNetDev читает счётчики всех интерфейсов из /proc/net/dev
+type ProcNetDev struct {
+	Path string // пусто — DefaultProcNetDev
+}
+
+func (p ProcNetDev) Read() (map[string]Counters, error) {
+	path := p.Path
+	if path == "" {
+		path = DefaultProcNetDev
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	all := make(map[string]Counters)
+
+	sc := bufio.NewScanner(f)
+	for lineNum := 0; sc.Scan(); lineNum++ {
+		if lineNum < 2 {
+			continue
+		}
+		line := strings.TrimSpace(sc.Text())
+		if line == "" {
+			continue
+		}
+		parts := strings.Split(line, ":")
+		if len(parts) != 2 {
+			continue
+		}
+		iface := strings.TrimSpace(parts[0])
+
+		fields := strings.Fields(parts[1])
+		if len(fields) < 16 {
+			return nil, fmt.Errorf("unexpected format for %s", iface)
+		}
+		rx, err1 := strconv.ParseUint(fields[0], 10, 64) // Receive bytes
+		tx, err2 := strconv.ParseUint(fields[8], 10, 64) // Transmit bytes
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("parse counters failed for %s", iface)
+		}
+		all[iface] = Counters{Rx: rx, Tx: tx}
+	}
+	return all, sc.Err()
+}
+
+// IsUplink: считаем только uplink-и вида en*, всё остальное (lo, cni0, flannel, veth и т.д.) — пропускаем
+func IsUplink(iface string) bool {
+	return iface != "lo" && strings.HasPrefix(iface, "en")
+}
+
+// SumUplinks складывает счётчики uplink-интерфейсов
+func SumUplinks(all map[string]Counters) Counters {
+	c, _ := Filter{}.Sum(all)
+	return c
+}
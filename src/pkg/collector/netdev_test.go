@@ -0,0 +1,78 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const netdevHeader = `Inter-|   Receive                                                |  Transmit
+ face |bytes    packets errs drop fifo frame compressed multicast|bytes    packets errs drop fifo colls carrier compressed
+`
+
+func writeNetDev(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "dev")
+	if err := os.WriteFile(path, []byte(netdevHeader+body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestProcNetDevRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		body    string
+		want    map[string]Counters
+		wantErr bool
+	}{
+		{"interfaces", "    lo: 100 1 0 0 0 0 0 0 100 1 0 0 0 0 0 0\n  eno1: 12345 10 0 0 0 0 0 0 67890 20 0 0 0 0 0 0\n",
+			map[string]Counters{"lo": {Rx: 100, Tx: 100}, "eno1": {Rx: 12345, Tx: 67890}}, false},
+		{"no space after colon", "eno1:1 0 0 0 0 0 0 0 2 0 0 0 0 0 0 0\n", map[string]Counters{"eno1": {Rx: 1, Tx: 2}}, false},
+		{"empty lines", "\n  eno1: 1 0 0 0 0 0 0 0 2 0 0 0 0 0 0 0\n\n", map[string]Counters{"eno1": {Rx: 1, Tx: 2}}, false},
+		{"max counters", "eno1: 18446744073709551615 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0\n", map[string]Counters{"eno1": {Rx: 1<<64 - 1}}, false},
+		{"short line", "eno1: 1 2 3\n", nil, true},
+		{"not a number", "eno1: x 0 0 0 0 0 0 0 2 0 0 0 0 0 0 0\n", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ProcNetDev{Path: writeNetDev(t, tt.body)}.Read()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+			for name, c := range tt.want {
+				if got[name] != c {
+					t.Errorf("%s: got %+v, want %+v", name, got[name], c)
+				}
+			}
+		})
+	}
+}
+
+func TestProcNetDevMissing(t *testing.T) {
+	if _, err := (ProcNetDev{Path: filepath.Join(t.TempDir(), "none")}).Read(); err == nil {
+		t.Fatal("want error for a missing file")
+	}
+}
+
+func TestDelta(t *testing.T) {
+	tests := []struct {
+		cur, prev uint64
+		want      float64
+	}{
+		{10, 3, 7},
+		{5, 5, 0},
+		{3, 10, 0}, // сброс счётчика
+	}
+	for _, tt := range tests {
+		if got := Delta(tt.cur, tt.prev); got != tt.want {
+			t.Errorf("Delta(%d, %d) = %v, want %v", tt.cur, tt.prev, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// смещения rx_bytes/tx_bytes в struct rtnl_link_stats64
+const (
+	stats64RxBytes = 16
+	stats64TxBytes = 24
+)
+
+// NetlinkStats64 читает 64-битные счётчики интерфейсов через rtnetlink (IFLA_STATS64),
+// без разбора текстового /proc/net/dev
+type NetlinkStats64 struct{}
+
+func (NetlinkStats64) Read() (map[string]Counters, error) {
+	rib, err := syscall.NetlinkRIB(syscall.RTM_GETLINK, syscall.AF_UNSPEC)
+	if err != nil {
+		return nil, fmt.Errorf("RTM_GETLINK: %w", err)
+	}
+	msgs, err := syscall.ParseNetlinkMessage(rib)
+	if err != nil {
+		return nil, fmt.Errorf("RTM_GETLINK: %w", err)
+	}
+
+	all := make(map[string]Counters)
+	for i := range msgs {
+		if msgs[i].Header.Type != syscall.RTM_NEWLINK {
+			continue
+		}
+		attrs, err := syscall.ParseNetlinkRouteAttr(&msgs[i])
+		if err != nil {
+			return nil, fmt.Errorf("RTM_NEWLINK: %w", err)
+		}
+		var name string
+		var stats []byte
+		for _, a := range attrs {
+			switch a.Attr.Type {
+			case syscall.IFLA_IFNAME:
+				name = string(bytes.TrimRight(a.Value, "\x00"))
+			case unix.IFLA_STATS64:
+				stats = a.Value
+			}
+		}
+		if name == "" || len(stats) < stats64TxBytes+8 {
+			continue
+		}
+		all[name] = Counters{
+			Rx: binary.NativeEndian.Uint64(stats[stats64RxBytes:]),
+			Tx: binary.NativeEndian.Uint64(stats[stats64TxBytes:]),
+		}
+	}
+	if len(all) == 0 {
+		return nil, fmt.Errorf("RTM_GETLINK: no IFLA_STATS64 in reply")
+	}
+	return all, nil
+}
@@ -0,0 +1,130 @@
+package collector
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Capability — результат проверки одной возможности ядра при старте
+type Capability struct {
+	Name   string
+	OK     bool
+	Detail string
+}
+
+// ProbeFile: возможность есть, если файл читается
+func ProbeFile(name, path string) Capability {
+	if _, err := os.ReadFile(path); err != nil {
+		return Capability{Name: name, Detail: err.Error()}
+	}
+	return Capability{Name: name, OK: true, Detail: path}
+}
+
+// ProbeStats64 проверяет, что rtnetlink отдаёт IFLA_STATS64
+func ProbeStats64() Capability {
+	all, err := NetlinkStats64{}.Read()
+	if err != nil {
+		return Capability{Name: "netlink stats64", Detail: err.Error()}
+	}
+	return Capability{Name: "netlink stats64", OK: true, Detail: strings.Join(Names(all), ",")}
+}
+
+// ProbeConntrackAcct: счётчики байт по соединениям conntrack (nf_conntrack_acct=1)
+func ProbeConntrackAcct(netfilterPath string) Capability {
+	if netfilterPath == "" {
+		netfilterPath = DefaultNetfilterPath
+	}
+	c := Capability{Name: "conntrack accounting"}
+	v, err := os.ReadFile(filepath.Join(netfilterPath, "nf_conntrack_acct"))
+	switch {
+	case err != nil:
+		c.Detail = "nf_conntrack not loaded"
+	case strings.TrimSpace(string(v)) != "1":
+		c.Detail = "disabled (net.netfilter.nf_conntrack_acct=0)"
+	default:
+		c.OK, c.Detail = true, "enabled"
+	}
+	return c
+}
+
+// ProbeTopology читает членство в bond/bridge из sysfs
+func ProbeTopology(sysPath string) Capability {
+	topo, err := ReadTopology(sysPath)
+	if err != nil {
+		return Capability{Name: "bond/bridge topology", Detail: err.Error()}
+	}
+	var masters []string
+	for _, name := range topo.Masters() {
+		masters = append(masters, name+": "+strings.Join(topo.Members(name), ","))
+	}
+	detail := "no bonds or bridges"
+	if len(masters) > 0 {
+		detail = strings.Join(masters, "; ")
+	}
+	return Capability{Name: "bond/bridge topology", OK: true, Detail: detail}
+}
+
+const DefaultProcSelfStatus = "/proc/self/status"
+
+// имена capability по номеру бита (linux/capability.h)
+var capNames = []string{
+	"chown", "dac_override", "dac_read_search", "fowner", "fsetid", "kill", "setgid", "setuid",
+	"setpcap", "linux_immutable", "net_bind_service", "net_broadcast", "net_admin", "net_raw",
+	"ipc_lock", "ipc_owner", "sys_module", "sys_rawio", "sys_chroot", "sys_ptrace", "sys_pacct",
+	"sys_admin", "sys_boot", "sys_nice", "sys_resource", "sys_time", "sys_tty_config", "mknod",
+	"lease", "audit_write", "audit_control", "setfcap", "mac_override", "mac_admin", "syslog",
+	"wake_alarm", "block_suspend", "audit_read", "perfmon", "bpf", "checkpoint_restore",
+}
+
+// ProcessIdentity — под кем работает процесс: реальные uid/gid и действующие capability
+type ProcessIdentity struct {
+	UID, GID     string
+	Capabilities []string
+}
+
+// ReadProcessIdentity разбирает /proc/self/status (Uid, Gid, CapEff)
+func ReadProcessIdentity(path string) (ProcessIdentity, error) {
+	if path == "" {
+		path = DefaultProcSelfStatus
+	}
+	var id ProcessIdentity
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return id, err
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		k, v, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		f := strings.Fields(v)
+		if len(f) == 0 {
+			continue
+		}
+		switch k {
+		case "Uid":
+			id.UID = f[0]
+		case "Gid":
+			id.GID = f[0]
+		case "CapEff":
+			mask, err := strconv.ParseUint(f[0], 16, 64)
+			if err != nil {
+				return id, fmt.Errorf("CapEff: %w", err)
+			}
+			for bit := 0; bit < 64; bit++ {
+				if mask&(1<<bit) == 0 {
+					continue
+				}
+				name := fmt.Sprintf("cap_%d", bit)
+				if bit < len(capNames) {
+					name = "cap_" + capNames[bit]
+				}
+				id.Capabilities = append(id.Capabilities, name)
+			}
+		}
+	}
+	return id, nil
+}
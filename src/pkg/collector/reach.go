@@ -0,0 +1,218 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// соединение держится, пока клиент пишет, но не дольше: порт проверочный, не сервис
+	reachReadTimeout = 2 * time.Second
+	reachReadLimit   = 64 << 10
+	// одновременных соединений на порт; сверх — принять и сразу закрыть, попытка всё равно считается
+	reachMaxConns = 64
+)
+
+// ReachPort — порт проверки входящей доступности
+type ReachPort struct {
+	Proto string // tcp, udp
+	Port  int
+}
+
+func (p ReachPort) String() string { return p.Proto + "/" + strconv.Itoa(p.Port) }
+
+// ParseReachPorts разбирает "tcp/8443,udp/5353,9000" (без протокола — tcp)
+func ParseReachPorts(v string) ([]ReachPort, error) {
+	var out []ReachPort
+	seen := map[ReachPort]bool{}
+	for _, f := range strings.Split(v, ",") {
+		f = strings.TrimSpace(f)
+		if f == "" {
+			continue
+		}
+		proto, port, ok := strings.Cut(f, "/")
+		if !ok {
+			proto, port = "tcp", f
+		}
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 || (proto != "tcp" && proto != "udp") {
+			return nil, fmt.Errorf("invalid port %q, want tcp/PORT or udp/PORT", f)
+		}
+		p := ReachPort{Proto: proto, Port: n}
+		if !seen[p] {
+			seen[p] = true
+			out = append(out, p)
+		}
+	}
+	return out, nil
+}
+
+// ReachCounters — накопительные счётчики порта с запуска. External — попытки не из
+// loopback, частных и link-local сетей: только они доказывают доступность из интернета
+type ReachCounters struct {
+	Attempts     uint64
+	External     uint64
+	Bytes        uint64
+	LastExternal time.Time
+	// пусто — порт слушается
+	Err string
+}
+
+// Reach слушает проверочные порты и считает входящие попытки и байты. Ответа нет:
+// TCP-соединение закрывается после чтения, на UDP агент молчит — усиливать чужие запросы нечем
+type Reach struct {
+	mu       sync.Mutex
+	counters map[ReachPort]*ReachCounters
+	order    []ReachPort
+	tcp      map[ReachPort]net.Listener
+	udp      map[ReachPort]net.PacketConn
+}
+
+// ListenReach открывает порты на addr (пусто — все адреса). Занятый или запрещённый порт
+// не мешает остальным: его ошибка остаётся в счётчиках
+func ListenReach(addr string, ports []ReachPort) *Reach {
+	r := &Reach{
+		counters: make(map[ReachPort]*ReachCounters),
+		order:    ports,
+		tcp:      make(map[ReachPort]net.Listener),
+		udp:      make(map[ReachPort]net.PacketConn),
+	}
+	for _, p := range ports {
+		c := &ReachCounters{}
+		r.counters[p] = c
+		hostPort := net.JoinHostPort(addr, strconv.Itoa(p.Port))
+		var err error
+		if p.Proto == "udp" {
+			var pc net.PacketConn
+			if pc, err = net.ListenPacket("udp", hostPort); err == nil {
+				r.udp[p] = pc
+			}
+		} else {
+			var l net.Listener
+			if l, err = net.Listen("tcp", hostPort); err == nil {
+				r.tcp[p] = l
+			}
+		}
+		if err != nil {
+			c.Err = err.Error()
+		}
+	}
+	return r
+}
+
+// Run принимает соединения и датаграммы до отмены ctx
+func (r *Reach) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for p, l := range r.tcp {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.acceptTCP(p, l)
+		}()
+	}
+	for p, pc := range r.udp {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.readUDP(p, pc)
+		}()
+	}
+	<-ctx.Done()
+	for _, l := range r.tcp {
+		l.Close()
+	}
+	for _, pc := range r.udp {
+		pc.Close()
+	}
+	wg.Wait()
+}
+
+func (r *Reach) acceptTCP(p ReachPort, l net.Listener) {
+	sem := make(chan struct{}, reachMaxConns)
+	for {
+		conn, err := l.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			// EMFILE и подобное: не крутиться вхолостую
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		r.count(p, conn.RemoteAddr(), 0)
+		select {
+		case sem <- struct{}{}:
+		default:
+			conn.Close()
+			continue
+		}
+		go func() {
+			defer func() { <-sem }()
+			defer conn.Close()
+			conn.SetReadDeadline(time.Now().Add(reachReadTimeout))
+			n, _ := io.Copy(io.Discard, io.LimitReader(conn, reachReadLimit))
+			r.count(p, nil, uint64(n))
+		}()
+	}
+}
+
+func (r *Reach) readUDP(p ReachPort, pc net.PacketConn) {
+	buf := make([]byte, 64<<10)
+	for {
+		n, from, err := pc.ReadFrom(buf)
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			continue
+		}
+		r.count(p, from, uint64(n))
+	}
+}
+
+// count: from != nil — новая попытка, иначе только байты уже посчитанного соединения
+func (r *Reach) count(p ReachPort, from net.Addr, n uint64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := r.counters[p]
+	c.Bytes += n
+	if from == nil {
+		return
+	}
+	c.Attempts++
+	if external(from) {
+		c.External++
+		c.LastExternal = time.Now()
+	}
+}
+
+func external(a net.Addr) bool {
+	ap, err := netip.ParseAddrPort(a.String())
+	if err != nil {
+		return false
+	}
+	ip := ap.Addr().Unmap()
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsUnspecified())
+}
+
+// Ports — порты в порядке настройки
+func (r *Reach) Ports() []ReachPort { return r.order }
+
+// Counters — копия счётчиков
+func (r *Reach) Counters() map[ReachPort]ReachCounters {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make(map[ReachPort]ReachCounters, len(r.counters))
+	for p, c := range r.counters {
+		out[p] = *c
+	}
+	return out
+}
@@ -0,0 +1,83 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrReplayEnd — записанные снимки кончились (Replay без Loop)
+var ErrReplayEnd = errors.New("replay: end of recording")
+
+// Replay отдаёт записанные снимки /proc/net/dev из каталога Dir по одному на Read,
+// в порядке имён файлов. С Loop после последнего снимка начинает сначала, продолжая
+// счётчики с того места, где они остановились: на стыке — средний прирост за шаг, а не сброс
+type Replay struct {
+	Dir  string
+	Loop bool
+
+	mu     sync.Mutex
+	files  []string
+	next   int
+	first  map[string]Counters
+	last   map[string]Counters
+	offset map[string]Counters
+}
+
+func (r *Replay) Read() (map[string]Counters, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.files == nil {
+		entries, err := os.ReadDir(r.Dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				r.files = append(r.files, filepath.Join(r.Dir, e.Name()))
+			}
+		}
+		if len(r.files) == 0 {
+			return nil, fmt.Errorf("replay: no snapshots in %s", r.Dir)
+		}
+		sort.Strings(r.files)
+		r.offset = make(map[string]Counters)
+	}
+
+	if r.next == len(r.files) {
+		if !r.Loop {
+			return nil, ErrReplayEnd
+		}
+		steps := uint64(max(len(r.files)-1, 1))
+		for iface, l := range r.last {
+			o, f := r.offset[iface], r.first[iface]
+			rx, tx := l.Rx-min(l.Rx, f.Rx), l.Tx-min(l.Tx, f.Tx)
+			o.Rx += rx + rx/steps
+			o.Tx += tx + tx/steps
+			r.offset[iface] = o
+		}
+		r.next = 0
+	}
+
+	path := r.files[r.next]
+	r.next++
+	snap, err := ProcNetDev{Path: path}.Read()
+	if err != nil {
+		return nil, err
+	}
+	if r.next == 1 {
+		r.first = snap
+	}
+	r.last = snap
+
+	out := make(map[string]Counters, len(snap))
+	for iface, c := range snap {
+		o := r.offset[iface]
+		out[iface] = Counters{Rx: c.Rx + o.Rx, Tx: c.Tx + o.Tx}
+	}
+	return out, nil
+}
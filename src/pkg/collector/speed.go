@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ReadSpeeds — согласованная скорость линка интерфейсов names, Мбит/с, из sysfs <iface>/speed.
+// Интерфейсы без скорости (виртуальные, без линка: ядро отдаёт -1 или EINVAL) пропускаются
+func ReadSpeeds(sysPath string, names []string) map[string]int {
+	if sysPath == "" {
+		sysPath = DefaultSysClassNet
+	}
+	out := make(map[string]int, len(names))
+	for _, n := range names {
+		b, err := os.ReadFile(filepath.Join(sysPath, n, "speed"))
+		if err != nil {
+			continue
+		}
+		if v, err := strconv.Atoi(strings.TrimSpace(string(b))); err == nil && v > 0 {
+			out[n] = v
+		}
+	}
+	return out
+}
@@ -0,0 +1,107 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+const DefaultSysClassNet = "/sys/class/net"
+
+// виды интерфейсов-агрегатов
+const (
+	KindBond   = "bond"
+	KindBridge = "bridge"
+)
+
+// Link — место интерфейса в топологии: Master — bond/bridge, в который он входит;
+// Kind — bond или bridge, если интерфейс сам агрегат
+type Link struct {
+	Master string
+	Kind   string
+}
+
+// Topology — интерфейсы по имени
+type Topology map[string]Link
+
+// ReadTopology читает членство в bond/bridge из sysfs (ссылка master, каталоги bonding, bridge)
+func ReadTopology(sysPath string) (Topology, error) {
+	if sysPath == "" {
+		sysPath = DefaultSysClassNet
+	}
+	entries, err := os.ReadDir(sysPath)
+	if err != nil {
+		return nil, err
+	}
+	topo := make(Topology, len(entries))
+	for _, e := range entries {
+		dir := filepath.Join(sysPath, e.Name())
+		var l Link
+		if target, err := os.Readlink(filepath.Join(dir, "master")); err == nil {
+			l.Master = filepath.Base(target)
+		}
+		switch {
+		case exists(filepath.Join(dir, "bonding")):
+			l.Kind = KindBond
+		case exists(filepath.Join(dir, "bridge")):
+			l.Kind = KindBridge
+		}
+		topo[e.Name()] = l
+	}
+	return topo, nil
+}
+
+// Members — интерфейсы, входящие в master, по алфавиту
+func (t Topology) Members(master string) []string {
+	var out []string
+	for name, l := range t {
+		if l.Master == master {
+			out = append(out, name)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Masters — интерфейсы, в которые кто-то входит, по алфавиту
+func (t Topology) Masters() []string {
+	seen := make(map[string]bool)
+	var out []string
+	for _, l := range t {
+		if l.Master != "" && !seen[l.Master] {
+			seen[l.Master] = true
+			out = append(out, l.Master)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// WithMembers дополняет names членами их bond/bridge (на всю глубину), которые есть в all
+func (t Topology) WithMembers(names []string, all map[string]Counters) []string {
+	seen := make(map[string]bool, len(names))
+	out := make([]string, 0, len(names))
+	var add func(string)
+	add = func(n string) {
+		if seen[n] {
+			return
+		}
+		seen[n] = true
+		out = append(out, n)
+		for _, m := range t.Members(n) {
+			if _, ok := all[m]; ok {
+				add(m)
+			}
+		}
+	}
+	for _, n := range names {
+		add(n)
+	}
+	sort.Strings(out)
+	return out
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,175 @@
+package enrich
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// провайдеры метаданных облака
+const (
+	CloudAuto  = "auto"
+	CloudAWS   = "aws"
+	CloudGCP   = "gcp"
+	CloudAzure = "azure"
+)
+
+const (
+	defaultIMDS        = "http://169.254.169.254"
+	defaultGCPMetadata = "http://metadata.google.internal"
+)
+
+// Cloud — метки из сервиса метаданных экземпляра: cloud_provider, cloud_region, cloud_zone,
+// cloud_instance_type, cloud_instance_id. Provider auto перебирает AWS, GCP и Azure
+// и запоминает первый ответивший
+type Cloud struct {
+	Provider string
+	// BaseURL подменяет адрес сервиса метаданных (для отладки)
+	BaseURL string
+	HTTP    *http.Client
+
+	detected string
+}
+
+func (*Cloud) Name() string { return "cloud" }
+
+func (c *Cloud) Labels(ctx context.Context) (map[string]string, error) {
+	if c.HTTP == nil {
+		c.HTTP = &http.Client{}
+	}
+	provider := c.Provider
+	if provider == "" || provider == CloudAuto {
+		provider = c.detected
+	}
+	if provider != "" {
+		return c.read(ctx, provider)
+	}
+	var errs []error
+	for _, p := range []string{CloudAWS, CloudGCP, CloudAzure} {
+		labels, err := c.read(ctx, p)
+		if err == nil {
+			c.detected = p
+			return labels, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", p, err))
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("no cloud metadata service: %w", errors.Join(errs...))
+}
+
+func (c *Cloud) read(ctx context.Context, provider string) (map[string]string, error) {
+	switch provider {
+	case CloudAWS:
+		return c.aws(ctx)
+	case CloudGCP:
+		return c.gcp(ctx)
+	case CloudAzure:
+		return c.azure(ctx)
+	}
+	return nil, fmt.Errorf("unknown cloud provider %q", provider)
+}
+
+func (c *Cloud) base(def string) string {
+	if c.BaseURL != "" {
+		return strings.TrimRight(c.BaseURL, "/")
+	}
+	return def
+}
+
+func (c *Cloud) fetch(ctx context.Context, method, url string, header map[string]string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return "", err
+	}
+	for k, v := range header {
+		req.Header.Set(k, v)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s %s: status %s", method, url, resp.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// aws — IMDSv2: сначала токен сессии, затем поля placement и экземпляра
+func (c *Cloud) aws(ctx context.Context) (map[string]string, error) {
+	base := c.base(defaultIMDS)
+	token, err := c.fetch(ctx, http.MethodPut, base+"/latest/api/token", map[string]string{"X-aws-ec2-metadata-token-ttl-seconds": "300"})
+	if err != nil {
+		return nil, err
+	}
+	h := map[string]string{"X-aws-ec2-metadata-token": token}
+	labels := map[string]string{"cloud_provider": CloudAWS}
+	for key, path := range map[string]string{
+		"cloud_region":        "placement/region",
+		"cloud_zone":          "placement/availability-zone",
+		"cloud_instance_type": "instance-type",
+		"cloud_instance_id":   "instance-id",
+	} {
+		v, err := c.fetch(ctx, http.MethodGet, base+"/latest/meta-data/"+path, h)
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = v
+	}
+	return labels, nil
+}
+
+// gcp — zone и machine-type приходят полными путями (projects/N/zones/europe-west1-b)
+func (c *Cloud) gcp(ctx context.Context) (map[string]string, error) {
+	base := c.base(defaultGCPMetadata) + "/computeMetadata/v1/instance/"
+	h := map[string]string{"Metadata-Flavor": "Google"}
+	labels := map[string]string{"cloud_provider": CloudGCP}
+	for key, path := range map[string]string{"cloud_zone": "zone", "cloud_instance_type": "machine-type", "cloud_instance_id": "id"} {
+		v, err := c.fetch(ctx, http.MethodGet, base+path, h)
+		if err != nil {
+			return nil, err
+		}
+		labels[key] = v[strings.LastIndexByte(v, '/')+1:]
+	}
+	// регион — зона без последнего суффикса: europe-west1-b → europe-west1
+	if z := labels["cloud_zone"]; strings.Count(z, "-") >= 2 {
+		labels["cloud_region"] = z[:strings.LastIndexByte(z, '-')]
+	}
+	return labels, nil
+}
+
+func (c *Cloud) azure(ctx context.Context) (map[string]string, error) {
+	body, err := c.fetch(ctx, http.MethodGet, c.base(defaultIMDS)+"/metadata/instance/compute?api-version=2021-02-01", map[string]string{"Metadata": "true"})
+	if err != nil {
+		return nil, err
+	}
+	var compute struct {
+		Location string `json:"location"`
+		Zone     string `json:"zone"`
+		VMSize   string `json:"vmSize"`
+		VMID     string `json:"vmId"`
+	}
+	if err := json.Unmarshal([]byte(body), &compute); err != nil {
+		return nil, fmt.Errorf("azure metadata: %w", err)
+	}
+	labels := map[string]string{
+		"cloud_provider":      CloudAzure,
+		"cloud_region":        compute.Location,
+		"cloud_instance_type": compute.VMSize,
+		"cloud_instance_id":   compute.VMID,
+	}
+	if compute.Zone != "" {
+		labels["cloud_zone"] = compute.Location + "-" + compute.Zone
+	}
+	return labels, nil
+}
@@ -0,0 +1,116 @@
+// Package enrich — цепочка источников меток отчёта: статические метки, метаданные облака,
+// метки Node в Kubernetes, внешняя команда. Каждый этап со своим таймаутом и кэшем
+package enrich
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// повтор этапа после ошибки, если его TTL больше
+const errorRetry = time.Minute
+
+// Stage — источник меток
+type Stage interface {
+	Name() string
+	Labels(ctx context.Context) (map[string]string, error)
+}
+
+// Step — этап цепочки с таймаутом и временем жизни результата (TTL 0 — читается один раз)
+type Step struct {
+	Stage   Stage
+	Timeout time.Duration
+	TTL     time.Duration
+
+	mu      sync.Mutex
+	labels  map[string]string
+	next    time.Time
+	loaded  bool
+	running bool
+	lastErr string
+}
+
+// Chain применяет этапы по порядку: при совпадении ключа побеждает более поздний этап
+type Chain struct {
+	Steps []*Step
+}
+
+// Warm читает все этапы синхронно (каждый в пределах своего таймаута), чтобы метки
+// были уже в первом отчёте
+func (c *Chain) Warm(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, s := range c.Steps {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.refresh(ctx)
+		}()
+	}
+	wg.Wait()
+}
+
+// Labels собирает метки из кэшей этапов и не ждёт источников: устаревший этап
+// перечитывается в фоне, до его ответа в отчёт идёт прошлый результат
+func (c *Chain) Labels(ctx context.Context) map[string]string {
+	var out map[string]string
+	now := time.Now()
+	for _, s := range c.Steps {
+		s.mu.Lock()
+		if !s.running && (!s.loaded || !s.next.IsZero()) && !now.Before(s.next) {
+			s.running = true
+			go s.refresh(ctx)
+		}
+		labels := s.labels
+		s.mu.Unlock()
+		for k, v := range labels {
+			if out == nil {
+				out = make(map[string]string)
+			}
+			out[k] = v
+		}
+	}
+	return out
+}
+
+func (s *Step) refresh(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, s.Timeout)
+	defer cancel()
+	labels, err := s.Stage.Labels(ctx)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running, s.loaded = false, true
+	now := time.Now()
+	if err != nil {
+		// прошлый результат остаётся: лучше старые метки, чем отчёт без них
+		retry := errorRetry
+		if s.TTL > 0 {
+			retry = min(retry, s.TTL)
+		}
+		s.next = now.Add(retry)
+		if text := err.Error(); text != s.lastErr {
+			msg.Warnf(msg.EnrichError, s.Stage.Name(), err)
+			s.lastErr = text
+		}
+		return
+	}
+	if s.lastErr != "" {
+		msg.Printf(msg.EnrichRecovered, s.Stage.Name())
+		s.lastErr = ""
+	}
+	s.labels = labels
+	s.next = time.Time{}
+	if s.TTL > 0 {
+		s.next = now.Add(s.TTL)
+	}
+}
+
+// Static — метки из конфигурации (LABELS)
+type Static map[string]string
+
+func (Static) Name() string { return "static" }
+
+func (s Static) Labels(context.Context) (map[string]string, error) { return s, nil }
@@ -0,0 +1,77 @@
+package enrich
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/iflixer/network-stater/src/pkg/kube"
+)
+
+// Kubernetes — выбранные метки объекта Node: topology.kubernetes.io/zone → k8s_zone
+// (имя после последнего "/" с префиксом k8s_), и k8s_node с именем узла
+type Kubernetes struct {
+	Client *kube.Client
+	Node   string
+	Keys   []string
+}
+
+func (*Kubernetes) Name() string { return "kubernetes" }
+
+func (k *Kubernetes) Labels(ctx context.Context) (map[string]string, error) {
+	nodeLabels, err := k.Client.NodeLabels(ctx, k.Node)
+	if err != nil {
+		return nil, err
+	}
+	labels := map[string]string{"k8s_node": k.Node}
+	for _, key := range k.Keys {
+		if v, ok := nodeLabels[key]; ok {
+			labels["k8s_"+key[strings.LastIndexByte(key, '/')+1:]] = v
+		}
+	}
+	return labels, nil
+}
+
+// Exec — метки от внешней команды: JSON-объект строк или строки key=value в stdout
+// (пустые строки и # — пропускаются). Команда выполняется без shell
+type Exec struct {
+	Command []string
+}
+
+func (*Exec) Name() string { return "exec" }
+
+func (e *Exec) Labels(ctx context.Context) (map[string]string, error) {
+	cmd := exec.CommandContext(ctx, e.Command[0], e.Command[1:]...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("%s: %w: %s", e.Command[0], err, msg)
+		}
+		return nil, fmt.Errorf("%s: %w", e.Command[0], err)
+	}
+	out = bytes.TrimSpace(out)
+	labels := make(map[string]string)
+	if len(out) > 0 && out[0] == '{' {
+		if err := json.Unmarshal(out, &labels); err != nil {
+			return nil, fmt.Errorf("%s: %w", e.Command[0], err)
+		}
+		return labels, nil
+	}
+	for _, line := range strings.Split(string(out), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		k, v, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("%s: invalid line %q, want key=value", e.Command[0], line)
+		}
+		labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return labels, nil
+}
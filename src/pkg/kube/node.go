@@ -0,0 +1,178 @@
+// Package kube — минимальный клиент Kubernetes API для публикации нагрузки на объект Node и чтения его меток
+// (in-cluster учётные данные сервис-аккаунта, без client-go)
+package kube
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DefaultServiceAccountDir — куда kubelet монтирует токен и CA сервис-аккаунта
+const DefaultServiceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+
+	tokenPath string
+}
+
+// InCluster собирает клиент из KUBERNETES_SERVICE_HOST/PORT и файлов сервис-аккаунта;
+// saDir пусто — DefaultServiceAccountDir
+func InCluster(saDir string) (*Client, error) {
+	if saDir == "" {
+		saDir = DefaultServiceAccountDir
+	}
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, fmt.Errorf("not running in a cluster: KUBERNETES_SERVICE_HOST/PORT are not set")
+	}
+	ca, err := os.ReadFile(filepath.Join(saDir, "ca.crt"))
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("%s: no certificates found", filepath.Join(saDir, "ca.crt"))
+	}
+	c := &Client{
+		BaseURL: "https://" + net.JoinHostPort(host, port),
+		HTTP: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+		},
+		tokenPath: filepath.Join(saDir, "token"),
+	}
+	// токен проверяем сразу, чтобы ошибка конфигурации была видна при старте
+	if _, err := c.token(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// token перечитывается на каждый запрос: kubelet периодически его ротирует
+func (c *Client) token() (string, error) {
+	if c.tokenPath == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(c.tokenPath)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.TrimSpace(b)), nil
+}
+
+// AnnotateNode выставляет аннотации на Node (merge patch, остальные аннотации не трогаются)
+func (c *Client) AnnotateNode(ctx context.Context, node string, annotations map[string]string) error {
+	patch := map[string]any{"metadata": map[string]any{"annotations": annotations}}
+	return c.patch(ctx, "/api/v1/nodes/"+url.PathEscape(node), "application/merge-patch+json", patch)
+}
+
+// Condition — условие в status.conditions объекта Node
+type Condition struct {
+	Type    string `json:"type"`
+	Status  string `json:"status"` // "True", "False" или "Unknown"
+	Reason  string `json:"reason,omitempty"`
+	Message string `json:"message,omitempty"`
+
+	LastHeartbeatTime  time.Time `json:"lastHeartbeatTime"`
+	LastTransitionTime time.Time `json:"lastTransitionTime"`
+}
+
+// SetNodeCondition обновляет одно условие Node; strategic merge patch сливает
+// conditions по type, так что условия kubelet-а остаются на месте
+func (c *Client) SetNodeCondition(ctx context.Context, node string, cond Condition) error {
+	patch := map[string]any{"status": map[string]any{"conditions": []Condition{cond}}}
+	return c.patch(ctx, "/api/v1/nodes/"+url.PathEscape(node)+"/status", "application/strategic-merge-patch+json", patch)
+}
+
+type nodeMeta struct {
+	Metadata struct {
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+}
+
+// NodeLabels читает metadata.labels объекта Node
+func (c *Client) NodeLabels(ctx context.Context, node string) (map[string]string, error) {
+	var n nodeMeta
+	if err := c.get(ctx, "/api/v1/nodes/"+url.PathEscape(node), &n); err != nil {
+		return nil, err
+	}
+	return n.Metadata.Labels, nil
+}
+
+// NodeAnnotations читает metadata.annotations объекта Node
+func (c *Client) NodeAnnotations(ctx context.Context, node string) (map[string]string, error) {
+	var n nodeMeta
+	if err := c.get(ctx, "/api/v1/nodes/"+url.PathEscape(node), &n); err != nil {
+		return nil, err
+	}
+	return n.Metadata.Annotations, nil
+}
+
+func (c *Client) get(ctx context.Context, path string, v any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("GET %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("GET %s: status %s: %s", path, resp.Status, bytes.TrimSpace(body))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func (c *Client) patch(ctx context.Context, path, contentType string, v any) error {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.BaseURL+path, bytes.NewReader(raw))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	token, err := c.token()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return fmt.Errorf("PATCH %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("PATCH %s: status %s: %s", path, resp.Status, bytes.TrimSpace(body))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return nil
+}
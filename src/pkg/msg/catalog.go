@@ -0,0 +1,177 @@
+package msg
+
+// Каталог: ID — префикс подсистемы и суть сообщения, текст — английский шаблон fmt.
+// Предупреждения записаны без "WARNING: ", его добавляет Warnf
+
+// запуск и настройка
+var (
+	NoDotEnv          = def("config.no_dotenv", "No .env file found")
+	ConfigRenamed     = def("config.renamed", "%s: %s is deprecated, use %s")
+	ConfigDropped     = def("config.dropped", "%s: %s is deprecated and has no effect, remove it (see %s)")
+	ConfigConflict    = def("config.conflict", "%s: %s is deprecated and ignored because %s is set")
+	ConfigFile        = def("config.file", "CONFIG_FILE: %v")
+	ConfigShadowed    = def("config.shadowed", "%s: %s is also set in the environment, the environment wins")
+	SimulatedSource   = def("config.collector_overrides", "COLLECTOR=%s overrides NETDEV_SOURCE=%s: interface counters are synthetic")
+	MessagesLoad      = def("config.messages", "messages: %v; using built-in texts")
+	CapabilitiesTitle = def("capabilities.title", "capabilities:")
+	CapabilityRow     = def("capabilities.row", "  %-20s %-3s %s")
+	CountersSource    = def("capabilities.counters", "interface counters from %s")
+	FeatureMissing    = def("capabilities.missing", "%s disabled: %s unavailable (%s)")
+	ProfileRefused    = def("capabilities.refused", "%s refused by %s profile: needs %s")
+	ExporterError     = def("config.exporter", "exporter: %v")
+)
+
+// bootstrap и enroll
+var (
+	BootstrapSource    = def("bootstrap.source", "bootstrap: configuration from %s")
+	BootstrapIgnored   = def("bootstrap.ignored", "bootstrap: ignoring %s")
+	BootstrapSave      = def("bootstrap.save", "bootstrap: cannot save %s: %v")
+	BootstrapCached    = def("bootstrap.cached", "bootstrap: %v; using saved %s")
+	BootstrapSkipped   = def("bootstrap.skipped", "bootstrap: %v")
+	BootstrapRetry     = def("bootstrap.retry", "bootstrap: %v; retrying in %s")
+	BootstrapApplied   = def("bootstrap.applied", "bootstrap: applied %s")
+	BootstrapKeyError  = def("bootstrap.key_error", "bootstrap: enroll: %v")
+	BootstrapKeyNew    = def("bootstrap.key_generated", "bootstrap: generated %s")
+	BootstrapEnrollErr = def("bootstrap.enroll_error", "bootstrap: enroll at %s: %v")
+	BootstrapMarker    = def("bootstrap.marker", "bootstrap: %v")
+	BootstrapEnrolled  = def("bootstrap.enrolled", "bootstrap: key %s registered at %s")
+	SigningKey         = def("signing.key", "signing: reports signed with key %s")
+	SigningLoad        = def("signing.load", "signing: %v (run `enroll` first)")
+	EncryptionLoad     = def("encryption.load", "encryption: %v")
+	EncryptionKeys     = def("encryption.keys", "encryption: reports sealed for keys %s (%s)")
+	EncryptionReload   = def("encryption.reload", "encryption: keeping previous keys: %v")
+)
+
+// интерфейсы, сбор и группы
+var (
+	InitInterfaces     = def("interfaces.init", "init readInterfaces: %v")
+	InterfacesMatched  = def("interfaces.matched", "interfaces matched again: %s")
+	InterfacesNone     = def("interfaces.none", "no_interfaces_matched include=%q exclude=%q available=%q")
+	InterfacesMembers  = def("interfaces.members", "interfaces: bond/bridge members not counted in totals: %s")
+	TopologyError      = def("interfaces.topology", "readTopology: %v")
+	InitFamilyCounters = def("family.init", "init readFamilyCounters: %v")
+	CollectorBusy      = def("collector.busy", "%s: previous read still running, skipped")
+	CollectorTimeout   = def("collector.timeout", "%s: timed out after %s")
+	BootIDUnavailable  = def("clock.boot_id", "boot_id unavailable: %v")
+	SubnetsDisabled    = def("subnets.disabled", "subnet groups disabled: %v")
+	SubnetsInit        = def("subnets.init", "init readSubnetGroups: %v")
+	SubnetsGroups      = def("subnets.groups", "subnets: accounting %s")
+	PortsDisabled      = def("ports.disabled", "port groups disabled: %v")
+	PortsInit          = def("ports.init", "init readPortGroups: %v")
+	PortsGroups        = def("ports.groups", "ports: accounting %s")
+	ASNDisabled        = def("asn.disabled", "ASN accounting disabled: %v")
+	ASNLoaded          = def("asn.loaded", "asn: %d prefixes of %d ASNs from %s loaded in %s")
+	UpstreamDisabled   = def("upstream.disabled", "upstream accounting disabled: %v")
+	UpstreamPrefixes   = def("upstream.prefixes", "upstreams: %s")
+	UpstreamError      = def("upstream.error", "upstream %s: %v")
+	LLDPDisabled       = def("lldp.disabled", "LLDP disabled: %v")
+	LLDPListening      = def("lldp.listening", "lldp: listening for neighbors (cdp=%t)")
+	LLDPConnected      = def("lldp.connected", "lldp: %s connected to %s (%s)")
+	ReachListening     = def("reach.listening", "reachability: counting inbound attempts on %s")
+	ReachUnavailable   = def("reach.unavailable", "reachability %s: %s")
+	PluginsLoaded      = def("plugins.loaded", "plugins: collecting from %s")
+	PluginsError       = def("plugins.error", "plugins: %v")
+	EnrichStages       = def("enrich.stages", "enrich: labels from %s")
+	EnrichError        = def("enrich.error", "enrich %s: %v")
+	EnrichRecovered    = def("enrich.recovered", "enrich %s: recovered")
+)
+
+// отчёты и доставка
+var (
+	Reporting        = def("report.sent", "reporting: rx=%.1fB/s tx=%.1fB/s%s to %s")
+	ReportingBatch   = def("report.batch", "reporting: batch of %d samples (metered) to %s")
+	QueueFull        = def("report.queue_full", "%s: send queue full or closed, dropping %d samples")
+	SendRetry        = def("report.retry", "%s: attempt %d/%d failed: %v; retrying in %s")
+	SinkAdded        = def("sink.added", "sinks: %s added: %s (%s)")
+	SinkChanged      = def("sink.changed", "sinks: %s now %s (%s), queued reports go to the old target")
+	SinkRemoved      = def("sink.removed", "sinks: %s removed (%s), %d reports left to drain")
+	SinkDrained      = def("sink.drained", "sinks: %s drained, %s released")
+	SinksError       = def("sink.error", "sinks: %v")
+	SinksReload      = def("sink.reload", "sinks: reload: %v")
+	ProxyError       = def("proxy.error", "%s: proxy: %v")
+	ProxyDirect      = def("proxy.direct", "%s: %s directly, no proxy")
+	ProxyVia         = def("proxy.via", "%s: %s via proxy %s")
+	SourceBound      = def("source.bound", "outbound connections from %s, PROXY protocol %s")
+	IntervalChanged  = def("interval.changed", "interval %s -> %s")
+	AdaptiveMode     = def("adaptive.mode", "adaptive: sampling every %s, reporting every %s..%s, faster above %.1fB/s")
+	AdaptiveLink     = def("adaptive.link", "adaptive: sampling every %s, reporting every %s..%s, faster above %.0f%% of link speed (until known: %.1fB/s, 0 = never)")
+	AdaptiveFast     = def("adaptive.fast", "adaptive: %.1fB/s above %.1fB/s, reporting every %s")
+	AdaptiveBackoff  = def("adaptive.backoff", "adaptive: load back below %.1fB/s, backing off to %s")
+	IdleResumed      = def("idle.resumed", "idle: traffic resumed, reporting every interval")
+	IdleHeartbeat    = def("idle.heartbeat", "idle: below %.0fB/s for %d intervals, heartbeat every %s")
+	PowerLow         = def("power.low", "power: low-power mode %v")
+	MeteredUplink    = def("metered.uplink", "metered: metered uplink %v")
+	EventLogged      = def("event.logged", "event %s: %s")
+	EventFlush       = def("event.flush", "%v")
+	EventDropped     = def("event.queue_full", "%s: event queue full or closed, dropping event %s")
+	EventsCorrelated = def("event.correlated", "%d correlated events: %s")
+)
+
+// состояние: месячный учёт, burst, длинные окна, цепочка хешей, локальное хранилище
+var (
+	MonthlyError      = def("monthly.error", "monthly: %v")
+	MonthlySave       = def("monthly.save", "monthly: save %s: %v")
+	MonthlyQuota      = def("monthly.quota", "monthly traffic %s is %.1f%% of quota %d bytes (alert at %.0f%%)")
+	BurstError        = def("burst.error", "burst: %v")
+	BurstSave         = def("burst.save", "burst: save %s: %v")
+	BurstExhausted    = def("burst.exhausted", "burst budget for %s exhausted: %s above %s, further bursts are billed")
+	BurstLow          = def("burst.low", "burst budget for %s is %.0f%% used (%s of %s)")
+	LongWindowsError  = def("longwindows.error", "long windows: %v")
+	LongWindowsSave   = def("longwindows.save", "long windows: save %s: %v")
+	LongWindowsResize = def("longwindows.step_changed", "long windows: %s saved with another LONG_WINDOW_STEP, starting over")
+	ChainError        = def("chain.error", "hash chain: %v")
+	ChainSave         = def("chain.save", "hash chain: save %s: %v")
+	StoreError        = def("store.error", "store: %v")
+	StoreRecovered    = def("store.recovered", "store: writing to %s again")
+	StoreCompacted    = def("store.compacted", "store: removed %d expired and %d over STORE_MAX_BYTES buckets, compressed %d; %s on disk")
+)
+
+// события: резервный канал, соседи, скорость линка, часы, Kubernetes
+var (
+	FailoverActive   = def("failover.active", "traffic moved onto backup link %s: rx=%.1fB/s tx=%.1fB/s")
+	FailoverIdle     = def("failover.idle", "backup link %s idle after %s: rx=%dB tx=%dB")
+	NeighborChange   = def("lldp.neighbor_changed", "%s neighbor changed: %s -> %s")
+	NeighborLost     = def("lldp.neighbor_lost", "%s lost neighbor %s")
+	LinkSpeedChanged = def("link.speed_changed", "%s link speed changed: %d -> %d Mb/s")
+	ClockStep        = def("clock.step", "wall clock stepped by %+.1fs")
+	KubePublishing   = def("kube.publishing", "kube: publishing %s on node %s every %s")
+	KubeCondition    = def("kube.condition", "kube: node %s condition %s -> %s")
+	KubeHighLoad     = def("kube.high_load", "total %.0f bit/s, 5m avg %.0f bit/s, threshold %.0f bit/s")
+	KubeError        = def("kube.error", "kube: %v")
+)
+
+// локальные алерты и выкладки
+var (
+	AlertFiring         = def("alert.firing", "%s: %s %s %s >= %s (%s)")
+	AlertResolved       = def("alert.resolved", "%s: %s %s back to %s")
+	AlertSilenced       = def("alert.silenced", "%s silenced until %s by %s: %s")
+	AlertUnsilenced     = def("alert.unsilenced", "silence %s for %s removed by %s")
+	AlertSilenceExpired = def("alert.silence_expired", "silence %s for %s expired")
+	AlertAcknowledged   = def("alert.acknowledged", "%s: %s %s acknowledged by %s")
+	DeployStarted       = def("deploy.started", "deploy %s on %s until %s (%s, by %s), alerts there silenced")
+	DeployFinished      = def("deploy.finished", "deploy %s on %s ended: %s")
+	DeployWatching      = def("deploy.watching", "deploy: watching %s")
+	DeployError         = def("deploy.error", "deploy: %v")
+)
+
+// API и отладка
+var (
+	APIListening     = def("api.listening", "api: listening on %s")
+	APIError         = def("api.error", "api: %v")
+	APIWriteMetrics  = def("api.write_metrics", "api: write metrics: %v")
+	APIWriteResponse = def("api.write_response", "api: write response: %v")
+	APIThrottled     = def("api.throttled", "api: %d requests over API limits since last report (%d clients tracked)")
+	APIDebugBundle   = def("api.debug_bundle", "api: debug bundle: %v")
+	DebugCapture     = def("debug.capture", "debug log capture: %v")
+)
+
+// серверный режим: сводка по парку и Grafana
+var (
+	ServerListening = def("server.listening", "server: listening on %s")
+	ServerError     = def("server.error", "server: %v")
+	FleetBuilt      = def("server.fleet_digest", "fleet digest %s: %d hosts, %d silent")
+	FleetFailed     = def("server.fleet_failed", "fleet digest %s: %v")
+	FleetDeliver    = def("server.fleet_deliver", "fleet digest %s: %s: %v")
+	GrafanaLoaded   = def("server.grafana", "grafana: dashboard %s provisioned at %s")
+	GrafanaFailed   = def("server.grafana_failed", "grafana: %v; retrying with the next digest")
+)
@@ -0,0 +1,170 @@
+// Package msg — каталог строк журнала и описаний событий агента. Тексты по умолчанию английские
+// и собраны в catalog.go: их грепают и переводят в одном месте, а вызовы ссылаются на ID.
+// Load подменяет шаблоны переводом (MESSAGES_FILE), SetASCII экранирует не-ASCII в выводе —
+// для журналов, которые читают системы без UTF-8
+package msg
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// ID — стабильный ключ сообщения: по нему ищут в журнале и переводят
+type ID string
+
+var (
+	catalog = map[ID]string{}
+
+	mu        sync.RWMutex
+	overrides map[ID]string
+	ascii     bool
+	errOut    io.Writer = os.Stderr
+)
+
+// def регистрирует сообщение с английским текстом по умолчанию
+func def(id, text string) ID {
+	if _, dup := catalog[ID(id)]; dup {
+		panic("msg: duplicate id " + id)
+	}
+	catalog[ID(id)] = text
+	return ID(id)
+}
+
+// Text — сообщение id с аргументами (описания событий, сообщения ошибок)
+func Text(id ID, args ...any) string {
+	mu.RLock()
+	tmpl, ok := overrides[id]
+	esc := ascii
+	mu.RUnlock()
+	if !ok {
+		tmpl = catalog[id]
+	}
+	s := fmt.Sprintf(tmpl, args...)
+	if esc {
+		s = toASCII(s)
+	}
+	return s
+}
+
+// Printf пишет сообщение в журнал
+func Printf(id ID, args ...any) {
+	log.Print(Text(id, args...))
+}
+
+// Warnf — предупреждение: префикс "WARNING: " не переводится, по нему фильтруют журнал
+func Warnf(id ID, args ...any) {
+	log.Print("WARNING: " + Text(id, args...))
+}
+
+// Errorf — ошибка в stderr без метки времени, как прежние fmt.Fprintf(os.Stderr)
+func Errorf(id ID, args ...any) {
+	writeErr(Text(id, args...))
+}
+
+// Error — ошибка, текст которой несёт само err (ошибки доставки, коллекторов)
+func Error(err error) {
+	s := err.Error()
+	mu.RLock()
+	esc := ascii
+	mu.RUnlock()
+	if esc {
+		s = toASCII(s)
+	}
+	writeErr(s)
+}
+
+func writeErr(s string) {
+	mu.RLock()
+	w := errOut
+	mu.RUnlock()
+	fmt.Fprintln(w, s)
+}
+
+// SetErrorOutput направляет ошибки в w (по умолчанию os.Stderr)
+func SetErrorOutput(w io.Writer) {
+	mu.Lock()
+	errOut = w
+	mu.Unlock()
+}
+
+// SetASCII включает экранирование не-ASCII символов (\uXXXX) в выводе
+func SetASCII(on bool) {
+	mu.Lock()
+	ascii = on
+	mu.Unlock()
+}
+
+// Catalog — тексты по умолчанию, заготовка для перевода
+func Catalog() map[ID]string {
+	return maps.Clone(catalog)
+}
+
+// Load подменяет тексты переводом из JSON {"id": "шаблон"}; пустой path — без перевода.
+// Перевод с неизвестным ID или другими аргументами отвергается целиком: битый шаблон
+// в журнале хуже английского
+func Load(path string) error {
+	if path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var tr map[ID]string
+	if err := json.Unmarshal(b, &tr); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	for _, id := range slices.Sorted(maps.Keys(tr)) {
+		orig, ok := catalog[id]
+		if !ok {
+			return fmt.Errorf("%s: unknown message %q", path, id)
+		}
+		if !slices.Equal(verbs(orig), verbs(tr[id])) {
+			return fmt.Errorf("%s: %s: arguments %v do not match %q", path, id, verbs(tr[id]), orig)
+		}
+	}
+	mu.Lock()
+	overrides = tr
+	mu.Unlock()
+	return nil
+}
+
+var verbRe = regexp.MustCompile(`%[-+# 0]*(?:\[\d+\])?(?:\d+|\*)?(?:\.(?:\d+|\*)?)?[a-zA-Z%]`)
+
+// verbs — глаголы шаблона без флагов и ширины, отсортированные: перевод может
+// переставить аргументы (%[2]s), но не поменять их число и типы
+func verbs(tmpl string) []string {
+	var out []string
+	for _, v := range verbRe.FindAllString(tmpl, -1) {
+		if v == "%%" {
+			continue
+		}
+		out = append(out, v[len(v)-1:])
+	}
+	slices.Sort(out)
+	return out
+}
+
+func toASCII(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case r > 0xffff:
+			fmt.Fprintf(&b, `\U%08x`, r)
+		default:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	return b.String()
+}
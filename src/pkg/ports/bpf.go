@@ -0,0 +1,253 @@
+package ports
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/subnets"
+)
+
+// направления в ключе счётчика: group*2 + dir
+const (
+	dirRx = 0 // ingress: локальный порт — получатель
+	dirTx = 1 // egress: локальный порт — источник
+)
+
+// Accounting — загруженные программы и карты; Close отключает их от cgroup
+type Accounting struct {
+	groups   []string // индекс = номер группы в карте, последний — OtherGroup
+	counters *ebpf.Map
+	closers  []interface{ Close() error }
+}
+
+// Open загружает программы учёта и подключает их к cgroup (пусто — subnets.DefaultCgroupPath).
+// Требования к ядру и правам те же, что у subnets: subnets.Supported и subnets.CheckCapabilities
+func Open(groups []Group, cgroupPath string) (a *Accounting, err error) {
+	if cgroupPath == "" {
+		cgroupPath = subnets.DefaultCgroupPath()
+	}
+	if err := subnets.Supported(); err != nil {
+		return nil, err
+	}
+	if err := rlimit.RemoveMemlock(); err != nil {
+		return nil, err
+	}
+
+	a = &Accounting{}
+	defer func() {
+		if err != nil {
+			a.Close()
+		}
+	}()
+
+	total := 0
+	for _, g := range groups {
+		total += len(g.Ports)
+		a.groups = append(a.groups, g.Name)
+	}
+	a.groups = append(a.groups, OtherGroup)
+
+	// ключ — порт в сетевом порядке байт, как он лежит в заголовке
+	portMap, err := ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "ns_ports",
+		Type:       ebpf.Hash,
+		KeySize:    2,
+		ValueSize:  4,
+		MaxEntries: uint32(total),
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, portMap)
+	for i, g := range groups {
+		for _, p := range g.Ports {
+			key := binary.BigEndian.AppendUint16(nil, p)
+			if err := portMap.Put(key, uint32(i)); err != nil {
+				return nil, fmt.Errorf("group %s: port %d: %w", g.Name, p, err)
+			}
+		}
+	}
+
+	a.counters, err = ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "ns_port_bytes",
+		Type:       ebpf.Array,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: uint32(len(a.groups) * 2),
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, a.counters)
+
+	for _, hook := range []struct {
+		dir    int
+		attach ebpf.AttachType
+	}{
+		{dirRx, ebpf.AttachCGroupInetIngress},
+		{dirTx, ebpf.AttachCGroupInetEgress},
+	} {
+		prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+			Name:         "ns_port_account",
+			Type:         ebpf.CGroupSKB,
+			Instructions: program(hook.dir, portMap.FD(), a.counters.FD(), len(a.groups)-1),
+			License:      "GPL",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("load program: %w", err)
+		}
+		a.closers = append(a.closers, prog)
+		l, err := link.AttachCgroup(link.CgroupOptions{Path: cgroupPath, Attach: hook.attach, Program: prog})
+		if err != nil {
+			return nil, fmt.Errorf("attach to %s: %w", cgroupPath, err)
+		}
+		a.closers = append(a.closers, l)
+	}
+	return a, nil
+}
+
+// Groups — имена групп в порядке конфигурации, последним OtherGroup
+func (a *Accounting) Groups() []string {
+	return a.groups
+}
+
+// Read возвращает накопленные байты по группам
+func (a *Accounting) Read() (map[string]collector.Counters, error) {
+	out := make(map[string]collector.Counters, len(a.groups))
+	for i, name := range a.groups {
+		var rx, tx uint64
+		if err := a.counters.Lookup(uint32(i*2+dirRx), &rx); err != nil {
+			return nil, err
+		}
+		if err := a.counters.Lookup(uint32(i*2+dirTx), &tx); err != nil {
+			return nil, err
+		}
+		out[name] = collector.Counters{Rx: rx, Tx: tx}
+	}
+	return out, nil
+}
+
+func (a *Accounting) Close() error {
+	var errs []error
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		errs = append(errs, a.closers[i].Close())
+	}
+	a.closers = nil
+	return errors.Join(errs...)
+}
+
+// program собирает cgroup_skb-программу: длина пакета прибавляется к счётчику группы
+// локального порта, а если он ни в какой группе — удалённого (так исходящие запросы
+// к чужим :443 тоже попадают в свою группу). Не TCP/UDP, фрагменты IPv4 и IPv6 с
+// extension headers идут в other. Пакет всегда пропускается (return 1).
+//
+// Стек: fp-24 — ключ счётчика, fp-16..fp-13 — порты источника и получателя,
+// fp-8..fp-5 — байты заголовка IP
+func program(dir, portsFD, countersFD, other int) asm.Instructions {
+	localOff, remoteOff := int16(-14), int16(-16) // ingress: локальный — порт получателя
+	if dir == dirTx {
+		localOff, remoteOff = -16, -14
+	}
+	loadBytes := func(off int32, fpOff int32, n int32) asm.Instructions {
+		return asm.Instructions{
+			asm.Mov.Reg(asm.R1, asm.R6),
+			asm.Mov.Imm(asm.R2, off),
+			asm.Mov.Reg(asm.R3, asm.RFP),
+			asm.Add.Imm(asm.R3, fpOff),
+			asm.Mov.Imm(asm.R4, n),
+			asm.FnSkbLoadBytes.Call(),
+		}
+	}
+	lookup := func(fpOff int16) asm.Instructions {
+		return asm.Instructions{
+			asm.LoadMapPtr(asm.R1, portsFD),
+			asm.Mov.Reg(asm.R2, asm.RFP),
+			asm.Add.Imm(asm.R2, int32(fpOff)),
+			asm.FnMapLookupElem.Call(),
+		}
+	}
+
+	var insns asm.Instructions
+	insns = append(insns, asm.Mov.Reg(asm.R6, asm.R1))
+
+	// версия IP и длина заголовка IPv4 — первый байт
+	insns = append(insns, loadBytes(0, -8, 1)...)
+	insns = append(insns,
+		asm.JNE.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R7, asm.RFP, -8, asm.Byte),
+		asm.Mov.Reg(asm.R8, asm.R7),
+		asm.RSh.Imm(asm.R8, 4),
+		asm.JEq.Imm(asm.R8, 6, "v6"),
+		asm.JNE.Imm(asm.R8, 4, "other"),
+
+		// IPv4: начало L4 = IHL*4; смещение 6 — флаги/фрагмент, 9 — протокол
+		asm.And.Imm(asm.R7, 0x0f),
+		asm.LSh.Imm(asm.R7, 2),
+	)
+	insns = append(insns, loadBytes(6, -8, 4)...)
+	insns = append(insns,
+		asm.JNE.Imm(asm.R0, 0, "other"),
+		// смещение фрагмента (13 бит в сетевом порядке), прочитанное как little-endian u16
+		asm.LoadMem(asm.R1, asm.RFP, -8, asm.Half),
+		asm.JSet.Imm(asm.R1, 0xff1f, "other"),
+		asm.LoadMem(asm.R1, asm.RFP, -5, asm.Byte),
+		asm.JEq.Imm(asm.R1, 6, "ports"),
+		asm.JEq.Imm(asm.R1, 17, "ports"),
+		asm.Ja.Label("other"),
+	)
+
+	// IPv6: next header (смещение 6) сразу TCP/UDP, L4 с 40-го байта
+	v6 := loadBytes(6, -8, 1)
+	v6[0] = v6[0].WithSymbol("v6")
+	insns = append(insns, v6...)
+	insns = append(insns,
+		asm.JNE.Imm(asm.R0, 0, "other"),
+		asm.Mov.Imm(asm.R7, 40),
+		asm.LoadMem(asm.R1, asm.RFP, -8, asm.Byte),
+		asm.JEq.Imm(asm.R1, 6, "ports"),
+		asm.JNE.Imm(asm.R1, 17, "other"),
+	)
+
+	// порты источника и получателя
+	insns = append(insns,
+		asm.Mov.Reg(asm.R1, asm.R6).WithSymbol("ports"),
+		asm.Mov.Reg(asm.R2, asm.R7),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -16),
+		asm.Mov.Imm(asm.R4, 4),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "other"),
+	)
+	insns = append(insns, lookup(localOff)...)
+	insns = append(insns, asm.JNE.Imm(asm.R0, 0, "found"))
+	insns = append(insns, lookup(remoteOff)...)
+	insns = append(insns,
+		asm.JEq.Imm(asm.R0, 0, "other"),
+		asm.LoadMem(asm.R8, asm.R0, 0, asm.Word).WithSymbol("found"),
+		asm.Ja.Label("count"),
+
+		asm.Mov.Imm(asm.R8, int32(other)).WithSymbol("other"),
+
+		asm.LSh.Imm(asm.R8, 1).WithSymbol("count"),
+		asm.Add.Imm(asm.R8, int32(dir)),
+		asm.StoreMem(asm.RFP, -24, asm.R8, asm.Word),
+		asm.LoadMapPtr(asm.R1, countersFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -24),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R1, asm.R6, 0, asm.Word), // __sk_buff.len
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+
+		asm.Mov.Imm(asm.R0, 1).WithSymbol("out"),
+		asm.Return(),
+	)
+	return insns
+}
@@ -0,0 +1,85 @@
+// Package ports разносит трафик по группам локальных и удалённых портов TCP/UDP
+// (раздача, приём потоков, фоновый трафик) eBPF-программами cgroup_skb,
+// как subnets — по группам сетей
+package ports
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/iflixer/network-stater/src/pkg/subnets"
+)
+
+// OtherGroup — трафик, не попавший ни в одну группу (в том числе не TCP/UDP)
+const OtherGroup = subnets.OtherGroup
+
+type Group struct {
+	Name  string
+	Ports []uint16
+}
+
+// ParseGroups разбирает "delivery=80,443;ingest=1935,8000-8100"
+func ParseGroups(s string) ([]Group, error) {
+	var groups []Group
+	seen := make(map[string]bool)
+	owner := make(map[uint16]string)
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, list, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid group %q, want name=port[,from-to...]", part)
+		}
+		if name == OtherGroup || seen[name] {
+			return nil, fmt.Errorf("duplicate group name %q", name)
+		}
+		seen[name] = true
+		g := Group{Name: name}
+		for _, r := range strings.Split(list, ",") {
+			if r = strings.TrimSpace(r); r == "" {
+				continue
+			}
+			from, to, err := parseRange(r)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", name, err)
+			}
+			for p := from; ; p++ {
+				if prev, ok := owner[p]; ok {
+					return nil, fmt.Errorf("group %s: port %d already in group %s", name, p, prev)
+				}
+				owner[p] = name
+				g.Ports = append(g.Ports, p)
+				if p == to {
+					break
+				}
+			}
+		}
+		if len(g.Ports) == 0 {
+			return nil, fmt.Errorf("group %s: no ports", name)
+		}
+		groups = append(groups, g)
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no groups configured")
+	}
+	return groups, nil
+}
+
+func parseRange(s string) (from, to uint16, err error) {
+	a, b, isRange := strings.Cut(s, "-")
+	f, err := strconv.ParseUint(strings.TrimSpace(a), 10, 16)
+	if err != nil || f == 0 {
+		return 0, 0, fmt.Errorf("invalid port %q", s)
+	}
+	t := f
+	if isRange {
+		if t, err = strconv.ParseUint(strings.TrimSpace(b), 10, 16); err != nil || t < f {
+			return 0, 0, fmt.Errorf("invalid port range %q", s)
+		}
+	}
+	return uint16(f), uint16(t), nil
+}
@@ -0,0 +1,104 @@
+package reporter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Provenance — звено цепочки хешей отчётов агента (HASH_CHAIN): каждый отчёт несёт хеш
+// предыдущего, и сервер по одним отчётам, без часов и эвристик, видит потерю, повтор
+// или перестановку. Индекс сквозной, с STATE_DIR переживает перезапуск
+type Provenance struct {
+	ChainIndex uint64 `json:"chain_index"`
+	// chain_hash предыдущего отчёта; пусто — начало цепочки
+	ChainPrev string `json:"chain_prev"`
+	ChainHash string `json:"chain_hash"`
+}
+
+// ChainDigest — chain_hash отчёта: SHA-256 (hex) строк через "\n":
+//
+//	nschain1, prev, host, index, window_start, window_end,
+//	interval_seconds, rx_bytes_per_sec, tx_bytes_per_sec
+//
+// Целые — десятичные, дробные — 16 hex-цифр их IEEE 754 binary64: число из JSON
+// разбирается в любом языке в те же биты, а десятичная запись у языков разная
+func ChainDigest(prev string, index uint64, pl Payload) string {
+	fields := []string{
+		"nschain1",
+		prev,
+		pl.Host,
+		strconv.FormatUint(index, 10),
+		strconv.FormatInt(pl.WindowStart, 10),
+		strconv.FormatInt(pl.WindowEnd, 10),
+		floatBits(pl.IntervalSeconds),
+		floatBits(pl.RxBytesPerSec),
+		floatBits(pl.TxBytesPerSec),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+func floatBits(v float64) string { return fmt.Sprintf("%016x", math.Float64bits(v)) }
+
+// Link делает pl звеном index после prev
+func (pl *Payload) Link(prev string, index uint64) {
+	pl.Provenance = &Provenance{ChainIndex: index, ChainPrev: prev, ChainHash: ChainDigest(prev, index, *pl)}
+}
+
+// ошибки ChainVerifier.Check; сообщение уточняет индексы
+var (
+	ErrChainMissing  = errors.New("report has no hash chain")
+	ErrChainTampered = errors.New("chain_hash does not match report")
+	ErrChainGap      = errors.New("reports missing")
+	ErrChainStale    = errors.New("duplicate or reordered report")
+	ErrChainFork     = errors.New("chain_prev does not match previous report")
+	ErrChainRestart  = errors.New("chain restarted")
+)
+
+// ChainVerifier проверяет цепочки отчётов на стороне сервера приёма, по одной на host.
+// Отчёт, принятый с ErrChainGap или ErrChainRestart, продолжает цепочку — это сигнал
+// для учёта, а не отказ; с остальными ошибками состояние не меняется.
+//
+// Состояние — последнее звено каждого агента в памяти процесса; при нескольких репликах
+// приёма его нужно хранить в общем хранилище, а отчёты одного агента обрабатывать по порядку
+type ChainVerifier struct {
+	mu   sync.Mutex
+	last map[string]Provenance
+}
+
+// Check проверяет отчёт и запоминает его звено
+func (v *ChainVerifier) Check(pl Payload) error {
+	p := pl.Provenance
+	if p == nil || p.ChainHash == "" {
+		return ErrChainMissing
+	}
+	if ChainDigest(p.ChainPrev, p.ChainIndex, pl) != p.ChainHash {
+		return fmt.Errorf("%w: index %d", ErrChainTampered, p.ChainIndex)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.last == nil {
+		v.last = make(map[string]Provenance)
+	}
+	last, seen := v.last[pl.Host]
+	var err error
+	switch {
+	case !seen:
+	case p.ChainPrev == "":
+		err = fmt.Errorf("%w at index %d after %d", ErrChainRestart, p.ChainIndex, last.ChainIndex)
+	case p.ChainIndex <= last.ChainIndex:
+		return fmt.Errorf("%w: index %d, last %d", ErrChainStale, p.ChainIndex, last.ChainIndex)
+	case p.ChainIndex > last.ChainIndex+1:
+		err = fmt.Errorf("%w: %d between index %d and %d", ErrChainGap, p.ChainIndex-last.ChainIndex-1, last.ChainIndex, p.ChainIndex)
+	case p.ChainPrev != last.ChainHash:
+		return fmt.Errorf("%w: index %d", ErrChainFork, p.ChainIndex)
+	}
+	v.last[pl.Host] = *p
+	return err
+}
@@ -0,0 +1,128 @@
+package reporter
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/netip"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// версии PROXY protocol
+const (
+	ProxyProtocolV1 = "v1"
+	ProxyProtocolV2 = "v2"
+)
+
+// Source — откуда уходят соединения с отчётами: на многоадресном узле отчёт должен идти
+// через сеть управления, а не через измеряемый аплинк. Нулевое значение — как решит маршрутизация
+type Source struct {
+	// локальный адрес соединения (без порта)
+	Addr netip.Addr
+	// интерфейс (SO_BINDTODEVICE, нужен CAP_NET_RAW): маршрут ищется только через него
+	Device string
+	// PROXY protocol (v1 или v2) в начале каждого соединения: для приёма за TCP-балансировщиком,
+	// который ждёт заголовок. Заголовок получает первый узел — при PROXY_URL это прокси
+	ProxyProtocol string
+	// счётчик собственного трафика агента (OWN_TRAFFIC); nil — не считать
+	Traffic *Traffic
+}
+
+// String — адрес и интерфейс для журнала
+func (s Source) String() string {
+	out := "any address"
+	if s.Addr.IsValid() {
+		out = s.Addr.String()
+	}
+	if s.Device != "" {
+		out += " on " + s.Device
+	}
+	return out
+}
+
+// ValidProxyProtocol — пусто, v1 или v2
+func ValidProxyProtocol(v string) bool {
+	return v == "" || v == ProxyProtocolV1 || v == ProxyProtocolV2
+}
+
+// DialContext — функция установки соединения для http.Transport
+func (s Source) DialContext(timeout time.Duration) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+	if s.Addr.IsValid() {
+		d.LocalAddr = &net.TCPAddr{IP: s.Addr.AsSlice()}
+	}
+	if s.Device != "" {
+		d.Control = func(_, _ string, c syscall.RawConn) error {
+			var serr error
+			if err := c.Control(func(fd uintptr) {
+				serr = unix.SetsockoptString(int(fd), unix.SOL_SOCKET, unix.SO_BINDTODEVICE, s.Device)
+			}); err != nil {
+				return err
+			}
+			if serr != nil {
+				return fmt.Errorf("bind to %s: %w", s.Device, serr)
+			}
+			return nil
+		}
+	}
+	if s.ProxyProtocol == "" && s.Traffic == nil {
+		return d.DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := d.DialContext(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		conn = s.Traffic.Conn(conn)
+		if s.ProxyProtocol == "" {
+			return conn, nil
+		}
+		if dl, ok := ctx.Deadline(); ok {
+			conn.SetWriteDeadline(dl)
+		}
+		_, err = conn.Write(proxyHeader(s.ProxyProtocol, conn.LocalAddr(), conn.RemoteAddr()))
+		conn.SetWriteDeadline(time.Time{})
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("PROXY header to %s: %w", addr, err)
+		}
+		return conn, nil
+	}
+}
+
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// proxyHeader — заголовок PROXY protocol для соединения src -> dst
+func proxyHeader(version string, src, dst net.Addr) []byte {
+	s, _ := netip.ParseAddrPort(src.String())
+	d, _ := netip.ParseAddrPort(dst.String())
+	sa, da := s.Addr().Unmap(), d.Addr().Unmap()
+	v4 := sa.Is4() && da.Is4()
+	if version == ProxyProtocolV1 {
+		if v4 {
+			return fmt.Appendf(nil, "PROXY TCP4 %s %s %d %d\r\n", sa, da, s.Port(), d.Port())
+		}
+		// разные семейства: IPv4-сторона — как ::ffff:a.b.c.d, строка TCP6 должна быть целиком IPv6
+		sa, da = netip.AddrFrom16(sa.As16()), netip.AddrFrom16(da.As16())
+		return fmt.Appendf(nil, "PROXY TCP6 %s %s %d %d\r\n", sa, da, s.Port(), d.Port())
+	}
+	// v2: подпись, PROXY-команда версии 2, TCP поверх IPv4 (0x11) или IPv6 (0x21), длина адресов
+	h := append([]byte{}, proxyV2Signature...)
+	h = append(h, 0x21)
+	if v4 {
+		h = append(h, 0x11, 0, 12)
+		h = append(h, sa.AsSlice()...)
+		h = append(h, da.AsSlice()...)
+	} else {
+		h = append(h, 0x21, 0, 36)
+		s16, d16 := sa.As16(), da.As16()
+		h = append(h, s16[:]...)
+		h = append(h, d16[:]...)
+	}
+	h = binary.BigEndian.AppendUint16(h, s.Port())
+	return binary.BigEndian.AppendUint16(h, d.Port())
+}
@@ -0,0 +1,63 @@
+package reporter
+
+import (
+	"bytes"
+	"net"
+	"testing"
+)
+
+func tcpAddr(ip string, port int) *net.TCPAddr {
+	return &net.TCPAddr{IP: net.ParseIP(ip), Port: port}
+}
+
+func TestProxyHeaderV1(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst *net.TCPAddr
+		want     string
+	}{
+		{"ipv4", tcpAddr("10.0.0.1", 40000), tcpAddr("192.0.2.10", 443), "PROXY TCP4 10.0.0.1 192.0.2.10 40000 443\r\n"},
+		{"ipv6", tcpAddr("2001:db8::1", 40000), tcpAddr("2001:db8::2", 443), "PROXY TCP6 2001:db8::1 2001:db8::2 40000 443\r\n"},
+		{"mapped ipv4", tcpAddr("::ffff:10.0.0.1", 1), tcpAddr("::ffff:10.0.0.2", 2), "PROXY TCP4 10.0.0.1 10.0.0.2 1 2\r\n"},
+		{"mixed", tcpAddr("10.0.0.1", 1), tcpAddr("2001:db8::2", 2), "PROXY TCP6 ::ffff:10.0.0.1 2001:db8::2 1 2\r\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(proxyHeader(ProxyProtocolV1, tt.src, tt.dst)); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyHeaderV2(t *testing.T) {
+	tests := []struct {
+		name     string
+		src, dst *net.TCPAddr
+		family   byte
+		addrLen  int
+	}{
+		{"ipv4", tcpAddr("10.0.0.1", 40000), tcpAddr("192.0.2.10", 443), 0x11, 12},
+		{"ipv6", tcpAddr("2001:db8::1", 40000), tcpAddr("2001:db8::2", 443), 0x21, 36},
+		{"mixed", tcpAddr("10.0.0.1", 40000), tcpAddr("2001:db8::2", 443), 0x21, 36},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := proxyHeader(ProxyProtocolV2, tt.src, tt.dst)
+			if !bytes.HasPrefix(h, proxyV2Signature) {
+				t.Fatalf("no v2 signature: %x", h)
+			}
+			rest := h[len(proxyV2Signature):]
+			if rest[0] != 0x21 || rest[1] != tt.family {
+				t.Errorf("command/family = %#x %#x, want 0x21 %#x", rest[0], rest[1], tt.family)
+			}
+			if n := int(rest[2])<<8 | int(rest[3]); n != tt.addrLen || len(rest) != 4+n {
+				t.Fatalf("address length %d, header %d bytes, want %d", n, len(rest)-4, tt.addrLen)
+			}
+			ports := rest[len(rest)-4:]
+			if sp, dp := int(ports[0])<<8|int(ports[1]), int(ports[2])<<8|int(ports[3]); sp != 40000 || dp != 443 {
+				t.Errorf("ports %d %d, want 40000 443", sp, dp)
+			}
+		})
+	}
+}
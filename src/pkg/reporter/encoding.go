@@ -0,0 +1,60 @@
+package reporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+)
+
+// форматы тела отчёта; новые схемы/кодировки добавляются сюда,
+// а на canary-эндпоинт их можно выкатывать раньше, чем на весь флот
+const (
+	EncodingJSON     = "json"
+	EncodingJSONGzip = "json+gzip"
+)
+
+// Body — закодированное тело запроса с нужными заголовками
+type Body struct {
+	Data            []byte
+	ContentType     string
+	ContentEncoding string
+	// дополнительные заголовки (подпись)
+	Headers map[string]string
+}
+
+func ValidEncoding(enc string) bool {
+	switch enc {
+	case EncodingJSON, EncodingJSONGzip:
+		return true
+	}
+	return false
+}
+
+// Encode кодирует отчёт, пачку отчётов ([]Payload — JSON-массив) или событие
+func Encode(v any, enc string) (Body, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return Body{}, err
+	}
+	switch enc {
+	case EncodingJSON, "":
+		return Body{Data: raw, ContentType: "application/json"}, nil
+	case EncodingJSONGzip:
+		return Gzip(raw)
+	}
+	return Body{}, fmt.Errorf("unknown encoding %q", enc)
+}
+
+// Gzip упаковывает готовый JSON
+func Gzip(raw []byte) (Body, error) {
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(raw); err != nil {
+		return Body{}, err
+	}
+	if err := zw.Close(); err != nil {
+		return Body{}, err
+	}
+	return Body{Data: buf.Bytes(), ContentType: "application/json", ContentEncoding: "gzip"}, nil
+}
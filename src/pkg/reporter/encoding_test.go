@@ -0,0 +1,61 @@
+package reporter
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	pl := Payload{Host: "web-1", Timestamp: 1700000000, RxBytesPerSec: 10}
+	tests := []struct {
+		name     string
+		value    any
+		enc      string
+		encoding string
+		wantErr  bool
+	}{
+		{"json", pl, EncodingJSON, "", false},
+		{"default", pl, "", "", false},
+		{"gzip", pl, EncodingJSONGzip, "gzip", false},
+		{"batch", []Payload{pl, pl}, EncodingJSONGzip, "gzip", false},
+		{"unknown encoding", pl, "msgpack", "", true},
+		{"not marshalable", func() {}, EncodingJSON, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := Encode(tt.value, tt.enc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if body.ContentType != "application/json" || body.ContentEncoding != tt.encoding {
+				t.Errorf("headers %q %q, want application/json %q", body.ContentType, body.ContentEncoding, tt.encoding)
+			}
+			data := body.Data
+			if tt.encoding == "gzip" {
+				zr, err := gzip.NewReader(bytes.NewReader(data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data, err = io.ReadAll(zr); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if !bytes.Contains(data, []byte(`"host":"web-1"`)) {
+				t.Errorf("body %s has no host", data)
+			}
+		})
+	}
+}
+
+func TestValidEncoding(t *testing.T) {
+	for enc, want := range map[string]bool{EncodingJSON: true, EncodingJSONGzip: true, "": false, "gzip": false} {
+		if got := ValidEncoding(enc); got != want {
+			t.Errorf("ValidEncoding(%q) = %v, want %v", enc, got, want)
+		}
+	}
+}
@@ -0,0 +1,369 @@
+package reporter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// конверт: тело отчёта шифруется случайным ключом (AES-256-GCM), а этот ключ — отдельно
+// для каждого ключа сервера (X25519 + HKDF-SHA256), поэтому расшифровать конверт
+// может любой из получателей. На смене ключей в конверте оба: старый и новый
+const (
+	EnvelopeContentType = "application/vnd.netload.envelope+json"
+	// KeyIDsHeader — ключи получателей конверта через запятую: по нему сервер видит,
+	// какие агенты уже знают новый ключ, не разбирая тела
+	KeyIDsHeader = "X-Encryption-Key-Ids"
+	envelopeAlg  = "X25519-HKDF-SHA256-A256GCM"
+	envelopeInfo = "netload-envelope-v1"
+)
+
+// Envelope — зашифрованное тело; []byte в JSON — base64
+type Envelope struct {
+	Version    int                 `json:"v"`
+	Alg        string              `json:"alg"`
+	Ephemeral  []byte              `json:"epk"`
+	Recipients []EnvelopeRecipient `json:"recipients"`
+	Nonce      []byte              `json:"nonce"`
+	Ciphertext []byte              `json:"ciphertext"`
+	// заголовки исходного тела: после расшифровки его разбирают как обычный запрос
+	ContentType     string `json:"content_type"`
+	ContentEncoding string `json:"content_encoding,omitempty"`
+}
+
+// EnvelopeRecipient — ключ содержимого, зашифрованный для ключа сервера KeyID
+type EnvelopeRecipient struct {
+	KeyID string `json:"kid"`
+	Key   []byte `json:"key"`
+}
+
+// aad связывает шифротекст с заголовками конверта: подменить тип или сжатие нельзя
+func (e *Envelope) aad() []byte {
+	return []byte(envelopeInfo + "\n" + base64.StdEncoding.EncodeToString(e.Ephemeral) + "\n" + e.ContentType + "\n" + e.ContentEncoding)
+}
+
+// RecipientKey — открытый ключ сервера; действует с NotBefore до NotAfter (нулевые — без границы)
+type RecipientKey struct {
+	ID        string
+	Public    *ecdh.PublicKey
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+func (k RecipientKey) validAt(t time.Time) bool {
+	return (k.NotBefore.IsZero() || !t.Before(k.NotBefore)) && (k.NotAfter.IsZero() || t.Before(k.NotAfter))
+}
+
+// ParseRecipientKeys разбирает файл ключей: строка — "<kid> <открытый ключ base64>
+// [not_before=RFC3339] [not_after=RFC3339]", # — комментарий
+func ParseRecipientKeys(data []byte) ([]RecipientKey, error) {
+	var out []RecipientKey
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		f := strings.Fields(line)
+		if len(f) < 2 {
+			return nil, fmt.Errorf("line %d: want <kid> <public key>", n)
+		}
+		raw, err := base64.StdEncoding.DecodeString(f[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, f[0], err)
+		}
+		pub, err := ecdh.X25519().NewPublicKey(raw)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %s: %w", n, f[0], err)
+		}
+		if strings.Contains(f[0], ",") {
+			return nil, fmt.Errorf("line %d: key id %q must not contain commas", n, f[0])
+		}
+		k := RecipientKey{ID: f[0], Public: pub}
+		for _, opt := range f[2:] {
+			name, v, _ := strings.Cut(opt, "=")
+			t, err := time.Parse(time.RFC3339, v)
+			switch {
+			case err != nil:
+				return nil, fmt.Errorf("line %d: %s: %s: %w", n, k.ID, name, err)
+			case name == "not_before":
+				k.NotBefore = t
+			case name == "not_after":
+				k.NotAfter = t
+			default:
+				return nil, fmt.Errorf("line %d: %s: unknown option %q", n, k.ID, name)
+			}
+		}
+		if slices.ContainsFunc(out, func(o RecipientKey) bool { return o.ID == k.ID }) {
+			return nil, fmt.Errorf("line %d: key %s listed twice", n, k.ID)
+		}
+		out = append(out, k)
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	if len(out) == 0 {
+		return nil, errors.New("no keys")
+	}
+	return out, nil
+}
+
+// Encryptor шифрует тела для ключей сервера из файла (ENCRYPTION_KEYS). Файл перечитывается,
+// когда меняется его mtime, — ключи меняются без перезапуска; каждый конверт идёт всем ключам,
+// действующим в момент отправки. Битый файл не заменяет прежние ключи, а без единого действующего
+// ключа отправка — ошибка: отчёт в открытом виде не уйдёт никогда
+type Encryptor struct {
+	path string
+	Now  func() time.Time
+
+	mu      sync.Mutex
+	keys    []RecipientKey
+	mtime   time.Time
+	current string // действующие ключи последней отправки, для журнала
+}
+
+// NewEncryptor читает ключи из path
+func NewEncryptor(path string) (*Encryptor, error) {
+	e := &Encryptor{path: path}
+	if err := e.reload(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Encryptor) reload() error {
+	st, err := os.Stat(e.path)
+	if err != nil {
+		return err
+	}
+	if st.ModTime().Equal(e.mtime) && e.keys != nil {
+		return nil
+	}
+	b, err := os.ReadFile(e.path)
+	if err != nil {
+		return err
+	}
+	keys, err := ParseRecipientKeys(b)
+	if err != nil {
+		// битый файл разбирается снова только после следующей правки, а не на каждой отправке
+		if e.keys != nil {
+			e.mtime = st.ModTime()
+		}
+		return fmt.Errorf("%s: %w", e.path, err)
+	}
+	e.keys, e.mtime = keys, st.ModTime()
+	return nil
+}
+
+func (e *Encryptor) now() time.Time {
+	if e.Now != nil {
+		return e.Now()
+	}
+	return time.Now()
+}
+
+// active — под mu: действующие ключи, новые (по NotBefore) первыми — сервер пробует их по порядку
+func (e *Encryptor) active(now time.Time) []RecipientKey {
+	var out []RecipientKey
+	for _, k := range e.keys {
+		if k.validAt(now) {
+			out = append(out, k)
+		}
+	}
+	slices.SortStableFunc(out, func(a, b RecipientKey) int { return b.NotBefore.Compare(a.NotBefore) })
+	return out
+}
+
+// Seal заменяет тело конвертом
+func (e *Encryptor) Seal(body *Body) error {
+	e.mu.Lock()
+	if err := e.reload(); err != nil {
+		msg.Warnf(msg.EncryptionReload, err)
+	}
+	keys := e.active(e.now())
+	ids := make([]string, len(keys))
+	for i, k := range keys {
+		ids[i] = k.ID
+	}
+	if cur := strings.Join(ids, ","); cur != e.current {
+		e.current = cur
+		if cur != "" {
+			msg.Printf(msg.EncryptionKeys, cur, e.path)
+		}
+	}
+	e.mu.Unlock()
+	if len(keys) == 0 {
+		return fmt.Errorf("encrypt: no key in %s is valid at %s", e.path, e.now().UTC().Format(time.RFC3339))
+	}
+
+	eph, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	env := Envelope{Version: 1, Alg: envelopeAlg, Ephemeral: eph.PublicKey().Bytes(), ContentType: body.ContentType, ContentEncoding: body.ContentEncoding}
+	cek := make([]byte, 32)
+	rand.Read(cek)
+	for _, k := range keys {
+		wrapped, err := wrapKey(eph, k.Public, k.ID, cek)
+		if err != nil {
+			return fmt.Errorf("encrypt for %s: %w", k.ID, err)
+		}
+		env.Recipients = append(env.Recipients, EnvelopeRecipient{KeyID: k.ID, Key: wrapped})
+	}
+	aead, err := newGCM(cek)
+	if err != nil {
+		return err
+	}
+	env.Nonce = make([]byte, aead.NonceSize())
+	rand.Read(env.Nonce)
+	env.Ciphertext = aead.Seal(nil, env.Nonce, body.Data, env.aad())
+
+	data, err := json.Marshal(env)
+	if err != nil {
+		return err
+	}
+	body.Data, body.ContentType, body.ContentEncoding = data, EnvelopeContentType, ""
+	if body.Headers == nil {
+		body.Headers = make(map[string]string)
+	}
+	body.Headers[KeyIDsHeader] = strings.Join(ids, ",")
+	return nil
+}
+
+// wrapKey шифрует ключ содержимого для одного получателя. Ключ обёртки уникален
+// для каждой пары (эфемерный ключ, получатель), поэтому нулевой nonce безопасен
+func wrapKey(eph *ecdh.PrivateKey, pub *ecdh.PublicKey, kid string, cek []byte) ([]byte, error) {
+	aead, err := recipientAEAD(eph, pub, eph.PublicKey(), pub, kid)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nil, make([]byte, aead.NonceSize()), cek, []byte(kid)), nil
+}
+
+// recipientAEAD — AES-GCM на ключе HKDF(X25519(priv, peer), salt=epk||получатель, info=v1||kid);
+// агент и сервер приходят к одному ключу с разных сторон
+func recipientAEAD(priv *ecdh.PrivateKey, peer, ephPub, recipient *ecdh.PublicKey, kid string) (cipher.AEAD, error) {
+	shared, err := priv.ECDH(peer)
+	if err != nil {
+		return nil, err
+	}
+	salt := append(slices.Clone(ephPub.Bytes()), recipient.Bytes()...)
+	return newGCM(hkdfSHA256(shared, salt, []byte(envelopeInfo+"\n"+kid)))
+}
+
+// hkdfSHA256 — HKDF (RFC 5869) на 32 байта, одним блоком expand
+func hkdfSHA256(secret, salt, info []byte) []byte {
+	ext := hmac.New(sha256.New, salt)
+	ext.Write(secret)
+	exp := hmac.New(sha256.New, ext.Sum(nil))
+	exp.Write(info)
+	exp.Write([]byte{1})
+	return exp.Sum(nil)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// GenerateEncryptionKey создаёт пару ключей сервера; закрытый остаётся на сервере приёма
+func GenerateEncryptionKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+// Decrypter открывает конверты на стороне сервера приёма. Ключей может быть несколько:
+// на время смены держат старый и новый, старый убирают, когда в KeyIDsHeader он больше
+// не встречается один и очереди агентов с ним досланы
+type Decrypter struct {
+	mu   sync.RWMutex
+	keys map[string]*ecdh.PrivateKey
+}
+
+// Add добавляет закрытый ключ kid (base64, как печатает encryption-key)
+func (d *Decrypter) Add(kid, private string) error {
+	raw, err := base64.StdEncoding.DecodeString(private)
+	if err != nil {
+		return fmt.Errorf("%s: %w", kid, err)
+	}
+	priv, err := ecdh.X25519().NewPrivateKey(raw)
+	if err != nil {
+		return fmt.Errorf("%s: %w", kid, err)
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.keys == nil {
+		d.keys = make(map[string]*ecdh.PrivateKey)
+	}
+	d.keys[kid] = priv
+	return nil
+}
+
+// Remove убирает ключ kid по окончании смены
+func (d *Decrypter) Remove(kid string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.keys, kid)
+}
+
+// Open расшифровывает конверт первым из своих ключей, перечисленных в нём;
+// возвращает исходное тело и ключ, которым оно открыто
+func (d *Decrypter) Open(data []byte) (Body, string, error) {
+	var env Envelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return Body{}, "", fmt.Errorf("decode envelope: %w", err)
+	}
+	if env.Version != 1 || env.Alg != envelopeAlg {
+		return Body{}, "", fmt.Errorf("unsupported envelope v%d %s", env.Version, env.Alg)
+	}
+	ephPub, err := ecdh.X25519().NewPublicKey(env.Ephemeral)
+	if err != nil {
+		return Body{}, "", fmt.Errorf("envelope epk: %w", err)
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	var kids []string
+	for _, r := range env.Recipients {
+		kids = append(kids, r.KeyID)
+		priv, ok := d.keys[r.KeyID]
+		if !ok {
+			continue
+		}
+		aead, err := recipientAEAD(priv, ephPub, ephPub, priv.PublicKey(), r.KeyID)
+		if err != nil {
+			return Body{}, "", err
+		}
+		cek, err := aead.Open(nil, make([]byte, aead.NonceSize()), r.Key, []byte(r.KeyID))
+		if err != nil {
+			return Body{}, "", fmt.Errorf("unwrap key %s: %w", r.KeyID, err)
+		}
+		content, err := newGCM(cek)
+		if err != nil {
+			return Body{}, "", err
+		}
+		plain, err := content.Open(nil, env.Nonce, env.Ciphertext, env.aad())
+		if err != nil {
+			return Body{}, "", fmt.Errorf("decrypt with %s: %w", r.KeyID, err)
+		}
+		return Body{Data: plain, ContentType: env.ContentType, ContentEncoding: env.ContentEncoding}, r.KeyID, nil
+	}
+	return Body{}, "", fmt.Errorf("no key for recipients %s", strings.Join(kids, ","))
+}
@@ -0,0 +1,121 @@
+package reporter
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encryptionKey(t *testing.T) (public, private string) {
+	t.Helper()
+	k, err := GenerateEncryptionKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(k.PublicKey().Bytes()), base64.StdEncoding.EncodeToString(k.Bytes())
+}
+
+func TestParseRecipientKeys(t *testing.T) {
+	pub, _ := encryptionKey(t)
+	tests := []struct {
+		name    string
+		data    string
+		keys    int
+		wantErr bool
+	}{
+		{"one key", "# server keys\nk1 " + pub + "\n", 1, false},
+		{"rotation window", "k1 " + pub + " not_after=2026-11-01T00:00:00Z\nk2 " + pub + " not_before=2026-10-01T00:00:00Z\n", 2, false},
+		{"empty", "# nothing\n", 0, true},
+		{"no public key", "k1\n", 0, true},
+		{"bad base64", "k1 !!!\n", 0, true},
+		{"duplicate", "k1 " + pub + "\nk1 " + pub + "\n", 0, true},
+		{"comma in id", "k,1 " + pub + "\n", 0, true},
+		{"unknown option", "k1 " + pub + " until=2026-11-01T00:00:00Z\n", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseRecipientKeys([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(keys) != tt.keys {
+				t.Errorf("got %d keys, want %d", len(keys), tt.keys)
+			}
+		})
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	pub1, priv1 := encryptionKey(t)
+	pub2, priv2 := encryptionKey(t)
+	now := time.Date(2026, 10, 14, 0, 0, 0, 0, time.UTC)
+	path := filepath.Join(t.TempDir(), "keys")
+	keys := fmt.Sprintf("old %s not_after=2026-10-20T00:00:00Z\nnew %s not_before=2026-10-10T00:00:00Z\n", pub1, pub2)
+	if err := os.WriteFile(path, []byte(keys), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	enc, err := NewEncryptor(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc.Now = func() time.Time { return now }
+
+	orig, err := Encode(Payload{Host: "web-1"}, EncodingJSONGzip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := orig
+	if err := enc.Seal(&body); err != nil {
+		t.Fatal(err)
+	}
+	if body.ContentType != EnvelopeContentType || body.ContentEncoding != "" || body.Headers[KeyIDsHeader] != "new,old" {
+		t.Fatalf("sealed body: %q %q %v", body.ContentType, body.ContentEncoding, body.Headers)
+	}
+
+	tests := []struct {
+		name    string
+		keys    map[string]string
+		wantKid string
+		wantErr bool
+	}{
+		{"old key only", map[string]string{"old": priv1}, "old", false},
+		{"both keys", map[string]string{"old": priv1, "new": priv2}, "new", false},
+		{"no matching key", map[string]string{"other": priv1}, "", true},
+		{"wrong key under the id", map[string]string{"new": priv1}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var d Decrypter
+			for kid, priv := range tt.keys {
+				if err := d.Add(kid, priv); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, kid, err := d.Open(body.Data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if kid != tt.wantKid || string(got.Data) != string(orig.Data) || got.ContentEncoding != "gzip" || got.ContentType != orig.ContentType {
+				t.Errorf("opened with %s: %q %q, want %s and the original body", kid, got.ContentType, got.ContentEncoding, tt.wantKid)
+			}
+		})
+	}
+
+	// после окончания действия обоих ключей — ошибка, а не открытый текст
+	enc.Now = func() time.Time { return now.AddDate(1, 0, 0) }
+	if err := os.WriteFile(path, []byte("old "+pub1+" not_after=2026-10-20T00:00:00Z\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, now, now.Add(time.Hour))
+	plain := orig
+	if err := enc.Seal(&plain); err == nil || !strings.Contains(err.Error(), "no key") {
+		t.Errorf("expired keys: err = %v", err)
+	}
+}
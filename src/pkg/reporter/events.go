@@ -0,0 +1,184 @@
+package reporter
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// EventBus сразу отправляет событие на Sink (если задан URL)
+// и держит его до следующего отчёта, чтобы оно попало и в поле events.
+// С Correlate события, пришедшие в пределах этого окна после первого, уходят одним
+// событием correlated: несколько признаков одной аварии — одно оповещение
+type EventBus struct {
+	Client    *http.Client
+	Sink      Sink
+	Host      string
+	NodeName  string
+	Signer    *Signer
+	Encryptor *Encryptor
+	Correlate time.Duration
+	// Queue, если задана, отправляет события заданиями с повторами (подписывает тогда она,
+	// Signer не нужен); без неё событие уходит одной попыткой прямо из Emit
+	Queue *Queue
+
+	mu      sync.Mutex
+	pending []Event
+	bundle  []Event
+	timer   *time.Timer
+	// взведённый или уже сработавший таймер окна, чей Flush ещё не закончился
+	timers sync.WaitGroup
+	// после Close окно не открывается: события уходят сразу
+	closed bool
+}
+
+// Emit пишет событие в лог, ставит в очередь и отправляет (с Correlate — по закрытии окна);
+// ошибка — только доставки без Queue
+func (b *EventBus) Emit(ctx context.Context, ev Event) error {
+	if ev.Timestamp == 0 {
+		ev.Timestamp = time.Now().UTC().Unix()
+	}
+	ev.Host, ev.NodeName = b.Host, b.NodeName
+	msg.Printf(msg.EventLogged, ev.Type, ev.Message)
+
+	b.mu.Lock()
+	if b.Correlate > 0 && !b.closed {
+		b.bundle = append(b.bundle, ev)
+		if len(b.bundle) == 1 {
+			b.timers.Add(1)
+			b.timer = time.AfterFunc(b.Correlate, func() {
+				defer b.timers.Done()
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				if err := b.Flush(ctx); err != nil {
+					msg.Warnf(msg.EventFlush, err)
+				}
+			})
+		}
+		b.mu.Unlock()
+		return nil
+	}
+	b.pending = append(b.pending, ev)
+	b.mu.Unlock()
+	return b.send(ctx, ev)
+}
+
+// Flush закрывает окно корреляции: одно событие уходит как есть, несколько — одним correlated
+func (b *EventBus) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	events := b.bundle
+	b.bundle = nil
+	if b.timer != nil {
+		// остановленный до срабатывания таймер свой Done уже не вызовет
+		if b.timer.Stop() {
+			b.timers.Done()
+		}
+		b.timer = nil
+	}
+	if len(events) == 0 {
+		b.mu.Unlock()
+		return nil
+	}
+	ev := events[0]
+	if len(events) > 1 {
+		ev = Correlated(events)
+		msg.Printf(msg.EventLogged, ev.Type, ev.Message)
+	}
+	b.pending = append(b.pending, ev)
+	b.mu.Unlock()
+	return b.send(ctx, ev)
+}
+
+// Close закрывает окно корреляции и ждёт Flush таймера, который успел сработать: после Close
+// таймеры шины в Queue не пишут, и очередь можно закрывать; поздние Emit Queue уже выбросит
+func (b *EventBus) Close(ctx context.Context) error {
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	err := b.Flush(ctx)
+	b.timers.Wait()
+	return err
+}
+
+func (b *EventBus) send(ctx context.Context, ev Event) error {
+	if b.Sink.URL == "" {
+		return nil
+	}
+	if b.Queue != nil {
+		b.Queue.Push(Job{
+			Exporter:  HTTPExporter{Sink: b.Sink, Client: b.Client},
+			Encoding:  b.Sink.Encoding,
+			Value:     ev,
+			Encryptor: b.Encryptor,
+			Done: func(_ time.Duration, err error) {
+				if err != nil {
+					msg.Warnf(msg.EventFlush, err)
+				}
+			},
+		})
+		return nil
+	}
+	body, err := Encode(ev, b.Sink.Encoding)
+	if err != nil {
+		return fmt.Errorf("%s: encode %s: %w", b.Sink.Name, b.Sink.Encoding, err)
+	}
+	if b.Encryptor != nil {
+		if err := b.Encryptor.Seal(&body); err != nil {
+			return fmt.Errorf("%s: %w", b.Sink.Name, err)
+		}
+	}
+	if b.Signer != nil {
+		b.Signer.Sign(&body)
+	}
+	return b.Sink.Post(ctx, b.Client, body)
+}
+
+// Correlated собирает события в одно с общим correlation_id; сами события — в events,
+// к каждому приписан тот же correlation_id
+func Correlated(events []Event) Event {
+	id := make([]byte, 8)
+	rand.Read(id)
+	first := events[0]
+	ev := Event{
+		Type:          "correlated",
+		Timestamp:     first.Timestamp,
+		Host:          first.Host,
+		NodeName:      first.NodeName,
+		Interface:     first.Interface,
+		CorrelationID: hex.EncodeToString(id),
+		Events:        make([]Event, len(events)),
+	}
+	types := make([]string, len(events))
+	for i, e := range events {
+		e.CorrelationID = ev.CorrelationID
+		ev.Events[i], types[i] = e, e.Type
+		// общий интерфейс — только если он у всех один
+		if e.Interface != ev.Interface {
+			ev.Interface = ""
+		}
+	}
+	ev.Message = msg.Text(msg.EventsCorrelated, len(events), strings.Join(types, ", "))
+	ev.Data = map[string]any{
+		"types": types,
+		"first": first.Timestamp,
+		"last":  events[len(events)-1].Timestamp,
+		"count": len(events),
+	}
+	return ev
+}
+
+// Drain забирает события, накопленные с прошлого отчёта
+func (b *EventBus) Drain() []Event {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	out := b.pending
+	b.pending = nil
+	return out
+}
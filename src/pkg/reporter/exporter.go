@@ -0,0 +1,25 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+)
+
+// Exporter — куда уходят закодированные отчёты: HTTP-эндпоинт или шина сообщений
+type Exporter interface {
+	// Name — имя для логов и телеметрии ("report", "canary")
+	Name() string
+	Export(ctx context.Context, body Body) error
+}
+
+// HTTPExporter шлёт отчёты POST-запросом на Sink
+type HTTPExporter struct {
+	Sink   Sink
+	Client *http.Client
+}
+
+func (e HTTPExporter) Name() string { return e.Sink.Name }
+
+func (e HTTPExporter) Export(ctx context.Context, body Body) error {
+	return e.Sink.Post(ctx, e.Client, body)
+}
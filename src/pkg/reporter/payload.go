@@ -0,0 +1,491 @@
+// Package reporter — формат отчёта агента и его доставка по HTTP.
+package reporter
+
+import (
+	"github.com/iflixer/network-stater/src/pkg/collector"
+	"github.com/iflixer/network-stater/src/pkg/sdk"
+)
+
+type Payload struct {
+	Host             string            `json:"host"`
+	NodeName         string            `json:"node_name,omitempty"`
+	Labels           map[string]string `json:"labels,omitempty"`
+	Timestamp        int64             `json:"timestamp"`
+	IntervalSeconds  float64           `json:"interval_seconds"`
+	RxBytesPerSec    float64           `json:"rx_bytes_per_sec"`
+	TxBytesPerSec    float64           `json:"tx_bytes_per_sec"`
+	RxBitsPerSec     float64           `json:"rx_bits_per_sec"`
+	TxBitsPerSec     float64           `json:"tx_bits_per_sec"`
+	TotalBytesPerSec float64           `json:"total_bytes_per_sec"`
+	TotalBitsPerSec  float64           `json:"total_bits_per_sec"`
+
+	// интервал по обоим часам: при скачке системного времени они расходятся,
+	// и бэкенд может восстановить честные скорости по монотонному
+	IntervalWallSeconds      float64 `json:"interval_wall_seconds"`
+	IntervalMonotonicSeconds float64 `json:"interval_monotonic_seconds"`
+	// окно, которое покрывает отчёт: [window_start, window_end) в unix-миллисекундах по стенным
+	// часам. Окна идут встык — начало следующего равно концу этого, даже при дрожании
+	// и переменном интервале; после пропущенного замера окно просто длиннее
+	WindowStart int64 `json:"window_start"`
+	WindowEnd   int64 `json:"window_end"`
+	// номер отчёта с запуска агента (с 1): пропуск — потерянный отчёт, сброс — перезапуск
+	Sequence uint64 `json:"sequence,omitempty"`
+
+	// поля сглаживания встраиваются плоско и пропадают из JSON, если режим выключен
+	*WindowAvg
+	*EWMAAvg
+	*DailyAvg
+	*WeeklyAvg
+	*MonthlyUsage
+	*BurstUsage
+	*IPFamilyRates
+	*collector.ConnStats
+	*Telemetry
+	*ClockInfo
+	*CacheStats
+	*Provenance
+
+	// отчёт снят при работе через лимитный канал
+	Metered     bool              `json:"metered,omitempty"`
+	BackupLinks []BackupLinkUsage `json:"backup_links,omitempty"`
+	// сработавшие локальные правила ALERT_RULES
+	Alerts []ActiveAlert `json:"alerts,omitempty"`
+	// идущие выкладки: смена трафика на их интерфейсах ожидаема
+	Deploys []DeployMark `json:"deploys,omitempty"`
+
+	// трафик по группам удалённых сетей (SUBNET_GROUPS)
+	SubnetGroups []GroupRates `json:"subnet_groups,omitempty"`
+	// трафик по группам портов (PORT_GROUPS)
+	PortGroups []GroupRates `json:"port_groups,omitempty"`
+	// самые нагруженные удалённые ASN (ASN_TABLE)
+	TopASNs []ASNRates `json:"top_asns,omitempty"`
+
+	// скорости по отдельным интерфейсам (INTERFACE_BREAKDOWN), включая членов bond/bridge
+	Interfaces []InterfaceRates `json:"interfaces,omitempty"`
+	// соседи по LLDP/CDP: к какому коммутатору и порту подключён каждый интерфейс
+	Neighbors []LinkNeighbor `json:"neighbors,omitempty"`
+	// LINK_SPEED: скорость линка какого-то интерфейса сменилась за интервал; какой-то
+	// интерфейс сейчас медленнее, чем бывал с запуска агента
+	LinkSpeedChanged bool `json:"link_speed_changed,omitempty"`
+	LinkDownshifted  bool `json:"link_downshifted,omitempty"`
+	// входящие попытки на проверочные порты (REACHABILITY_PORTS)
+	Reachability []ReachPortStats `json:"reachability,omitempty"`
+	// значения своих коллекторов (pkg/sdk), в порядке PLUGINS
+	Plugins []PluginSample `json:"plugins,omitempty"`
+
+	// ни один интерфейс не прошёл фильтр: нулевые скорости ничего не значат
+	NoInterfacesMatched bool `json:"no_interfaces_matched,omitempty"`
+
+	// сколько отчётов перед этим придержано политиками отправки
+	SkippedSamples int `json:"skipped_samples,omitempty"`
+
+	// события с прошлого отчёта
+	Events []Event `json:"events,omitempty"`
+}
+
+// SetRates заполняет мгновенные скорости (байт/с) и производные поля
+func (p *Payload) SetRates(rxBps, txBps float64) {
+	p.RxBytesPerSec = rxBps
+	p.TxBytesPerSec = txBps
+	p.RxBitsPerSec = rxBps * 8
+	p.TxBitsPerSec = txBps * 8
+	p.TotalBytesPerSec = rxBps + txBps
+	p.TotalBitsPerSec = (rxBps + txBps) * 8
+}
+
+// 5-минутное скользящее среднее
+type WindowAvg struct {
+	RxBytesPerSec5m    float64 `json:"rx_bytes_per_sec_5m"`
+	TxBytesPerSec5m    float64 `json:"tx_bytes_per_sec_5m"`
+	TotalBytesPerSec5m float64 `json:"total_bytes_per_sec_5m"`
+	RxBitsPerSec5m     float64 `json:"rx_bits_per_sec_5m"`
+	TxBitsPerSec5m     float64 `json:"tx_bits_per_sec_5m"`
+	TotalBitsPerSec5m  float64 `json:"total_bits_per_sec_5m"`
+}
+
+func NewWindowAvg(rx, tx float64) *WindowAvg {
+	return &WindowAvg{
+		RxBytesPerSec5m:    rx,
+		TxBytesPerSec5m:    tx,
+		TotalBytesPerSec5m: rx + tx,
+		RxBitsPerSec5m:     rx * 8,
+		TxBitsPerSec5m:     tx * 8,
+		TotalBitsPerSec5m:  (rx + tx) * 8,
+	}
+}
+
+// средние за сутки и неделю (LONG_WINDOWS) по минутным корзинам; coverage — сколько секунд
+// окна агент реально видел: после установки или долгого простоя среднее ещё не за всё окно
+type DailyAvg struct {
+	RxBytesPerSec24h    float64 `json:"rx_bytes_per_sec_24h"`
+	TxBytesPerSec24h    float64 `json:"tx_bytes_per_sec_24h"`
+	TotalBytesPerSec24h float64 `json:"total_bytes_per_sec_24h"`
+	RxBitsPerSec24h     float64 `json:"rx_bits_per_sec_24h"`
+	TxBitsPerSec24h     float64 `json:"tx_bits_per_sec_24h"`
+	TotalBitsPerSec24h  float64 `json:"total_bits_per_sec_24h"`
+	CoverageSeconds24h  float64 `json:"coverage_seconds_24h"`
+}
+
+func NewDailyAvg(rx, tx, coverage float64) *DailyAvg {
+	return &DailyAvg{
+		RxBytesPerSec24h:    rx,
+		TxBytesPerSec24h:    tx,
+		TotalBytesPerSec24h: rx + tx,
+		RxBitsPerSec24h:     rx * 8,
+		TxBitsPerSec24h:     tx * 8,
+		TotalBitsPerSec24h:  (rx + tx) * 8,
+		CoverageSeconds24h:  coverage,
+	}
+}
+
+type WeeklyAvg struct {
+	RxBytesPerSec7d    float64 `json:"rx_bytes_per_sec_7d"`
+	TxBytesPerSec7d    float64 `json:"tx_bytes_per_sec_7d"`
+	TotalBytesPerSec7d float64 `json:"total_bytes_per_sec_7d"`
+	RxBitsPerSec7d     float64 `json:"rx_bits_per_sec_7d"`
+	TxBitsPerSec7d     float64 `json:"tx_bits_per_sec_7d"`
+	TotalBitsPerSec7d  float64 `json:"total_bits_per_sec_7d"`
+	CoverageSeconds7d  float64 `json:"coverage_seconds_7d"`
+}
+
+func NewWeeklyAvg(rx, tx, coverage float64) *WeeklyAvg {
+	return &WeeklyAvg{
+		RxBytesPerSec7d:    rx,
+		TxBytesPerSec7d:    tx,
+		TotalBytesPerSec7d: rx + tx,
+		RxBitsPerSec7d:     rx * 8,
+		TxBitsPerSec7d:     tx * 8,
+		TotalBitsPerSec7d:  (rx + tx) * 8,
+		CoverageSeconds7d:  coverage,
+	}
+}
+
+// экспоненциально взвешенное среднее
+type EWMAAvg struct {
+	RxBytesPerSecEWMA    float64 `json:"rx_bytes_per_sec_ewma"`
+	TxBytesPerSecEWMA    float64 `json:"tx_bytes_per_sec_ewma"`
+	TotalBytesPerSecEWMA float64 `json:"total_bytes_per_sec_ewma"`
+	RxBitsPerSecEWMA     float64 `json:"rx_bits_per_sec_ewma"`
+	TxBitsPerSecEWMA     float64 `json:"tx_bits_per_sec_ewma"`
+	TotalBitsPerSecEWMA  float64 `json:"total_bits_per_sec_ewma"`
+}
+
+func NewEWMAAvg(rx, tx float64) *EWMAAvg {
+	return &EWMAAvg{
+		RxBytesPerSecEWMA:    rx,
+		TxBytesPerSecEWMA:    tx,
+		TotalBytesPerSecEWMA: rx + tx,
+		RxBitsPerSecEWMA:     rx * 8,
+		TxBitsPerSecEWMA:     tx * 8,
+		TotalBitsPerSecEWMA:  (rx + tx) * 8,
+	}
+}
+
+// Telemetry — состояние самого агента: где теряются отчёты, при сборе или при доставке
+type Telemetry struct {
+	ConsecutiveReportFailures int     `json:"agent_consecutive_report_failures"`
+	LastReportLatencyMs       float64 `json:"agent_last_report_latency_ms"`
+	SamplesDropped            uint64  `json:"agent_samples_dropped"`
+	CollectorReadErrors       uint64  `json:"agent_collector_read_errors"`
+	UptimeSeconds             float64 `json:"agent_uptime_seconds"`
+	// чтения, не уложившиеся в таймаут коллектора, и длительность последнего чтения каждого
+	CollectorTimeouts    uint64             `json:"agent_collector_timeouts,omitempty"`
+	CollectorDurationsMs map[string]float64 `json:"agent_collector_duration_ms,omitempty"`
+	// собственный трафик агента (OWN_TRAFFIC) за интервал; subtracted — он уже вычтен из rx/tx
+	OwnRxBytesPerSec     float64 `json:"agent_own_rx_bytes_per_sec,omitempty"`
+	OwnTxBytesPerSec     float64 `json:"agent_own_tx_bytes_per_sec,omitempty"`
+	OwnTrafficSubtracted bool    `json:"agent_own_traffic_subtracted,omitempty"`
+}
+
+// ClockInfo — время по нескольким часам, чтобы бэкенд мог заметить и поправить уход часов узла:
+// timestamp_ms - uptime_seconds даёт время загрузки, которое в рамках boot_id не должно меняться
+type ClockInfo struct {
+	TimestampMs   int64   `json:"timestamp_ms"`
+	UptimeSeconds float64 `json:"uptime_seconds"` // CLOCK_BOOTTIME, системное время на него не влияет
+	BootID        string  `json:"boot_id,omitempty"`
+}
+
+// месячный учёт трафика (календарный месяц по UTC)
+type MonthlyUsage struct {
+	Month            string  `json:"month"`
+	MonthRxBytes     uint64  `json:"month_rx_bytes"`
+	MonthTxBytes     uint64  `json:"month_tx_bytes"`
+	MonthProjectedRx uint64  `json:"month_projected_rx_bytes"`
+	MonthProjectedTx uint64  `json:"month_projected_tx_bytes"`
+	QuotaUsedPct     float64 `json:"month_quota_used_pct,omitempty"`
+	QuotaExceeded    bool    `json:"month_quota_exceeded,omitempty"`
+}
+
+// BurstUsage — расход burst-бюджета тарифа «база + burst» за расчётный период (BURST_BASE_BPS)
+type BurstUsage struct {
+	BurstPeriod            string  `json:"burst_period"`
+	BurstBaseBitsPerSec    float64 `json:"burst_base_bits_per_sec"`
+	BurstCeilingBitsPerSec float64 `json:"burst_ceiling_bits_per_sec"`
+	BurstSecondsUsed       float64 `json:"burst_seconds_used"`
+	BurstSecondsAllowed    float64 `json:"burst_seconds_allowed"`
+	BurstSecondsRemaining  float64 `json:"burst_seconds_remaining"`
+	BurstBudgetUsedPct     float64 `json:"burst_budget_used_pct"`
+	// объём сверх базовой скорости в слотах burst
+	BurstExcessBytes uint64 `json:"burst_excess_bytes"`
+	// сколько ещё можно добавить к текущей скорости: до потолка, а без бюджета — до базы
+	BurstHeadroomBitsPerSec float64 `json:"burst_headroom_bits_per_sec"`
+	BurstActive             bool    `json:"burst_active"`
+	BurstExhausted          bool    `json:"burst_exhausted"`
+}
+
+// скорости по семействам IP; счётчики ядра общие на хост (включая lo)
+type IPFamilyRates struct {
+	IPv4RxBytesPerSec float64 `json:"ipv4_rx_bytes_per_sec"`
+	IPv4TxBytesPerSec float64 `json:"ipv4_tx_bytes_per_sec"`
+	IPv4RxBitsPerSec  float64 `json:"ipv4_rx_bits_per_sec"`
+	IPv4TxBitsPerSec  float64 `json:"ipv4_tx_bits_per_sec"`
+	IPv6RxBytesPerSec float64 `json:"ipv6_rx_bytes_per_sec"`
+	IPv6TxBytesPerSec float64 `json:"ipv6_tx_bytes_per_sec"`
+	IPv6RxBitsPerSec  float64 `json:"ipv6_rx_bits_per_sec"`
+	IPv6TxBitsPerSec  float64 `json:"ipv6_tx_bits_per_sec"`
+}
+
+// NewIPFamilyRates считает скорости по приросту счётчиков за sec секунд
+func NewIPFamilyRates(cur, prev collector.FamilyCounters, sec float64) *IPFamilyRates {
+	v4rx, v4tx := collector.Delta(cur.V4.Rx, prev.V4.Rx)/sec, collector.Delta(cur.V4.Tx, prev.V4.Tx)/sec
+	v6rx, v6tx := collector.Delta(cur.V6.Rx, prev.V6.Rx)/sec, collector.Delta(cur.V6.Tx, prev.V6.Tx)/sec
+	return &IPFamilyRates{
+		IPv4RxBytesPerSec: v4rx,
+		IPv4TxBytesPerSec: v4tx,
+		IPv4RxBitsPerSec:  v4rx * 8,
+		IPv4TxBitsPerSec:  v4tx * 8,
+		IPv6RxBytesPerSec: v6rx,
+		IPv6TxBytesPerSec: v6tx,
+		IPv6RxBitsPerSec:  v6rx * 8,
+		IPv6TxBitsPerSec:  v6tx * 8,
+	}
+}
+
+// ActiveAlert — правило, порог которого сейчас превышен на интерфейсе
+type ActiveAlert struct {
+	Rule      string `json:"rule"`
+	Interface string `json:"interface"`
+	Direction string `json:"direction"`
+	Level     string `json:"level"` // warning или critical
+	// скорость, с которой сравнивался порог: мгновенная или средняя за окно
+	BitsPerSec          float64 `json:"bits_per_sec"`
+	ThresholdBitsPerSec float64 `json:"threshold_bits_per_sec"`
+	WindowSeconds       float64 `json:"window_seconds,omitempty"`
+	Since               int64   `json:"since"`
+	// тишина и подтверждение дежурного (API агента /v1/alerts)
+	SilenceID      string `json:"silence_id,omitempty"`
+	SilencedUntil  int64  `json:"silenced_until,omitempty"`
+	Acknowledged   bool   `json:"acknowledged,omitempty"`
+	AcknowledgedBy string `json:"acknowledged_by,omitempty"`
+	// алерт заглушён выкладкой
+	DeployID string `json:"deploy_id,omitempty"`
+}
+
+// PluginSample — значение своего коллектора
+type PluginSample struct {
+	Collector string `json:"collector"`
+	sdk.Sample
+}
+
+// DeployMark — выкладка, идущая во время отчёта
+type DeployMark struct {
+	ID string `json:"id"`
+	// маска интерфейсов, на которых ждут смены трафика
+	Interface string `json:"interface"`
+	Source    string `json:"source"` // file, api или kube
+	Since     int64  `json:"since"`
+	Until     int64  `json:"until"`
+}
+
+// BackupLinkUsage — расход по резервному/лимитному каналу
+type BackupLinkUsage struct {
+	Interface     string  `json:"interface"`
+	Active        bool    `json:"active"`
+	ActiveSince   int64   `json:"active_since,omitempty"`
+	RxBytesPerSec float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec float64 `json:"tx_bytes_per_sec"`
+	// с момента последнего включения
+	RxBytes uint64 `json:"rx_bytes"`
+	TxBytes uint64 `json:"tx_bytes"`
+	// с момента старта агента
+	TotalRxBytes uint64 `json:"total_rx_bytes"`
+	TotalTxBytes uint64 `json:"total_tx_bytes"`
+}
+
+// GroupRates — скорости одной группы трафика (сетей или портов)
+type GroupRates struct {
+	Group         string  `json:"group"`
+	RxBytesPerSec float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec float64 `json:"tx_bytes_per_sec"`
+	RxBitsPerSec  float64 `json:"rx_bits_per_sec"`
+	TxBitsPerSec  float64 `json:"tx_bits_per_sec"`
+}
+
+// NewGroupRates считает скорости по группам в порядке order
+func NewGroupRates(order []string, cur, prev map[string]collector.Counters, sec float64) []GroupRates {
+	out := make([]GroupRates, 0, len(order))
+	for _, g := range order {
+		rx, tx := collector.Delta(cur[g].Rx, prev[g].Rx)/sec, collector.Delta(cur[g].Tx, prev[g].Tx)/sec
+		out = append(out, GroupRates{
+			Group:         g,
+			RxBytesPerSec: rx,
+			TxBytesPerSec: tx,
+			RxBitsPerSec:  rx * 8,
+			TxBitsPerSec:  tx * 8,
+		})
+	}
+	return out
+}
+
+// ReachPortStats — входящие попытки на проверочный порт: за интервал отчёта и с запуска агента.
+// external_* — попытки не из loopback, частных и link-local сетей
+type ReachPortStats struct {
+	Port             string `json:"port"` // tcp/8443
+	Listening        bool   `json:"listening"`
+	Error            string `json:"error,omitempty"`
+	Attempts         uint64 `json:"attempts"`
+	ExternalAttempts uint64 `json:"external_attempts"`
+	Bytes            uint64 `json:"bytes"`
+	TotalAttempts    uint64 `json:"total_attempts"`
+	TotalExternal    uint64 `json:"total_external_attempts"`
+	LastExternal     int64  `json:"last_external_at,omitempty"`
+}
+
+// NewReachPortStats — приросты счётчиков портов между cur и prev в порядке ports
+func NewReachPortStats(ports []collector.ReachPort, cur, prev map[collector.ReachPort]collector.ReachCounters) []ReachPortStats {
+	out := make([]ReachPortStats, 0, len(ports))
+	for _, p := range ports {
+		c, o := cur[p], prev[p]
+		st := ReachPortStats{
+			Port:             p.String(),
+			Listening:        c.Err == "",
+			Error:            c.Err,
+			Attempts:         c.Attempts - o.Attempts,
+			ExternalAttempts: c.External - o.External,
+			Bytes:            c.Bytes - o.Bytes,
+			TotalAttempts:    c.Attempts,
+			TotalExternal:    c.External,
+		}
+		if !c.LastExternal.IsZero() {
+			st.LastExternal = c.LastExternal.Unix()
+		}
+		out = append(out, st)
+	}
+	return out
+}
+
+// InterfaceRates — скорости одного интерфейса; aggregated — вошёл ли он в суммарные поля
+// (член bond/bridge не входит, если учтён его master)
+type InterfaceRates struct {
+	Interface string `json:"interface"`
+	// человеческое имя: из INTERFACE_LABELS, иначе сосед по LLDP (коммутатор/порт)
+	Label         string  `json:"label,omitempty"`
+	LLDPNeighbor  string  `json:"lldp_neighbor,omitempty"`
+	Master        string  `json:"master,omitempty"`
+	Kind          string  `json:"kind,omitempty"`
+	Aggregated    bool    `json:"aggregated"`
+	RxBytesPerSec float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec float64 `json:"tx_bytes_per_sec"`
+	RxBitsPerSec  float64 `json:"rx_bits_per_sec"`
+	TxBitsPerSec  float64 `json:"tx_bits_per_sec"`
+	// интерфейс в зоне идущей выкладки
+	DeployID string `json:"deploy_id,omitempty"`
+	// согласованная скорость линка, Мбит/с (LINK_SPEED)
+	SpeedMbps int `json:"speed_mbps,omitempty"`
+}
+
+// NewInterfaceRates считает скорости интерфейсов names; в суммарные поля вошли aggregated
+func NewInterfaceRates(names, aggregated []string, topo collector.Topology, cur, prev map[string]collector.Counters, sec float64) []InterfaceRates {
+	in := make(map[string]bool, len(aggregated))
+	for _, n := range aggregated {
+		in[n] = true
+	}
+	out := make([]InterfaceRates, 0, len(names))
+	for _, n := range names {
+		rx, tx := collector.Delta(cur[n].Rx, prev[n].Rx)/sec, collector.Delta(cur[n].Tx, prev[n].Tx)/sec
+		out = append(out, InterfaceRates{
+			Interface:     n,
+			Master:        topo[n].Master,
+			Kind:          topo[n].Kind,
+			Aggregated:    in[n],
+			RxBytesPerSec: rx,
+			TxBytesPerSec: tx,
+			RxBitsPerSec:  rx * 8,
+			TxBitsPerSec:  tx * 8,
+		})
+	}
+	return out
+}
+
+// ASNRates — скорости обмена с одной автономной системой; ASN 0 — адреса вне таблицы
+type ASNRates struct {
+	ASN           uint32  `json:"asn"`
+	RxBytesPerSec float64 `json:"rx_bytes_per_sec"`
+	TxBytesPerSec float64 `json:"tx_bytes_per_sec"`
+	RxBitsPerSec  float64 `json:"rx_bits_per_sec"`
+	TxBitsPerSec  float64 `json:"tx_bits_per_sec"`
+}
+
+// CacheStats — обмен с upstream-ами (origin) против обмена с клиентами на CDN-узле.
+// Отношения не заполняются, пока клиентам ничего не отдано
+type CacheStats struct {
+	UpstreamRxBytesPerSec float64 `json:"upstream_rx_bytes_per_sec"`
+	UpstreamTxBytesPerSec float64 `json:"upstream_tx_bytes_per_sec"`
+	ClientRxBytesPerSec   float64 `json:"client_rx_bytes_per_sec"`
+	ClientTxBytesPerSec   float64 `json:"client_tx_bytes_per_sec"`
+	// байт докачано с origin на байт, отданный клиентам
+	CacheFillRatio *float64 `json:"cache_fill_ratio,omitempty"`
+	// 1 - cache_fill_ratio (не меньше 0) — байтовая эффективность кэша
+	CacheEfficiency *float64 `json:"cache_efficiency,omitempty"`
+}
+
+// NewCacheStats считает скорости и отношения по приросту счётчиков upstream и клиентов
+func NewCacheStats(upCur, upPrev, clCur, clPrev collector.Counters, sec float64) *CacheStats {
+	cs := &CacheStats{
+		UpstreamRxBytesPerSec: collector.Delta(upCur.Rx, upPrev.Rx) / sec,
+		UpstreamTxBytesPerSec: collector.Delta(upCur.Tx, upPrev.Tx) / sec,
+		ClientRxBytesPerSec:   collector.Delta(clCur.Rx, clPrev.Rx) / sec,
+		ClientTxBytesPerSec:   collector.Delta(clCur.Tx, clPrev.Tx) / sec,
+	}
+	if cs.ClientTxBytesPerSec > 0 {
+		ratio := cs.UpstreamRxBytesPerSec / cs.ClientTxBytesPerSec
+		eff := max(0, 1-ratio)
+		cs.CacheFillRatio, cs.CacheEfficiency = &ratio, &eff
+	}
+	return cs
+}
+
+// LinkNeighbor — сосед по LLDP/CDP на интерфейсе
+type LinkNeighbor struct {
+	Interface  string `json:"interface"`
+	Protocol   string `json:"protocol"`
+	SystemName string `json:"system_name,omitempty"`
+	ChassisID  string `json:"chassis_id"`
+	PortID     string `json:"port_id"`
+	PortDesc   string `json:"port_description,omitempty"`
+}
+
+func NewLinkNeighbor(iface string, nb collector.Neighbor) LinkNeighbor {
+	return LinkNeighbor{
+		Interface:  iface,
+		Protocol:   nb.Protocol,
+		SystemName: nb.SystemName,
+		ChassisID:  nb.ChassisID,
+		PortID:     nb.PortID,
+		PortDesc:   nb.PortDesc,
+	}
+}
+
+// Event — разовое событие (в отличие от периодического отчёта)
+type Event struct {
+	Type      string         `json:"type"`
+	Timestamp int64          `json:"timestamp"`
+	Host      string         `json:"host"`
+	NodeName  string         `json:"node_name,omitempty"`
+	Interface string         `json:"interface,omitempty"`
+	Message   string         `json:"message"`
+	Data      map[string]any `json:"data,omitempty"`
+	// общее у событий, пришедших вместе (EVENT_CORRELATION_WINDOW); у correlated — и сами события
+	CorrelationID string  `json:"correlation_id,omitempty"`
+	Events        []Event `json:"events,omitempty"`
+}
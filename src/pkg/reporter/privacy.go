@@ -0,0 +1,100 @@
+package reporter
+
+import (
+	"math"
+	"math/rand/v2"
+)
+
+// Privacy — преобразование отчёта для стороннего получателя: остаются только агрегаты узла —
+// суммарные скорости, сглаженные средние, семейства IP и группы сетей/портов, — к каждой
+// скорости добавляется шум Лапласа и/или она округляется. Детали (интерфейсы, соседи, ASN,
+// события, алерты, телеметрия) не уходят вовсе. Отчёт основному получателю не меняется
+type Privacy struct {
+	// Epsilon — бюджет приватности на одну скорость (меньше — больше шума); 0 — без шума.
+	// Шум каждой скорости и каждого отчёта независим: по композиции k скоростей за n отчётов
+	// расходуют до k·n·Epsilon
+	Epsilon float64
+	// Sensitivity — вклад одного участника, который шум должен скрыть, байт/с
+	Sensitivity float64
+	// Round — шаг округления скоростей, байт/с; 0 — без округления
+	Round float64
+}
+
+// Apply возвращает обезличенную копию pl
+func (p Privacy) Apply(pl Payload) Payload {
+	out := Payload{
+		Host:                     pl.Host,
+		NodeName:                 pl.NodeName,
+		Labels:                   pl.Labels,
+		Timestamp:                pl.Timestamp,
+		IntervalSeconds:          pl.IntervalSeconds,
+		IntervalWallSeconds:      pl.IntervalWallSeconds,
+		IntervalMonotonicSeconds: pl.IntervalMonotonicSeconds,
+		WindowStart:              pl.WindowStart,
+		WindowEnd:                pl.WindowEnd,
+		Sequence:                 pl.Sequence,
+		NoInterfacesMatched:      pl.NoInterfacesMatched,
+	}
+	out.SetRates(p.rate(pl.RxBytesPerSec), p.rate(pl.TxBytesPerSec))
+	if w := pl.WindowAvg; w != nil {
+		out.WindowAvg = NewWindowAvg(p.rate(w.RxBytesPerSec5m), p.rate(w.TxBytesPerSec5m))
+	}
+	if e := pl.EWMAAvg; e != nil {
+		out.EWMAAvg = NewEWMAAvg(p.rate(e.RxBytesPerSecEWMA), p.rate(e.TxBytesPerSecEWMA))
+	}
+	if d := pl.DailyAvg; d != nil {
+		out.DailyAvg = NewDailyAvg(p.rate(d.RxBytesPerSec24h), p.rate(d.TxBytesPerSec24h), d.CoverageSeconds24h)
+	}
+	if w := pl.WeeklyAvg; w != nil {
+		out.WeeklyAvg = NewWeeklyAvg(p.rate(w.RxBytesPerSec7d), p.rate(w.TxBytesPerSec7d), w.CoverageSeconds7d)
+	}
+	if f := pl.IPFamilyRates; f != nil {
+		v4rx, v4tx, v6rx, v6tx := p.rate(f.IPv4RxBytesPerSec), p.rate(f.IPv4TxBytesPerSec), p.rate(f.IPv6RxBytesPerSec), p.rate(f.IPv6TxBytesPerSec)
+		out.IPFamilyRates = &IPFamilyRates{
+			IPv4RxBytesPerSec: v4rx,
+			IPv4TxBytesPerSec: v4tx,
+			IPv4RxBitsPerSec:  v4rx * 8,
+			IPv4TxBitsPerSec:  v4tx * 8,
+			IPv6RxBytesPerSec: v6rx,
+			IPv6TxBytesPerSec: v6tx,
+			IPv6RxBitsPerSec:  v6rx * 8,
+			IPv6TxBitsPerSec:  v6tx * 8,
+		}
+	}
+	out.SubnetGroups = p.groups(pl.SubnetGroups)
+	out.PortGroups = p.groups(pl.PortGroups)
+	return out
+}
+
+func (p Privacy) groups(in []GroupRates) []GroupRates {
+	if len(in) == 0 {
+		return nil
+	}
+	out := make([]GroupRates, 0, len(in))
+	for _, g := range in {
+		rx, tx := p.rate(g.RxBytesPerSec), p.rate(g.TxBytesPerSec)
+		out = append(out, GroupRates{Group: g.Group, RxBytesPerSec: rx, TxBytesPerSec: tx, RxBitsPerSec: rx * 8, TxBitsPerSec: tx * 8})
+	}
+	return out
+}
+
+// rate: сначала шум, потом округление — округление после шума не раскрывает точное значение;
+// отрицательная скорость после шума — ноль
+func (p Privacy) rate(v float64) float64 {
+	if p.Epsilon > 0 {
+		v += laplace(p.Sensitivity / p.Epsilon)
+	}
+	if p.Round > 0 {
+		v = math.Round(v/p.Round) * p.Round
+	}
+	return math.Max(v, 0)
+}
+
+// laplace — выборка из распределения Лапласа с нулевым центром и масштабом b
+func laplace(b float64) float64 {
+	u := rand.Float64() - 0.5
+	for u == -0.5 {
+		u = rand.Float64() - 0.5
+	}
+	return -b * math.Copysign(1, u) * math.Log(1-2*math.Abs(u))
+}
@@ -0,0 +1,165 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"maps"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MetricPrefix — префикс имён метрик в формате Prometheus
+const MetricPrefix = "netload_"
+
+// накопительные поля отчёта — в Prometheus это counter, остальное gauge
+var counterFields = map[string]bool{
+	"month_rx_bytes":              true,
+	"month_tx_bytes":              true,
+	"burst_excess_bytes":          true,
+	"agent_samples_dropped":       true,
+	"agent_collector_read_errors": true,
+	"agent_collector_timeouts":    true,
+}
+
+// поля-словари отчёта: ключ словаря становится меткой с этим именем
+var mapFieldLabels = map[string]string{
+	"agent_collector_duration_ms": "collector",
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// в значении метки текстовый формат экранирует только \\, \" и \n; strconv.Quote
+// экранировал бы и остальное (\t, не-ASCII), а Prometheus прочитал бы это буквально
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// WritePrometheus выводит все числовые поля отчёта в текстовом формате Prometheus:
+// имя метрики — JSON-ключ с префиксом (у счётчиков ещё и _total), метки — host, node_name и статические labels;
+// поле-словарь из mapFieldLabels — по метрике на ключ с меткой вроде collector="…"
+func WritePrometheus(w io.Writer, pl Payload) error {
+	raw, err := json.Marshal(pl)
+	if err != nil {
+		return err
+	}
+	var fields map[string]any
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return err
+	}
+	pairs := promLabelPairs(pl)
+	labels := formatLabels(pairs)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		var v float64
+		switch x := fields[k].(type) {
+		case float64:
+			v = x
+		case bool:
+			if x {
+				v = 1
+			}
+		case map[string]any:
+			if label, ok := mapFieldLabels[k]; ok {
+				if err := writeMapField(w, k, label, x, pairs); err != nil {
+					return err
+				}
+			}
+			continue
+		default:
+			continue
+		}
+		// время, а не измерение
+		if k == "timestamp" || k == "window_start" || k == "window_end" {
+			continue
+		}
+		typ, name := "gauge", MetricPrefix+k
+		if counterFields[k] {
+			typ, name = "counter", name+"_total"
+		}
+		if _, err := fmt.Fprintf(w, "# TYPE %s %s\n%s%s %s\n", name, typ, name, labels, formatFloat(v)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeMapField выводит словарь m одной метрикой-gauge: строка на ключ, ключ — в метке label
+func writeMapField(w io.Writer, k, label string, m map[string]any, pairs map[string]string) error {
+	name := MetricPrefix + k
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		if _, ok := m[key].(float64); ok {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+	if _, err := fmt.Fprintf(w, "# TYPE %s gauge\n", name); err != nil {
+		return err
+	}
+	withKey := maps.Clone(pairs)
+	for _, key := range keys {
+		withKey[label] = key
+		if _, err := fmt.Fprintf(w, "%s%s %s\n", name, formatLabels(withKey), formatFloat(m[key].(float64))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func promLabelPairs(pl Payload) map[string]string {
+	pairs := map[string]string{"host": pl.Host}
+	if pl.NodeName != "" {
+		pairs["node_name"] = pl.NodeName
+	}
+	for k, v := range pl.Labels {
+		name := PromLabelName(k)
+		if _, taken := pairs[name]; !taken {
+			pairs[name] = v
+		}
+	}
+	return pairs
+}
+
+func formatLabels(pairs map[string]string) string {
+	names := make([]string, 0, len(pairs))
+	for k := range pairs {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, k := range names {
+		parts[i] = k + `="` + labelEscaper.Replace(pairs[k]) + `"`
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+// PromLabelName — имя метки Prometheus для ключа LABELS: недопустимые символы — "_"
+func PromLabelName(k string) string {
+	name := invalidLabelChars.ReplaceAllString(k, "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
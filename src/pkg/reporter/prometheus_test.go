@@ -0,0 +1,58 @@
+package reporter
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestWritePrometheus(t *testing.T) {
+	tests := []struct {
+		name string
+		pl   Payload
+		want []string
+	}{
+		{
+			"gauge",
+			Payload{Host: "web-1", RxBytesPerSec: 10},
+			[]string{"# TYPE netload_rx_bytes_per_sec gauge\n", `netload_rx_bytes_per_sec{host="web-1"} 10` + "\n"},
+		},
+		{
+			"counter",
+			Payload{Host: "web-1", MonthlyUsage: &MonthlyUsage{MonthRxBytes: 5}},
+			[]string{"# TYPE netload_month_rx_bytes_total counter\n", `netload_month_rx_bytes_total{host="web-1"} 5` + "\n"},
+		},
+		{
+			"labels sorted and renamed",
+			Payload{Host: "web-1", NodeName: "n1", Labels: map[string]string{"dc-name": "ams", "host": "other"}},
+			[]string{`{dc_name="ams",host="web-1",node_name="n1"}`},
+		},
+		{
+			"collector durations",
+			Payload{Host: "web-1", Telemetry: &Telemetry{CollectorDurationsMs: map[string]float64{"netdev": 0.5, "conntrack": 12}}},
+			[]string{
+				"# TYPE netload_agent_collector_duration_ms gauge\n" +
+					`netload_agent_collector_duration_ms{collector="conntrack",host="web-1"} 12` + "\n" +
+					`netload_agent_collector_duration_ms{collector="netdev",host="web-1"} 0.5` + "\n",
+			},
+		},
+		{
+			"label value escaping",
+			Payload{Host: "a\\b\"c\nd\té"},
+			[]string{`{host="a\\b\"c\nd` + "\té" + `"}`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b bytes.Buffer
+			if err := WritePrometheus(&b, tt.pl); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(b.String(), want) {
+					t.Errorf("output has no %q:\n%s", want, b.String())
+				}
+			}
+		})
+	}
+}
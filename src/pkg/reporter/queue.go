@@ -0,0 +1,146 @@
+package reporter
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/msg"
+)
+
+// Retry — политика повторов одной отправки
+type Retry struct {
+	MaxAttempts    int           // всего попыток, включая первую
+	BaseDelay      time.Duration // пауза перед второй попыткой, дальше удваивается
+	MaxDelay       time.Duration
+	AttemptTimeout time.Duration // дедлайн одной попытки целиком
+}
+
+// backoff — экспоненциальная пауза с полным jitter-ом перед попыткой attempt+1
+func (r Retry) backoff(attempt int) time.Duration {
+	d := r.BaseDelay << (attempt - 1)
+	if d <= 0 || d > r.MaxDelay {
+		d = r.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d) + 1
+}
+
+// Job — одна отправка; Value кодируется один раз в Encoding, Done вызывается
+// по итогу всех попыток: latency — последней попытки
+type Job struct {
+	Exporter Exporter
+	Encoding string
+	Value    any
+	Samples  int
+	// Encryptor, если задан, заменяет тело конвертом для ключей сервера
+	Encryptor *Encryptor
+	Done      func(latency time.Duration, err error)
+}
+
+// Queue отправляет задания в отдельной горутине, чтобы повторы старого отчёта не задерживали
+// сбор следующего. Очередь ограничена: при переполнении выбрасывается самое старое задание
+type Queue struct {
+	Retry Retry
+	// Signer, если задан, подписывает каждое тело
+	Signer *Signer
+	// Dropped вызывается для выброшенного при переполнении задания и для пришедшего после Close
+	Dropped func(Job)
+
+	jobs   chan Job
+	mu     sync.Mutex
+	closed bool
+}
+
+func NewQueue(retry Retry, size int, dropped func(Job)) *Queue {
+	return &Queue{Retry: retry, Dropped: dropped, jobs: make(chan Job, size)}
+}
+
+// Push ставит задание в очередь; после Close задание выбрасывается: события и отчёты из
+// горутин, которые ещё работают во время остановки, не должны ронять процесс
+func (q *Queue) Push(j Job) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		if q.Dropped != nil {
+			q.Dropped(j)
+		}
+		return
+	}
+	for {
+		select {
+		case q.jobs <- j:
+			return
+		default:
+		}
+		select {
+		case old := <-q.jobs:
+			if q.Dropped != nil {
+				q.Dropped(old)
+			}
+		default:
+		}
+	}
+}
+
+// Close — новых заданий не будет; Run дошлёт оставшиеся и вернётся
+func (q *Queue) Close() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if !q.closed {
+		q.closed = true
+		close(q.jobs)
+	}
+}
+
+// Run обрабатывает задания до Close; отмена ctx прерывает текущие попытки и паузы
+func (q *Queue) Run(ctx context.Context) {
+	for j := range q.jobs {
+		latency, err := q.send(ctx, j)
+		if j.Done != nil {
+			j.Done(latency, err)
+		}
+	}
+}
+
+func (q *Queue) send(ctx context.Context, j Job) (latency time.Duration, err error) {
+	name := j.Exporter.Name()
+	body, err := Encode(j.Value, j.Encoding)
+	if err != nil {
+		return 0, fmt.Errorf("%s: encode %s: %w", name, j.Encoding, err)
+	}
+	// шифруется один раз: подпись ниже — уже над конвертом, и сервер проверяет её до расшифровки
+	if j.Encryptor != nil {
+		if err := j.Encryptor.Seal(&body); err != nil {
+			return 0, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	for attempt := 1; ; attempt++ {
+		// каждая попытка со своим nonce: сервер мог запомнить прошлый, не приняв отчёт
+		if q.Signer != nil {
+			q.Signer.Sign(&body)
+		}
+		actx, cancel := ctx, context.CancelFunc(func() {})
+		if q.Retry.AttemptTimeout > 0 {
+			actx, cancel = context.WithTimeout(ctx, q.Retry.AttemptTimeout)
+		}
+		started := time.Now()
+		err = j.Exporter.Export(actx, body)
+		latency = time.Since(started)
+		cancel()
+		if err == nil || !Retryable(err) || attempt >= q.Retry.MaxAttempts || ctx.Err() != nil {
+			return latency, err
+		}
+		wait := q.Retry.backoff(attempt)
+		msg.Printf(msg.SendRetry, name, attempt, q.Retry.MaxAttempts, err, wait.Round(time.Millisecond))
+		select {
+		case <-ctx.Done():
+			return latency, err
+		case <-time.After(wait):
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package reporter
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type nopExporter struct{ sent atomic.Int32 }
+
+func (e *nopExporter) Name() string { return "test" }
+
+func (e *nopExporter) Export(context.Context, Body) error {
+	e.sent.Add(1)
+	return nil
+}
+
+func TestQueuePushAfterClose(t *testing.T) {
+	var dropped atomic.Int32
+	exp := &nopExporter{}
+	q := NewQueue(Retry{MaxAttempts: 1}, 4, func(Job) { dropped.Add(1) })
+	done := make(chan struct{})
+	go func() { q.Run(context.Background()); close(done) }()
+
+	q.Push(Job{Exporter: exp, Value: 1})
+	q.Close()
+	q.Close()
+	// после Close — не паника, а Dropped
+	q.Push(Job{Exporter: exp, Value: 2})
+	<-done
+	if exp.sent.Load() != 1 || dropped.Load() != 1 {
+		t.Errorf("sent %d, dropped %d; want 1 and 1", exp.sent.Load(), dropped.Load())
+	}
+}
+
+func TestEventBusCloseWaitsForTimer(t *testing.T) {
+	// таймер окна срабатывает одновременно с остановкой; событие либо уходит, либо выброшено
+	for i := 0; i < 50; i++ {
+		q := NewQueue(Retry{MaxAttempts: 1}, 4, nil)
+		done := make(chan struct{})
+		go func() { q.Run(context.Background()); close(done) }()
+		b := &EventBus{Client: http.DefaultClient, Sink: Sink{Name: "events", URL: "http://127.0.0.1:1"}, Correlate: time.Millisecond, Queue: q}
+		b.Emit(context.Background(), Event{Type: "test"})
+		time.Sleep(time.Duration(i%3) * time.Millisecond)
+
+		var wg sync.WaitGroup
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// поздний Emit из другой горутины во время остановки
+			b.Emit(context.Background(), Event{Type: "late"})
+		}()
+		if err := b.Close(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		wg.Wait()
+		q.Close()
+		<-done
+	}
+}
@@ -0,0 +1,121 @@
+package reporter
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// заголовки подписи: сервер находит публичный ключ по KeyIDHeader и проверяет
+// подпись времени, nonce и ровно тех байт, что пришли в теле (после gzip, если он есть)
+const (
+	SignatureHeader = "X-Signature"
+	KeyIDHeader     = "X-Key-Id"
+	TimestampHeader = "X-Signature-Timestamp"
+	NonceHeader     = "X-Signature-Nonce"
+	signaturePrefix = "ed25519="
+)
+
+// Signer подписывает тела отчётов ключом агента (Ed25519)
+type Signer struct {
+	key   ed25519.PrivateKey
+	KeyID string
+}
+
+// GenerateKey создаёт ключ агента в path (PKCS#8 PEM, 0600); существующий не перезаписывает
+func GenerateKey(path string) (*Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return nil, err
+	}
+	if err := pem.Encode(f, &pem.Block{Type: "PRIVATE KEY", Bytes: der}); err != nil {
+		f.Close()
+		return nil, err
+	}
+	if err := f.Close(); err != nil {
+		return nil, err
+	}
+	return newSigner(priv), nil
+}
+
+// LoadSigner читает ключ, созданный GenerateKey
+func LoadSigner(path string) (*Signer, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, fmt.Errorf("%s: no PRIVATE KEY block", path)
+	}
+	k, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	priv, ok := k.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("%s: not an Ed25519 key", path)
+	}
+	return newSigner(priv), nil
+}
+
+func newSigner(priv ed25519.PrivateKey) *Signer {
+	return &Signer{key: priv, KeyID: KeyID(priv.Public().(ed25519.PublicKey))}
+}
+
+// KeyID — первые 8 байт SHA-256 публичного ключа в hex
+func KeyID(pub ed25519.PublicKey) string {
+	sum := sha256.Sum256(pub)
+	return hex.EncodeToString(sum[:8])
+}
+
+// PublicKey — публичный ключ в base64, его регистрируют на сервере
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.key.Public().(ed25519.PublicKey))
+}
+
+// Sign добавляет к телу заголовки подписи. Время и nonce каждый раз новые,
+// поэтому повторную отправку подписывают заново: сервер отбросит старый nonce
+func (s *Signer) Sign(b *Body) {
+	if b.Headers == nil {
+		b.Headers = make(map[string]string)
+	}
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	nonce := make([]byte, 16)
+	rand.Read(nonce)
+	n := hex.EncodeToString(nonce)
+	b.Headers[KeyIDHeader] = s.KeyID
+	b.Headers[TimestampHeader] = ts
+	b.Headers[NonceHeader] = n
+	b.Headers[SignatureHeader] = signaturePrefix + base64.StdEncoding.EncodeToString(ed25519.Sign(s.key, signedMessage(ts, n, b.Data)))
+}
+
+// signedMessage: время и nonce входят в подпись, их нельзя подменить, не сломав её
+func signedMessage(ts, nonce string, data []byte) []byte {
+	msg := make([]byte, 0, len(ts)+len(nonce)+2+len(data))
+	msg = append(msg, ts...)
+	msg = append(msg, '\n')
+	msg = append(msg, nonce...)
+	msg = append(msg, '\n')
+	return append(msg, data...)
+}
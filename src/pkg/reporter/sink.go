@@ -0,0 +1,159 @@
+package reporter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Sink — HTTP-эндпоинт, принимающий отчёты
+type Sink struct {
+	Name     string
+	URL      string
+	APIKey   string
+	Encoding string
+}
+
+// Send отправляет один отчёт
+func (s Sink) Send(ctx context.Context, client *http.Client, pl Payload) error {
+	return s.SendValue(ctx, client, pl)
+}
+
+// SendBatch отправляет пачку отчётов одним JSON-массивом
+func (s Sink) SendBatch(ctx context.Context, client *http.Client, pls []Payload) error {
+	return s.SendValue(ctx, client, pls)
+}
+
+// SendValue кодирует v в формате Encoding и отправляет
+func (s Sink) SendValue(ctx context.Context, client *http.Client, v any) error {
+	body, err := Encode(v, s.Encoding)
+	if err != nil {
+		return fmt.Errorf("%s: encode %s: %w", s.Name, s.Encoding, err)
+	}
+	return s.Post(ctx, client, body)
+}
+
+func (s Sink) NewRequest(ctx context.Context, body Body) (*http.Request, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body.Data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", body.ContentType)
+	if body.ContentEncoding != "" {
+		req.Header.Set("Content-Encoding", body.ContentEncoding)
+	}
+	if s.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.APIKey)
+	}
+	for k, v := range body.Headers {
+		req.Header.Set(k, v)
+	}
+	return req, nil
+}
+
+func (s Sink) Post(ctx context.Context, client *http.Client, body Body) error {
+	req, err := s.NewRequest(ctx, body)
+	if err != nil {
+		return fmt.Errorf("POST %s: %w", s.URL, err)
+	}
+
+	//log.Printf("body: %s", body.Data)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("POST %s: %w", s.URL, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return &StatusError{URL: s.URL, Code: resp.StatusCode, Status: resp.Status}
+	}
+	return nil
+}
+
+// StatusError — сервер ответил, но не 2xx
+type StatusError struct {
+	URL    string
+	Code   int
+	Status string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("POST %s: status %s", e.URL, e.Status)
+}
+
+// Retryable: имеет смысл повторить при сетевой ошибке, 429 и 5xx, а также при ошибках
+// с Temporary() == true (так их помечают экспортёры шин); остальное повтором не лечится
+func Retryable(err error) bool {
+	var se *StatusError
+	if errors.As(err, &se) {
+		return se.Code == http.StatusTooManyRequests || se.Code >= 500
+	}
+	var ue *url.Error
+	if errors.As(err, &ue) {
+		return true
+	}
+	var te interface{ Temporary() bool }
+	return errors.As(err, &te) && te.Temporary()
+}
+
+// NewHTTPClient — клиент с раздельными таймаутами: connect — установка TCP/TLS,
+// response — ожидание заголовков ответа после отправки запроса.
+// proxy — явный прокси (http, https, socks5, socks5h; логин и пароль — в URL),
+// nil — по HTTP_PROXY/HTTPS_PROXY/NO_PROXY
+func NewHTTPClient(connect, response time.Duration, proxy *url.URL) *http.Client {
+	return NewSourceHTTPClient(connect, response, proxy, Source{})
+}
+
+// NewSourceHTTPClient — NewHTTPClient с соединениями от src
+func NewSourceHTTPClient(connect, response time.Duration, proxy *url.URL, src Source) *http.Client {
+	proxyFunc := http.ProxyFromEnvironment
+	if proxy != nil {
+		proxyFunc = http.ProxyURL(proxy)
+	}
+	return &http.Client{Transport: &http.Transport{
+		Proxy:                 proxyFunc,
+		DialContext:           src.DialContext(connect),
+		TLSHandshakeTimeout:   connect,
+		ResponseHeaderTimeout: response,
+		IdleConnTimeout:       90 * time.Second,
+		ForceAttemptHTTP2:     true,
+	}}
+}
+
+// ParseProxy разбирает PROXY_URL; схема обязательна
+func ParseProxy(raw string) (*url.URL, error) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q, want http, https, socks5 or socks5h", u.Scheme)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy %q: no host", raw)
+	}
+	return u, nil
+}
+
+// ProxyFor — прокси, через который client пойдёт на target (без пароля), "" — напрямую
+func ProxyFor(client *http.Client, target string) (string, error) {
+	t, ok := client.Transport.(*http.Transport)
+	if !ok || t.Proxy == nil {
+		return "", nil
+	}
+	req, err := http.NewRequest(http.MethodPost, target, nil)
+	if err != nil {
+		return "", err
+	}
+	u, err := t.Proxy(req)
+	if err != nil || u == nil {
+		return "", err
+	}
+	return u.Redacted(), nil
+}
@@ -0,0 +1,79 @@
+package reporter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestSinkPost(t *testing.T) {
+	tests := []struct {
+		name      string
+		status    int
+		wantErr   bool
+		retryable bool
+	}{
+		{"ok", http.StatusOK, false, false},
+		{"accepted", http.StatusAccepted, false, false},
+		{"bad request", http.StatusBadRequest, true, false},
+		{"too many requests", http.StatusTooManyRequests, true, true},
+		{"server error", http.StatusBadGateway, true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got *http.Request
+			var body []byte
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = r
+				body, _ = io.ReadAll(r.Body)
+				w.WriteHeader(tt.status)
+			}))
+			defer srv.Close()
+
+			s := Sink{Name: "report", URL: srv.URL + "/v1/report", APIKey: "k", Encoding: EncodingJSONGzip}
+			b, err := Encode(Payload{Host: "web-1"}, s.Encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b.Headers = map[string]string{"X-Extra": "1"}
+			err = s.Post(context.Background(), NewHTTPClient(time.Second, time.Second, nil), b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				var se *StatusError
+				if !errors.As(err, &se) || se.Code != tt.status {
+					t.Errorf("err = %v, want StatusError %d", err, tt.status)
+				}
+				if Retryable(err) != tt.retryable {
+					t.Errorf("Retryable = %v, want %v", Retryable(err), tt.retryable)
+				}
+			}
+			if got.Method != http.MethodPost || got.URL.Path != "/v1/report" {
+				t.Errorf("request %s %s", got.Method, got.URL.Path)
+			}
+			for k, want := range map[string]string{"Authorization": "Bearer k", "Content-Encoding": "gzip", "Content-Type": "application/json", "X-Extra": "1"} {
+				if v := got.Header.Get(k); v != want {
+					t.Errorf("%s = %q, want %q", k, v, want)
+				}
+			}
+			if string(body) != string(b.Data) {
+				t.Error("body differs from the encoded one")
+			}
+		})
+	}
+}
+
+func TestSinkPostUnreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+	err := Sink{Name: "report", URL: url}.SendValue(context.Background(), NewHTTPClient(time.Second, time.Second, nil), Payload{})
+	if err == nil || !Retryable(err) {
+		t.Errorf("err = %v, want a retryable network error", err)
+	}
+}
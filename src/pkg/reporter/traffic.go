@@ -0,0 +1,107 @@
+package reporter
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"sync/atomic"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+)
+
+// Traffic — байты, которые агент сам передал по сети (OWN_TRAFFIC): отчёты, canary, события,
+// запросы к API. Счёт на сокете — с TLS и HTTP, но без заголовков TCP/IP и ретрансмитов — и по
+// локальному адресу соединения, чтобы отнести трафик к интерфейсу. Соединения через loopback
+// не считаются: измеряемый аплинк они не нагружают
+type Traffic struct {
+	mu     sync.Mutex
+	byAddr map[netip.Addr]*addrTraffic
+}
+
+type addrTraffic struct {
+	rx, tx atomic.Uint64
+}
+
+// Counts — принято и отправлено с запуска, всего
+func (t *Traffic) Counts() (rx, tx uint64) {
+	for _, c := range t.ByAddr() {
+		rx, tx = rx+c.Rx, tx+c.Tx
+	}
+	return rx, tx
+}
+
+// ByAddr — принято и отправлено с запуска по локальным адресам
+func (t *Traffic) ByAddr() map[netip.Addr]collector.Counters {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	out := make(map[netip.Addr]collector.Counters, len(t.byAddr))
+	for a, c := range t.byAddr {
+		out[a] = collector.Counters{Rx: c.rx.Load(), Tx: c.tx.Load()}
+	}
+	return out
+}
+
+func (t *Traffic) counter(a netip.Addr) *addrTraffic {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	c := t.byAddr[a]
+	if c == nil {
+		if t.byAddr == nil {
+			t.byAddr = make(map[netip.Addr]*addrTraffic)
+		}
+		c = &addrTraffic{}
+		t.byAddr[a] = c
+	}
+	return c
+}
+
+// Conn оборачивает соединение счётчиком; nil Traffic — соединение как есть
+func (t *Traffic) Conn(c net.Conn) net.Conn {
+	if t == nil {
+		return c
+	}
+	remote, err := netip.ParseAddrPort(c.RemoteAddr().String())
+	if err == nil && remote.Addr().IsLoopback() {
+		return c
+	}
+	local, _ := netip.ParseAddrPort(c.LocalAddr().String())
+	return &countedConn{Conn: c, c: t.counter(local.Addr().Unmap())}
+}
+
+// Listener считает трафик принятых соединений
+func (t *Traffic) Listener(l net.Listener) net.Listener {
+	if t == nil {
+		return l
+	}
+	return &countedListener{Listener: l, t: t}
+}
+
+type countedConn struct {
+	net.Conn
+	c *addrTraffic
+}
+
+func (c *countedConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+	c.c.rx.Add(uint64(n))
+	return n, err
+}
+
+func (c *countedConn) Write(b []byte) (int, error) {
+	n, err := c.Conn.Write(b)
+	c.c.tx.Add(uint64(n))
+	return n, err
+}
+
+type countedListener struct {
+	net.Listener
+	t *Traffic
+}
+
+func (l *countedListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return l.t.Conn(c), nil
+}
@@ -0,0 +1,113 @@
+package reporter
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const DefaultMaxSkew = 5 * time.Minute
+
+// Verifier проверяет подписанные отчёты на стороне сервера приёма:
+// подпись, расхождение времени агента не больше MaxSkew и одноразовость nonce.
+// Перехваченный запрос нельзя переиграть ни сразу (nonce уже видели), ни позже
+// (время вышло за окно, а nonce из окна помнятся).
+//
+// Один Verifier на процесс; при нескольких репликах сервера nonce-ы нужно
+// хранить в общем хранилище — Seen подменяется.
+type Verifier struct {
+	MaxSkew time.Duration
+	// Seen отмечает nonce и возвращает true, если он уже встречался за время ttl;
+	// по умолчанию — память процесса
+	Seen func(keyID, nonce string, ttl time.Duration) bool
+	Now  func() time.Time
+
+	mu     sync.Mutex
+	nonces map[string]time.Time
+	pruned time.Time
+}
+
+// Verify проверяет тело data по заголовкам запроса (header — http.Header.Get,
+// nats.Header.Get и т.п.) и публичному ключу агента
+func (v *Verifier) Verify(publicKey string, data []byte, header func(string) string) error {
+	pub, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("invalid public key")
+	}
+	sv, ok := strings.CutPrefix(header(SignatureHeader), signaturePrefix)
+	if !ok {
+		return errors.New("unsupported signature scheme")
+	}
+	sig, err := base64.StdEncoding.DecodeString(sv)
+	if err != nil {
+		return fmt.Errorf("decode signature: %w", err)
+	}
+	ts, nonce := header(TimestampHeader), header(NonceHeader)
+	if ts == "" || nonce == "" {
+		return fmt.Errorf("missing %s or %s", TimestampHeader, NonceHeader)
+	}
+	// сначала подпись: иначе чужие запросы могли бы занять nonce
+	if !ed25519.Verify(pub, signedMessage(ts, nonce, data), sig) {
+		return errors.New("signature mismatch")
+	}
+
+	sec, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%s: %w", TimestampHeader, err)
+	}
+	skew := v.maxSkew()
+	if d := v.now().Sub(time.Unix(sec, 0)); d > skew || d < -skew {
+		return fmt.Errorf("timestamp skew %s exceeds %s", d.Round(time.Second), skew)
+	}
+	// nonce живёт, пока время запроса с ним может пройти проверку
+	seen := v.Seen
+	if seen == nil {
+		seen = v.seen
+	}
+	if seen(KeyID(pub), nonce, 2*skew) {
+		return errors.New("replayed nonce")
+	}
+	return nil
+}
+
+func (v *Verifier) maxSkew() time.Duration {
+	if v.MaxSkew > 0 {
+		return v.MaxSkew
+	}
+	return DefaultMaxSkew
+}
+
+func (v *Verifier) now() time.Time {
+	if v.Now != nil {
+		return v.Now()
+	}
+	return time.Now()
+}
+
+func (v *Verifier) seen(keyID, nonce string, ttl time.Duration) bool {
+	now := v.now()
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.nonces == nil {
+		v.nonces = make(map[string]time.Time)
+	}
+	if now.Sub(v.pruned) >= time.Second {
+		for k, exp := range v.nonces {
+			if now.After(exp) {
+				delete(v.nonces, k)
+			}
+		}
+		v.pruned = now
+	}
+	k := keyID + "/" + nonce
+	if exp, ok := v.nonces[k]; ok && !now.After(exp) {
+		return true
+	}
+	v.nonces[k] = now.Add(ttl)
+	return false
+}
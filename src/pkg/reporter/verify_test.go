@@ -0,0 +1,85 @@
+package reporter
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSignVerify(t *testing.T) {
+	signer, err := GenerateKey(filepath.Join(t.TempDir(), "agent.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := GenerateKey(filepath.Join(t.TempDir(), "other.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sign := func() (Body, http.Header) {
+		b := Body{Data: []byte(`{"host":"web-1"}`)}
+		signer.Sign(&b)
+		h := make(http.Header)
+		for k, v := range b.Headers {
+			h.Set(k, v)
+		}
+		return b, h
+	}
+	tests := []struct {
+		name    string
+		key     string
+		mutate  func(b *Body, h http.Header)
+		now     time.Duration // сдвиг часов сервера
+		wantErr string
+	}{
+		{"valid", signer.PublicKey(), nil, 0, ""},
+		{"tampered body", signer.PublicKey(), func(b *Body, h http.Header) { b.Data = []byte(`{"host":"web-2"}`) }, 0, "signature mismatch"},
+		{"tampered timestamp", signer.PublicKey(), func(b *Body, h http.Header) { h.Set(TimestampHeader, "1") }, 0, "signature mismatch"},
+		{"other key", other.PublicKey(), nil, 0, "signature mismatch"},
+		{"no signature", signer.PublicKey(), func(b *Body, h http.Header) { h.Del(SignatureHeader) }, 0, "unsupported signature scheme"},
+		{"clock skew", signer.PublicKey(), nil, 10 * time.Minute, "timestamp skew"},
+		{"bad public key", "xx", nil, 0, "invalid public key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, h := sign()
+			if tt.mutate != nil {
+				tt.mutate(&b, h)
+			}
+			v := &Verifier{Now: func() time.Time { return time.Now().Add(tt.now) }}
+			err := v.Verify(tt.key, b.Data, h.Get)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+
+	// тот же запрос второй раз — повтор
+	b, h := sign()
+	v := &Verifier{}
+	if err := v.Verify(signer.PublicKey(), b.Data, h.Get); err != nil {
+		t.Fatal(err)
+	}
+	if err := v.Verify(signer.PublicKey(), b.Data, h.Get); err == nil || !strings.Contains(err.Error(), "replayed nonce") {
+		t.Errorf("replay: err = %v", err)
+	}
+}
+
+func TestLoadSigner(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "agent.key")
+	s, err := GenerateKey(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := GenerateKey(path); err == nil {
+		t.Error("GenerateKey overwrote an existing key")
+	}
+	loaded, err := LoadSigner(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.KeyID != s.KeyID || loaded.PublicKey() != s.PublicKey() {
+		t.Errorf("loaded %s, want %s", loaded.KeyID, s.KeyID)
+	}
+}
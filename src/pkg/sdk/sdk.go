@@ -0,0 +1,139 @@
+// Package sdk — подключение своих коллекторов к агенту: внутренние API, закрытые устройства,
+// всё, чего нет в pkg/collector. Коллектор — пакет вне этого репозитория, который в init()
+// вызывает Register; агент с ним собирается обычным go build, если пакет импортирован
+// в cmd/netload-reporter (см. README, «свои коллекторы»). Пример — examples/appliance-collector.
+//
+// Пакет не зависит от остальных частей агента: коллектор видит только свои настройки
+// и возвращает значения, а в отчёт их переносит агент
+package sdk
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Sample — одно значение коллектора за интервал
+type Sample struct {
+	// имя в snake_case, уникальное вместе с Labels в пределах коллектора
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+	// единица: bytes, bytes_per_sec, bits_per_sec, count, ratio, seconds; пусто — безразмерное
+	Unit   string            `json:"unit,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+// Collector — источник значений. Collect вызывается на каждом интервале отчёта в своей горутине;
+// ctx отменяется по таймауту коллектора (COLLECTOR_TIMEOUT, COLLECTOR_TIMEOUTS=<имя>=...).
+// Два вызова одного коллектора не пересекаются, так что состояние между вызовами можно
+// держать в самом коллекторе без блокировок. Ошибка — значения этого интервала пропадают,
+// агент пишет её в журнал и продолжает. Если коллектор реализует io.Closer, агент закрывает
+// его при остановке
+type Collector interface {
+	Collect(ctx context.Context) ([]Sample, error)
+}
+
+// Config — настройки коллектора: Get("URL") у коллектора appliance читает PLUGIN_APPLIANCE_URL
+type Config interface {
+	Get(key string) string
+}
+
+// Factory создаёт коллектор при старте агента; ошибка настройки не даёт агенту стартовать
+type Factory func(cfg Config) (Collector, error)
+
+var nameRe = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+var (
+	mu        sync.Mutex
+	factories = map[string]Factory{}
+)
+
+// Register добавляет коллектор name ([a-z][a-z0-9_]*); вызывается из init() пакета коллектора.
+// Повторное имя — паника: два пакета с одним именем в одной сборке — ошибка сборки, а не настройки
+func Register(name string, f Factory) {
+	if !nameRe.MatchString(name) {
+		panic(fmt.Sprintf("sdk: invalid collector name %q", name))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if _, dup := factories[name]; dup {
+		panic("sdk: collector " + name + " registered twice")
+	}
+	factories[name] = f
+}
+
+// Registered — имена вкомпилированных коллекторов по алфавиту
+func Registered() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	out := make([]string, 0, len(factories))
+	for name := range factories {
+		out = append(out, name)
+	}
+	slices.Sort(out)
+	return out
+}
+
+// New создаёт зарегистрированный коллектор name
+func New(name string, cfg Config) (Collector, error) {
+	mu.Lock()
+	f, ok := factories[name]
+	mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown collector %q (registered: %s)", name, strings.Join(Registered(), ","))
+	}
+	c, err := f(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return c, nil
+}
+
+// MaxSamples — предел значений одного коллектора за интервал: отчёт не должен
+// разрастаться из-за одного источника
+const MaxSamples = 1000
+
+// Validate проверяет значения так же, как агент перед отправкой: имена snake_case,
+// конечные числа, без повторов имени с теми же метками и не больше MaxSamples.
+// Агент отбрасывает весь интервал коллектора, не прошедший проверку
+func Validate(samples []Sample) error {
+	if len(samples) > MaxSamples {
+		return fmt.Errorf("%d samples, at most %d allowed", len(samples), MaxSamples)
+	}
+	seen := make(map[string]bool, len(samples))
+	for _, s := range samples {
+		if !nameRe.MatchString(s.Name) {
+			return fmt.Errorf("invalid sample name %q, want snake_case", s.Name)
+		}
+		if math.IsNaN(s.Value) || math.IsInf(s.Value, 0) {
+			return fmt.Errorf("%s: value %v is not finite", s.Name, s.Value)
+		}
+		key := s.Name
+		for _, k := range slices.Sorted(maps.Keys(s.Labels)) {
+			key += "\x00" + k + "=" + s.Labels[k]
+		}
+		if seen[key] {
+			return fmt.Errorf("%s: duplicate sample %v", s.Name, s.Labels)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// EnvConfig — настройки коллектора name из окружения PLUGIN_<NAME>_<KEY>
+func EnvConfig(name string) Config { return envConfig("PLUGIN_" + strings.ToUpper(name) + "_") }
+
+type envConfig string
+
+func (p envConfig) Get(key string) string { return os.Getenv(string(p) + key) }
+
+// MapConfig — настройки из map, для тестов и встраивания
+type MapConfig map[string]string
+
+func (m MapConfig) Get(key string) string { return m[key] }
@@ -0,0 +1,100 @@
+package sdk
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+type fixed []Sample
+
+func (f fixed) Collect(context.Context) ([]Sample, error) { return f, nil }
+
+func TestValidate(t *testing.T) {
+	tooMany := make([]Sample, MaxSamples+1)
+	for i := range tooMany {
+		tooMany[i] = Sample{Name: "v", Labels: map[string]string{"i": strings.Repeat("x", i)}}
+	}
+	tests := []struct {
+		name    string
+		samples []Sample
+		wantErr string
+	}{
+		{"empty", nil, ""},
+		{"ok", []Sample{{Name: "sessions", Value: 1}, {Name: "rx_bytes_per_sec", Value: 2, Labels: map[string]string{"port": "wan0"}}}, ""},
+		{"same name other labels", []Sample{{Name: "rx", Labels: map[string]string{"port": "a"}}, {Name: "rx", Labels: map[string]string{"port": "b"}}}, ""},
+		{"duplicate", []Sample{{Name: "rx"}, {Name: "rx"}}, "duplicate"},
+		{"duplicate labels", []Sample{{Name: "rx", Labels: map[string]string{"a": "1", "b": "2"}}, {Name: "rx", Labels: map[string]string{"b": "2", "a": "1"}}}, "duplicate"},
+		{"label value is not a key", []Sample{{Name: "rx", Labels: map[string]string{"a": "1=b"}}, {Name: "rx", Labels: map[string]string{"a": "1", "b": ""}}}, ""},
+		{"NaN", []Sample{{Name: "rx", Value: math.NaN()}}, "not finite"},
+		{"Inf", []Sample{{Name: "rx", Value: math.Inf(-1)}}, "not finite"},
+		{"bad name", []Sample{{Name: "RxBytes"}}, "snake_case"},
+		{"empty name", []Sample{{Name: ""}}, "snake_case"},
+		{"at MaxSamples", tooMany[:MaxSamples], ""},
+		{"over MaxSamples", tooMany, "at most"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.samples)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("err = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func mustPanic(t *testing.T, want string, f func()) {
+	t.Helper()
+	defer func() {
+		t.Helper()
+		r := recover()
+		if s, _ := r.(string); !strings.Contains(s, want) {
+			t.Errorf("panic %v, want %q", r, want)
+		}
+	}()
+	f()
+}
+
+func TestRegister(t *testing.T) {
+	factory := func(cfg Config) (Collector, error) { return fixed{{Name: "v", Value: 1}}, nil }
+	Register("sdk_test_ok", factory)
+	mustPanic(t, "registered twice", func() { Register("sdk_test_ok", factory) })
+	for _, name := range []string{"", "Upper", "1st", "with-dash"} {
+		mustPanic(t, "invalid collector name", func() { Register(name, factory) })
+	}
+	if !slices.Contains(Registered(), "sdk_test_ok") || slices.Contains(Registered(), "Upper") {
+		t.Errorf("Registered() = %v", Registered())
+	}
+}
+
+func TestNew(t *testing.T) {
+	Register("sdk_test_cfg", func(cfg Config) (Collector, error) {
+		if cfg.Get("URL") == "" {
+			return nil, errors.New("URL is required")
+		}
+		return fixed{}, nil
+	})
+	if _, err := New("sdk_test_cfg", MapConfig{"URL": "http://x"}); err != nil {
+		t.Errorf("New: %v", err)
+	}
+	if _, err := New("sdk_test_cfg", MapConfig{}); err == nil || err.Error() != "sdk_test_cfg: URL is required" {
+		t.Errorf("factory error: %v", err)
+	}
+	if _, err := New("sdk_test_missing", MapConfig{}); err == nil || !strings.Contains(err.Error(), "unknown collector") {
+		t.Errorf("unknown collector: %v", err)
+	}
+}
+
+func TestEnvConfig(t *testing.T) {
+	t.Setenv("PLUGIN_APPLIANCE_URL", "http://device")
+	cfg := EnvConfig("appliance")
+	if got := cfg.Get("URL"); got != "http://device" {
+		t.Errorf("Get(URL) = %q", got)
+	}
+	if got := cfg.Get("TOKEN"); got != "" {
+		t.Errorf("Get(TOKEN) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,60 @@
+// Package sdktest — помощники для тестов своих коллекторов: коллектор вызывается и проверяется
+// так же, как это делает агент, но без агента, сети и /proc
+package sdktest
+
+import (
+	"context"
+	"maps"
+	"testing"
+	"time"
+
+	"github.com/iflixer/network-stater/src/pkg/sdk"
+)
+
+// Timeout — таймаут Collect по умолчанию, как COLLECTOR_TIMEOUT агента
+const Timeout = 5 * time.Second
+
+// New создаёт зарегистрированный коллектор name с настройками cfg (ключи — как в Config.Get)
+func New(t testing.TB, name string, cfg map[string]string) sdk.Collector {
+	t.Helper()
+	c, err := sdk.New(name, sdk.MapConfig(cfg))
+	if err != nil {
+		t.Fatalf("sdk.New: %v", err)
+	}
+	return c
+}
+
+// Collect вызывает c.Collect с Timeout и проверяет результат sdk.Validate
+func Collect(t testing.TB, c sdk.Collector) []sdk.Sample {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), Timeout)
+	defer cancel()
+	samples, err := c.Collect(ctx)
+	if err != nil {
+		t.Fatalf("Collect: %v", err)
+	}
+	if err := sdk.Validate(samples); err != nil {
+		t.Fatalf("agent would drop samples: %v", err)
+	}
+	return samples
+}
+
+// Find ищет значение с именем name и ровно такими метками (nil — без меток)
+func Find(samples []sdk.Sample, name string, labels map[string]string) (sdk.Sample, bool) {
+	for _, s := range samples {
+		if s.Name == name && maps.Equal(s.Labels, labels) {
+			return s, true
+		}
+	}
+	return sdk.Sample{}, false
+}
+
+// Require — значение name с метками labels, иначе тест падает
+func Require(t testing.TB, samples []sdk.Sample, name string, labels map[string]string) sdk.Sample {
+	t.Helper()
+	s, ok := Find(samples, name, labels)
+	if !ok {
+		t.Fatalf("no sample %s%v among %d samples", name, labels, len(samples))
+	}
+	return s
+}
@@ -0,0 +1,348 @@
+package subnets
+
+import (
+	"errors"
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/cilium/ebpf"
+	"github.com/cilium/ebpf/asm"
+	"github.com/cilium/ebpf/features"
+	"github.com/cilium/ebpf/link"
+	"github.com/cilium/ebpf/rlimit"
+	"golang.org/x/sys/unix"
+
+	"github.com/iflixer/network-stater/src/pkg/collector"
+)
+
+// направления в ключе счётчика: group*2 + dir
+const (
+	dirRx = 0 // ingress, удалённый адрес — источник
+	dirTx = 1 // egress, удалённый адрес — получатель
+)
+
+// ключ LPM trie: IPv4 хранится как ::ffff:a.b.c.d, длина префикса — от 128-битного адреса
+type trieKey struct {
+	PrefixLen uint32
+	Addr      [16]byte
+}
+
+// Accounting — загруженные программы и карты; Close отключает их от cgroup
+type Accounting struct {
+	groups   []string // индекс = номер группы в карте, последний — OtherGroup
+	trie     *ebpf.Map
+	prefixes map[netip.Prefix]uint32
+	counters *ebpf.Map
+	closers  []interface{ Close() error }
+}
+
+// spareEntries — запас в trie для SetPrefixes (адреса upstream-ов меняются на ходу)
+const spareEntries = 256
+
+// CheckCapabilities: для загрузки cgroup_skb нужны CAP_BPF и CAP_NET_ADMIN либо CAP_SYS_ADMIN
+func CheckCapabilities() error {
+	raw, err := os.ReadFile("/proc/self/status")
+	if err != nil {
+		return err
+	}
+	for _, line := range strings.Split(string(raw), "\n") {
+		v, ok := strings.CutPrefix(line, "CapEff:")
+		if !ok {
+			continue
+		}
+		caps, err := strconv.ParseUint(strings.TrimSpace(v), 16, 64)
+		if err != nil {
+			return fmt.Errorf("parse CapEff: %w", err)
+		}
+		has := func(c int) bool { return caps&(1<<c) != 0 }
+		if has(unix.CAP_SYS_ADMIN) || (has(unix.CAP_BPF) && has(unix.CAP_NET_ADMIN)) {
+			return nil
+		}
+		return errors.New("need CAP_BPF and CAP_NET_ADMIN (or CAP_SYS_ADMIN)")
+	}
+	return errors.New("CapEff not found in /proc/self/status")
+}
+
+// Supported проверяет, что ядро умеет всё, что использует программа учёта.
+// Программа собирается в Go (asm) и читает только поля __sk_buff из UAPI, поэтому
+// ей не нужны ни clang, ни BTF/CO-RE, ни заголовки ядра — достаточно самих возможностей
+func Supported() error {
+	if err := features.HaveProgramType(ebpf.CGroupSKB); err != nil {
+		return fmt.Errorf("cgroup_skb programs: %w", err)
+	}
+	if err := features.HaveProgramHelper(ebpf.CGroupSKB, asm.FnSkbLoadBytes); err != nil {
+		return fmt.Errorf("bpf_skb_load_bytes: %w", err)
+	}
+	if err := features.HaveMapType(ebpf.LPMTrie); err != nil {
+		return fmt.Errorf("LPM trie maps: %w", err)
+	}
+	return nil
+}
+
+// DefaultCgroupPath ищет точку монтирования cgroup v2 (чистая v2 или hybrid-режим systemd)
+func DefaultCgroupPath() string {
+	for _, p := range []string{"/sys/fs/cgroup", "/sys/fs/cgroup/unified"} {
+		if _, err := os.Stat(filepath.Join(p, "cgroup.controllers")); err == nil {
+			return p
+		}
+	}
+	return "/sys/fs/cgroup"
+}
+
+// Open загружает программы учёта и подключает их к cgroup (пусто — DefaultCgroupPath).
+// Учитывается трафик сокетов процессов этой cgroup и всех вложенных, то есть всего узла
+func Open(groups []Group, cgroupPath string) (a *Accounting, err error) {
+	if cgroupPath == "" {
+		cgroupPath = DefaultCgroupPath()
+	}
+	if err := Supported(); err != nil {
+		return nil, err
+	}
+	// ядра до 5.11 считают память карт по RLIMIT_MEMLOCK
+	if err := rlimit.RemoveMemlock(); err != nil {
+		return nil, err
+	}
+
+	a = &Accounting{}
+	defer func() {
+		if err != nil {
+			a.Close()
+		}
+	}()
+
+	prefixes := 0
+	for _, g := range groups {
+		prefixes += len(g.Prefixes)
+		a.groups = append(a.groups, g.Name)
+	}
+	a.groups = append(a.groups, OtherGroup)
+
+	a.trie, err = ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "ns_groups",
+		Type:       ebpf.LPMTrie,
+		KeySize:    20,
+		ValueSize:  4,
+		MaxEntries: uint32(prefixes + spareEntries),
+		Flags:      unix.BPF_F_NO_PREALLOC,
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, a.trie)
+	a.prefixes = make(map[netip.Prefix]uint32, prefixes)
+	for i, g := range groups {
+		for _, p := range g.Prefixes {
+			if err := a.trie.Put(newTrieKey(p), uint32(i)); err != nil {
+				return nil, fmt.Errorf("group %s: %s: %w", g.Name, p, err)
+			}
+			a.prefixes[p] = uint32(i)
+		}
+	}
+
+	a.counters, err = ebpf.NewMap(&ebpf.MapSpec{
+		Name:       "ns_bytes",
+		Type:       ebpf.Array,
+		KeySize:    4,
+		ValueSize:  8,
+		MaxEntries: uint32(len(a.groups) * 2),
+	})
+	if err != nil {
+		return nil, err
+	}
+	a.closers = append(a.closers, a.counters)
+
+	for _, hook := range []struct {
+		dir    int
+		attach ebpf.AttachType
+	}{
+		{dirRx, ebpf.AttachCGroupInetIngress},
+		{dirTx, ebpf.AttachCGroupInetEgress},
+	} {
+		prog, err := ebpf.NewProgram(&ebpf.ProgramSpec{
+			Name:         "ns_account",
+			Type:         ebpf.CGroupSKB,
+			Instructions: program(hook.dir, a.trie.FD(), a.counters.FD(), len(a.groups)-1),
+			License:      "GPL",
+		})
+		if err != nil {
+			return nil, fmt.Errorf("load program: %w", err)
+		}
+		a.closers = append(a.closers, prog)
+		l, err := link.AttachCgroup(link.CgroupOptions{Path: cgroupPath, Attach: hook.attach, Program: prog})
+		if err != nil {
+			return nil, fmt.Errorf("attach to %s: %w", cgroupPath, err)
+		}
+		a.closers = append(a.closers, l)
+	}
+	return a, nil
+}
+
+// Groups — имена групп в порядке конфигурации, последним OtherGroup
+func (a *Accounting) Groups() []string {
+	return a.groups
+}
+
+// SetPrefixes заменяет сети группы name, не трогая счётчики; пакеты в момент
+// замены могут попасть в other
+func (a *Accounting) SetPrefixes(name string, prefixes []netip.Prefix) error {
+	idx := slices.Index(a.groups, name)
+	if idx < 0 || name == OtherGroup {
+		return fmt.Errorf("unknown group %q", name)
+	}
+	want := make(map[netip.Prefix]bool, len(prefixes))
+	for _, p := range prefixes {
+		want[p.Masked()] = true
+	}
+	for p, g := range a.prefixes {
+		if g == uint32(idx) && !want[p] {
+			if err := a.trie.Delete(newTrieKey(p)); err != nil && !errors.Is(err, ebpf.ErrKeyNotExist) {
+				return fmt.Errorf("group %s: remove %s: %w", name, p, err)
+			}
+			delete(a.prefixes, p)
+		}
+	}
+	for p := range want {
+		if g, ok := a.prefixes[p]; ok && g == uint32(idx) {
+			continue
+		}
+		if err := a.trie.Put(newTrieKey(p), uint32(idx)); err != nil {
+			return fmt.Errorf("group %s: %s: %w", name, p, err)
+		}
+		a.prefixes[p] = uint32(idx)
+	}
+	return nil
+}
+
+// Read возвращает накопленные байты по группам. Групп могут быть десятки тысяч
+// (по ASN), поэтому карта читается одним пакетным запросом, где ядро это умеет
+func (a *Accounting) Read() (map[string]collector.Counters, error) {
+	vals, err := a.readAll()
+	if err != nil {
+		return nil, err
+	}
+	out := make(map[string]collector.Counters, len(a.groups))
+	for i, name := range a.groups {
+		out[name] = collector.Counters{Rx: vals[i*2+dirRx], Tx: vals[i*2+dirTx]}
+	}
+	return out, nil
+}
+
+func (a *Accounting) readAll() ([]uint64, error) {
+	n := len(a.groups) * 2
+	keys, vals := make([]uint32, n), make([]uint64, n)
+	var cursor ebpf.MapBatchCursor
+	got, err := a.counters.BatchLookup(&cursor, keys, vals, nil)
+	if err == nil || (errors.Is(err, ebpf.ErrKeyNotExist) && got == n) {
+		out := make([]uint64, n)
+		for j := 0; j < got; j++ {
+			out[keys[j]] = vals[j]
+		}
+		return out, nil
+	}
+	// до 5.6 пакетных операций нет — по одному ключу
+	for i := range vals {
+		if err := a.counters.Lookup(uint32(i), &vals[i]); err != nil {
+			return nil, err
+		}
+	}
+	return vals, nil
+}
+
+func (a *Accounting) Close() error {
+	var errs []error
+	// в обратном порядке: сначала отключаем программы, потом закрываем карты
+	for i := len(a.closers) - 1; i >= 0; i-- {
+		errs = append(errs, a.closers[i].Close())
+	}
+	a.closers = nil
+	return errors.Join(errs...)
+}
+
+func newTrieKey(p netip.Prefix) trieKey {
+	k := trieKey{PrefixLen: uint32(p.Bits()), Addr: p.Addr().As16()}
+	if p.Addr().Is4() {
+		k.PrefixLen += 96
+	}
+	return k
+}
+
+// program собирает cgroup_skb-программу: длина пакета прибавляется к счётчику группы,
+// в которую попал удалённый адрес. Пакет всегда пропускается (return 1).
+// Пакет в cgroup_skb начинается с IP-заголовка; поля __sk_buff — из стабильного UAPI,
+// поэтому программа не зависит от версии ядра и его заголовков.
+//
+// Стек: fp-32 — первый байт пакета, fp-28 — ключ счётчика, fp-24..fp-5 — trieKey
+func program(dir, trieFD, countersFD, other int) asm.Instructions {
+	v4Off, v6Off := int32(12), int32(8) // адрес источника
+	if dir == dirTx {
+		v4Off, v6Off = 16, 24 // адрес получателя
+	}
+	return asm.Instructions{
+		asm.Mov.Reg(asm.R6, asm.R1),
+
+		// версия IP — старшие 4 бита первого байта
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, 0),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -32),
+		asm.Mov.Imm(asm.R4, 1),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R7, asm.RFP, -32, asm.Byte),
+		asm.RSh.Imm(asm.R7, 4),
+		asm.StoreImm(asm.RFP, -24, 128, asm.Word),
+		asm.JEq.Imm(asm.R7, 6, "v6"),
+		asm.JNE.Imm(asm.R7, 4, "out"),
+
+		// IPv4 -> ::ffff:a.b.c.d
+		asm.StoreImm(asm.RFP, -20, 0, asm.Word),
+		asm.StoreImm(asm.RFP, -16, 0, asm.Word),
+		asm.StoreImm(asm.RFP, -12, 0, asm.Half),
+		asm.StoreImm(asm.RFP, -10, 0xff, asm.Byte),
+		asm.StoreImm(asm.RFP, -9, 0xff, asm.Byte),
+		asm.Mov.Reg(asm.R1, asm.R6),
+		asm.Mov.Imm(asm.R2, v4Off),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -8),
+		asm.Mov.Imm(asm.R4, 4),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "out"),
+		asm.Ja.Label("lookup"),
+
+		asm.Mov.Reg(asm.R1, asm.R6).WithSymbol("v6"),
+		asm.Mov.Imm(asm.R2, v6Off),
+		asm.Mov.Reg(asm.R3, asm.RFP),
+		asm.Add.Imm(asm.R3, -20),
+		asm.Mov.Imm(asm.R4, 16),
+		asm.FnSkbLoadBytes.Call(),
+		asm.JNE.Imm(asm.R0, 0, "out"),
+
+		// номер группы или other
+		asm.LoadMapPtr(asm.R1, trieFD).WithSymbol("lookup"),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -24),
+		asm.FnMapLookupElem.Call(),
+		asm.Mov.Imm(asm.R8, int32(other)),
+		asm.JEq.Imm(asm.R0, 0, "count"),
+		asm.LoadMem(asm.R8, asm.R0, 0, asm.Word),
+
+		asm.LSh.Imm(asm.R8, 1).WithSymbol("count"),
+		asm.Add.Imm(asm.R8, int32(dir)),
+		asm.StoreMem(asm.RFP, -28, asm.R8, asm.Word),
+		asm.LoadMapPtr(asm.R1, countersFD),
+		asm.Mov.Reg(asm.R2, asm.RFP),
+		asm.Add.Imm(asm.R2, -28),
+		asm.FnMapLookupElem.Call(),
+		asm.JEq.Imm(asm.R0, 0, "out"),
+		asm.LoadMem(asm.R1, asm.R6, 0, asm.Word), // __sk_buff.len
+		asm.StoreXAdd(asm.R0, asm.R1, asm.DWord),
+
+		asm.Mov.Imm(asm.R0, 1).WithSymbol("out"),
+		asm.Return(),
+	}
+}
@@ -0,0 +1,57 @@
+// Package subnets разносит трафик по группам удалённых сетей (CDN, origin, внутренние)
+// при помощи eBPF-программ cgroup_skb, подключённых к корневой cgroup v2
+package subnets
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+)
+
+// OtherGroup — трафик, не попавший ни в одну группу
+const OtherGroup = "other"
+
+type Group struct {
+	Name     string
+	Prefixes []netip.Prefix
+}
+
+// ParseGroups разбирает "cdn=203.0.113.0/24,2001:db8::/32;origin=198.51.100.0/24"
+func ParseGroups(s string) ([]Group, error) {
+	var groups []Group
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, list, ok := strings.Cut(part, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid group %q, want name=cidr[,cidr...]", part)
+		}
+		if name == OtherGroup || seen[name] {
+			return nil, fmt.Errorf("duplicate group name %q", name)
+		}
+		seen[name] = true
+		g := Group{Name: name}
+		for _, c := range strings.Split(list, ",") {
+			if c = strings.TrimSpace(c); c == "" {
+				continue
+			}
+			p, err := netip.ParsePrefix(c)
+			if err != nil {
+				return nil, fmt.Errorf("group %s: %w", name, err)
+			}
+			g.Prefixes = append(g.Prefixes, p.Masked())
+		}
+		if len(g.Prefixes) == 0 {
+			return nil, fmt.Errorf("group %s: no networks", name)
+		}
+		groups = append(groups, g)
+	}
+	if len(groups) == 0 {
+		return nil, fmt.Errorf("no groups configured")
+	}
+	return groups, nil
+}
@@ -0,0 +1,99 @@
+package window
+
+import "time"
+
+// Bucket — байты и покрытое отчётами время за один шаг; Start — unix-время начала
+type Bucket struct {
+	Start   int64   `json:"start"`
+	Rx      float64 `json:"rx"`
+	Tx      float64 `json:"tx"`
+	Seconds float64 `json:"seconds"`
+}
+
+// Buckets — средние за длинные окна (сутки, неделя) по корзинам фиксированного шага:
+// память — size/step корзин независимо от интервала отчётов, точность края окна — одна корзина.
+// Среднее считается по покрытому времени, а не по длине окна: пока агент не работал,
+// трафик неизвестен и нулём не считается
+type Buckets struct {
+	size, step time.Duration
+	closed     []Bucket
+	cur        Bucket
+}
+
+// NewBuckets — окно до size с шагом step
+func NewBuckets(size, step time.Duration) *Buckets {
+	return &Buckets{size: size, step: step}
+}
+
+// Add учитывает прирост байт за dt к моменту now
+func (b *Buckets) Add(now time.Time, drx, dtx float64, dt time.Duration) {
+	start := now.Truncate(b.step).Unix()
+	if start != b.cur.Start {
+		if b.cur.Seconds > 0 {
+			b.closed = append(b.closed, b.cur)
+		}
+		b.cur = Bucket{Start: start}
+		cut := now.Add(-b.size).Unix()
+		i := 0
+		for i < len(b.closed) && b.closed[i].Start < cut {
+			i++
+		}
+		// копия, а не срез: иначе старые корзины держит базовый массив
+		if i > 0 {
+			b.closed = append([]Bucket(nil), b.closed[i:]...)
+		}
+	}
+	b.cur.Rx += drx
+	b.cur.Tx += dtx
+	b.cur.Seconds += dt.Seconds()
+}
+
+// Average — средние скорости за последние d и сколько секунд из них покрыто; ok=false, если нисколько
+func (b *Buckets) Average(now time.Time, d time.Duration) (rx, tx, coverage float64, ok bool) {
+	cut := now.Add(-d).Unix()
+	sum := b.cur
+	for i := len(b.closed) - 1; i >= 0 && b.closed[i].Start >= cut; i-- {
+		c := b.closed[i]
+		sum.Rx += c.Rx
+		sum.Tx += c.Tx
+		sum.Seconds += c.Seconds
+	}
+	if sum.Seconds <= 0 {
+		return 0, 0, 0, false
+	}
+	return sum.Rx / sum.Seconds, sum.Tx / sum.Seconds, sum.Seconds, true
+}
+
+// BucketsState — корзины для сохранения между перезапусками
+type BucketsState struct {
+	StepSeconds float64  `json:"step_seconds"`
+	Buckets     []Bucket `json:"buckets"`
+}
+
+func (b *Buckets) State() BucketsState {
+	out := BucketsState{StepSeconds: b.step.Seconds(), Buckets: append([]Bucket(nil), b.closed...)}
+	if b.cur.Seconds > 0 {
+		out.Buckets = append(out.Buckets, b.cur)
+	}
+	return out
+}
+
+// Restore подхватывает сохранённые корзины, если шаг не менялся; устаревшие отбрасываются
+func (b *Buckets) Restore(s BucketsState, now time.Time) bool {
+	if s.StepSeconds != b.step.Seconds() {
+		return false
+	}
+	cut := now.Add(-b.size).Unix()
+	cur := now.Truncate(b.step).Unix()
+	b.closed = b.closed[:0]
+	for _, c := range s.Buckets {
+		switch {
+		case c.Start < cut || c.Start > cur:
+		case c.Start == cur:
+			b.cur = c
+		default:
+			b.closed = append(b.closed, c)
+		}
+	}
+	return true
+}
@@ -0,0 +1,32 @@
+package window
+
+import (
+	"math"
+	"time"
+)
+
+// EWMA хранит одно число вместо истории; вес новой точки зависит от
+// прошедшего времени, поэтому неровный интервал не искажает half-life
+type EWMA struct {
+	halfLife time.Duration
+	value    float64
+	primed   bool
+}
+
+func NewEWMA(halfLife time.Duration) *EWMA {
+	return &EWMA{halfLife: halfLife}
+}
+
+// Update добавляет скорость rate, измеренную за dt, и возвращает новое среднее
+func (e *EWMA) Update(rate float64, dt time.Duration) float64 {
+	if !e.primed {
+		e.value, e.primed = rate, true
+		return e.value
+	}
+	alpha := 1 - math.Exp(-math.Ln2*dt.Seconds()/e.halfLife.Seconds())
+	e.value += alpha * (rate - e.value)
+	return e.value
+}
+
+// Value — текущее среднее
+func (e *EWMA) Value() float64 { return e.value }
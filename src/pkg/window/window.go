@@ -0,0 +1,51 @@
+// Package window — скользящие средние скоростей: окно по накопителям
+// и экспоненциально взвешенное среднее.
+package window
+
+import "time"
+
+type histEntry struct {
+	t     time.Time
+	cumRx float64
+	cumTx float64
+}
+
+// Window — среднее за последние size по накопленным байтам;
+// память пропорциональна size/интервал
+type Window struct {
+	size time.Duration
+	// накопители с момента старта
+	cumRx, cumTx float64
+	history      []histEntry
+}
+
+// New создаёт окно, начальная точка — start
+func New(size time.Duration, start time.Time) *Window {
+	return &Window{size: size, history: []histEntry{{t: start}}}
+}
+
+// Add учитывает прирост байт к моменту now и возвращает средние скорости
+// за окно; ok=false, если окно ещё нулевой длины
+func (w *Window) Add(now time.Time, drx, dtx float64) (rx, tx float64, ok bool) {
+	w.cumRx += drx
+	w.cumTx += dtx
+	w.history = append(w.history, histEntry{t: now, cumRx: w.cumRx, cumTx: w.cumTx})
+	w.prune(now)
+
+	old := w.history[0]
+	dt := now.Sub(old.t).Seconds()
+	if dt <= 0 {
+		return 0, 0, false
+	}
+	return (w.cumRx - old.cumRx) / dt, (w.cumTx - old.cumTx) / dt, true
+}
+
+func (w *Window) prune(now time.Time) {
+	cut := now.Add(-w.size)
+	// оставляем самую старую точку, если она единственная
+	i := 0
+	for i < len(w.history)-1 && w.history[i].t.Before(cut) {
+		i++
+	}
+	w.history = w.history[i:]
+}
@@ -0,0 +1,105 @@
+package window
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+var t0 = time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+
+func TestWindowAdd(t *testing.T) {
+	type step struct {
+		at       time.Duration // от t0
+		drx, dtx float64
+		rx, tx   float64
+		ok       bool
+	}
+	tests := []struct {
+		name  string
+		size  time.Duration
+		steps []step
+	}{
+		{"zero length", time.Minute, []step{{0, 100, 100, 0, 0, false}}},
+		{"average from start", time.Minute, []step{
+			{10 * time.Second, 1000, 500, 100, 50, true},
+			{20 * time.Second, 3000, 500, 200, 50, true},
+		}},
+		{"old points pruned", 30 * time.Second, []step{
+			{10 * time.Second, 1000, 0, 100, 0, true},
+			{20 * time.Second, 1000, 0, 100, 0, true},
+			{30 * time.Second, 1000, 0, 100, 0, true},
+			// окно с t=10s: 3000 байт за 30s
+			{40 * time.Second, 2000, 0, 4000.0 / 30, 0, true},
+		}},
+		{"gap restarts window", 30 * time.Second, []step{
+			{2 * time.Minute, 1200, 0, 0, 0, false},
+			{2*time.Minute + 10*time.Second, 500, 0, 50, 0, true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := New(tt.size, t0)
+			for i, s := range tt.steps {
+				rx, tx, ok := w.Add(t0.Add(s.at), s.drx, s.dtx)
+				if ok != s.ok || math.Abs(rx-s.rx) > 1e-9 || math.Abs(tx-s.tx) > 1e-9 {
+					t.Errorf("step %d: got %v %v %v, want %v %v %v", i, rx, tx, ok, s.rx, s.tx, s.ok)
+				}
+			}
+		})
+	}
+}
+
+func TestEWMAUpdate(t *testing.T) {
+	tests := []struct {
+		name  string
+		rates []float64
+		dt    time.Duration
+		want  float64
+	}{
+		{"first value primes", []float64{100}, time.Second, 100},
+		{"one half-life", []float64{0, 100}, time.Minute, 50},
+		{"two half-lives", []float64{0, 100}, 2 * time.Minute, 75},
+		{"zero dt keeps value", []float64{40, 100}, 0, 40},
+		{"constant rate", []float64{7, 7, 7}, 10 * time.Second, 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			e := NewEWMA(time.Minute)
+			var got float64
+			for _, r := range tt.rates {
+				got = e.Update(r, tt.dt)
+			}
+			if math.Abs(got-tt.want) > 1e-9 || e.Value() != got {
+				t.Errorf("got %v (Value %v), want %v", got, e.Value(), tt.want)
+			}
+		})
+	}
+}
+
+func TestBucketsAverage(t *testing.T) {
+	b := NewBuckets(time.Hour, 10*time.Minute)
+	// 15s-отчёты по 1500 байт rx: 100 B/s
+	for at := 15 * time.Second; at <= 30*time.Minute; at += 15 * time.Second {
+		b.Add(t0.Add(at), 1500, 0, 15*time.Second)
+	}
+	now := t0.Add(30 * time.Minute)
+	rx, _, coverage, ok := b.Average(now, time.Hour)
+	if !ok || math.Abs(rx-100) > 1e-9 || coverage != 30*60 {
+		t.Errorf("got rx=%v coverage=%v ok=%v, want 100 1800 true", rx, coverage, ok)
+	}
+	if _, _, _, ok := NewBuckets(time.Hour, time.Minute).Average(now, time.Hour); ok {
+		t.Error("empty buckets: want ok=false")
+	}
+
+	restored := NewBuckets(time.Hour, 10*time.Minute)
+	if !restored.Restore(b.State(), now) {
+		t.Fatal("Restore with the same step failed")
+	}
+	if rx2, _, cov2, _ := restored.Average(now, time.Hour); rx2 != rx || cov2 != coverage {
+		t.Errorf("after Restore: rx=%v coverage=%v, want %v %v", rx2, cov2, rx, coverage)
+	}
+	if NewBuckets(time.Hour, time.Minute).Restore(b.State(), now) {
+		t.Error("Restore with another step: want false")
+	}
+}